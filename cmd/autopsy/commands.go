@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+	"chatgpt-autopsy-go/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// runImport uploads an export file and runs the import pipeline on it
+func runImport(a *app, args []string) error {
+	if len(args) != 1 {
+		return usageError("import needs one file")
+	}
+
+	upload, err := a.uploads.UploadLocalFile(args[0])
+	if err != nil {
+		if upload != nil {
+			return fmt.Errorf("%s is already imported as upload %d", args[0], upload.ID)
+		}
+		return err
+	}
+
+	fmt.Printf("Importing %s as upload %d\n", args[0], upload.ID)
+	if err := a.pipeline.Import(upload.ID); err != nil {
+		return fmt.Errorf("import of upload %d failed: %w", upload.ID, err)
+	}
+
+	importRecord, err := a.store.Imports.ForUpload(upload.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get import: %w", err)
+	}
+	counts := services.NewImportEvent(importRecord).Counts
+	fmt.Printf("Imported %d conversations, %d messages and %d threads\n",
+		counts["conversations_count"], counts["messages_count"], counts["threads_count"])
+	return nil
+}
+
+// runStatus lists the most recent uploads with their import state and
+// counts what the database holds
+func runStatus(a *app, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "number of uploads to list")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+
+	uploads, total, err := a.uploads.ListUploads(1, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFILE\tSTATUS\tSTAGE\tPROGRESS\tUPLOADED")
+	for _, upload := range uploads {
+		stage, progress := "-", "-"
+		if importRecord, err := a.store.Imports.ForUpload(upload.ID); err == nil {
+			stage = importRecord.Stage
+			progress = fmt.Sprintf("%d%%", importRecord.ProgressPercent)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", upload.ID, upload.OriginalFilename, upload.Status,
+			stage, progress, upload.UploadedAt.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if total > int64(len(uploads)) {
+		fmt.Printf("(%d of %d uploads)\n", len(uploads), total)
+	}
+
+	var conversations, messages, analyses int64
+	for _, count := range []struct {
+		query *gorm.DB
+		total *int64
+	}{
+		{a.store.Conversations.Query(), &conversations},
+		{a.store.Messages.Query(), &messages},
+		{a.store.Analyses.Query(), &analyses},
+	} {
+		if err := count.query.Count(count.total).Error; err != nil {
+			return fmt.Errorf("failed to count records: %w", err)
+		}
+	}
+	dates, err := threadDates(a.store, "", "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%d conversations, %d messages, %d dates, %d analyses\n", conversations, messages, len(dates), analyses)
+	return nil
+}
+
+// runAnalyze generates the analyses for a date or every date in a range
+func runAnalyze(a *app, args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	date := flags.String("date", "", "date to analyse (YYYY-MM-DD)")
+	dateRange := flags.String("range", "", "dates to analyse (YYYY-MM-DD..YYYY-MM-DD)")
+	force := flags.Bool("force", false, "regenerate existing analyses")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	if (*date == "") == (*dateRange == "") {
+		return usageError("analyze needs one of -date or -range")
+	}
+
+	dates, err := selectDates(a.store, *date, *dateRange)
+	if err != nil {
+		return err
+	}
+	if len(dates) == 0 {
+		fmt.Println("No dates with conversations in range")
+		return nil
+	}
+
+	failed := 0
+	for _, day := range dates {
+		if err := a.analysis.GenerateAnalysisForDate(day, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", day, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Analysed %s\n", day)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d dates failed", failed, len(dates))
+	}
+	return nil
+}
+
+// runList lists dates
+func runList(a *app, args []string) error {
+	if len(args) != 1 || args[0] != "dates" {
+		return usageError("list supports: dates")
+	}
+
+	dates, err := threadDates(a.store, "", "")
+	if err != nil {
+		return err
+	}
+	for i := len(dates) - 1; i >= 0; i-- {
+		fmt.Println(dates[i])
+	}
+	return nil
+}
+
+// runShow prints an analysis as markdown, or lists a date's analyses
+func runShow(a *app, args []string) error {
+	if len(args) < 2 || args[0] != "analysis" {
+		return usageError("show supports: analysis <date> [type]")
+	}
+	date := args[1]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return usageError("date must be in YYYY-MM-DD format")
+	}
+
+	if len(args) == 2 {
+		analyses, err := a.store.Analyses.ForDate(date)
+		if err != nil {
+			return fmt.Errorf("failed to get analyses: %w", err)
+		}
+		if len(analyses) == 0 {
+			return fmt.Errorf("no analyses for %s", date)
+		}
+		sort.Slice(analyses, func(i, j int) bool { return analyses[i].AnalysisType < analyses[j].AnalysisType })
+		for _, analysis := range analyses {
+			fmt.Println(analysis.AnalysisType)
+		}
+		return nil
+	}
+
+	analysis, err := a.store.Analyses.ForDateAndType(date, args[2])
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return fmt.Errorf("no %s analysis for %s", args[2], date)
+		}
+		return fmt.Errorf("failed to get analysis: %w", err)
+	}
+	fmt.Print(analysis.MarkdownContent)
+	return nil
+}
+
+// runExport writes the analyses of a date, a range or every date as JSON
+// or markdown
+func runExport(a *app, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	date := flags.String("date", "", "date to export (YYYY-MM-DD)")
+	dateRange := flags.String("range", "", "dates to export (YYYY-MM-DD..YYYY-MM-DD)")
+	format := flags.String("format", "json", "json or markdown")
+	output := flags.String("o", "", "file to write instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	if *date != "" && *dateRange != "" {
+		return usageError("export takes -date or -range, not both")
+	}
+	if *format != "json" && *format != "markdown" {
+		return usageError("format must be json or markdown")
+	}
+
+	query := a.store.Analyses.Query().Where("date IS NOT NULL")
+	switch {
+	case *date != "":
+		if _, err := time.Parse("2006-01-02", *date); err != nil {
+			return usageError("date must be in YYYY-MM-DD format")
+		}
+		query = query.Where("date = ?", *date)
+	case *dateRange != "":
+		from, to, err := parseRange(*dateRange)
+		if err != nil {
+			return err
+		}
+		query = query.Where("date >= ? AND date <= ?", from, to)
+	}
+	var analyses []models.Analysis
+	if err := query.Order("date ASC, analysis_type ASC").Find(&analyses).Error; err != nil {
+		return fmt.Errorf("failed to get analyses: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(analyses); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	} else {
+		for i, analysis := range analyses {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			if _, err := fmt.Fprintln(out, strings.TrimRight(analysis.MarkdownContent, "\n")); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+	}
+
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d analyses to %s\n", len(analyses), *output)
+	}
+	return nil
+}
+
+// runSearch prints the messages containing every word of the query
+func runSearch(a *app, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	uploadID := flags.Uint("upload", 0, "only messages from this upload")
+	conversationID := flags.Uint("conversation", 0, "only messages from this conversation")
+	role := flags.String("role", "", "only messages with this role")
+	limit := flags.Int("limit", 20, "maximum number of messages")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	query := strings.Join(flags.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return usageError("search needs a query")
+	}
+
+	messages, total, err := a.search.SearchMessages(services.SearchFilter{
+		Query:          query,
+		UploadID:       *uploadID,
+		ConversationID: *conversationID,
+		Role:           *role,
+	}, 1, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCONVERSATION\tTIME\tROLE\tCONTENT")
+	for _, msg := range messages {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", msg.ID, msg.ConversationID,
+			msg.Timestamp.UTC().Format("2006-01-02 15:04"), msg.Role, snippet(msg.Content, 80))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("(%d of %d matches)\n", len(messages), total)
+	return nil
+}
+
+// runPurge permanently removes an upload and everything derived from it
+func runPurge(a *app, args []string) error {
+	if len(args) != 1 {
+		return usageError("purge needs an upload ID")
+	}
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return usageError("invalid upload ID: %s", args[0])
+	}
+
+	report, err := a.pipeline.Purge(uint(id))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged upload %d: %d conversations, %d messages, %d threads, %d analyses, %d files (%d bytes)\n",
+		report.UploadID, report.Conversations, report.Messages, report.Threads, report.Analyses,
+		report.FilesRemoved, report.BytesReclaimed)
+	if len(report.InvalidatedDates) > 0 {
+		fmt.Printf("Dates to analyze again: %s\n", strings.Join(report.InvalidatedDates, ", "))
+	}
+	return nil
+}
+
+// selectDates returns the given date, or the dates in a range that have
+// conversations
+func selectDates(store *repository.Store, date, dateRange string) ([]string, error) {
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, usageError("date must be in YYYY-MM-DD format")
+		}
+		return []string{date}, nil
+	}
+
+	from, to, err := parseRange(dateRange)
+	if err != nil {
+		return nil, err
+	}
+	return threadDates(store, from, to)
+}
+
+// parseRange splits a YYYY-MM-DD..YYYY-MM-DD range
+func parseRange(dateRange string) (string, string, error) {
+	from, to, ok := strings.Cut(dateRange, "..")
+	if !ok {
+		return "", "", usageError("range must be YYYY-MM-DD..YYYY-MM-DD")
+	}
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return "", "", usageError("range must be YYYY-MM-DD..YYYY-MM-DD")
+		}
+	}
+	if from > to {
+		return "", "", usageError("range starts after it ends")
+	}
+	return from, to, nil
+}
+
+// threadDates lists the dates with threads in ascending order, optionally
+// limited to a range
+func threadDates(store *repository.Store, from, to string) ([]string, error) {
+	query := store.Threads.Query()
+	if from != "" {
+		query = query.Where("date >= ? AND date <= ?", from, to)
+	}
+	var dates []string
+	if err := query.Distinct("date").Order("date ASC").Pluck("date", &dates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list dates: %w", err)
+	}
+
+	// Date columns can scan back with a time attached
+	for i, date := range dates {
+		if len(date) > 10 {
+			dates[i] = date[:10]
+		}
+	}
+	return dates, nil
+}
+
+// snippet shortens text to one line of at most n runes
+func snippet(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
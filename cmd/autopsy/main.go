@@ -0,0 +1,160 @@
+// Command autopsy imports, analyses, searches and exports chat exports
+// without the HTTP server. It uses the same configuration and database as
+// the server, so the two can share a data directory; run one of them at a
+// time when importing or purging.
+//
+//	autopsy import <file>                            import an export and wait for it to finish
+//	autopsy status                                   list uploads and what the database holds
+//	autopsy analyze -date <date> | -range <a>..<b>   generate analyses (-force to regenerate)
+//	autopsy list dates                               list dates with conversations
+//	autopsy show analysis <date> [type]              print an analysis, or list a date's analyses
+//	autopsy export [-date | -range] [-format] [-o]   write analyses as JSON or markdown
+//	autopsy search [-upload] [-role] [-limit] <q>    search message content
+//	autopsy purge <upload-id>                        remove an upload and everything derived from it
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/repository"
+	"chatgpt-autopsy-go/internal/services"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// app holds the services the commands run on
+type app struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	uploads  *services.UploadService
+	pipeline *services.PipelineService
+	analysis *services.AnalysisService
+	search   *services.SearchService
+}
+
+// commands maps each subcommand to its implementation
+var commands = map[string]func(a *app, args []string) error{
+	"import":  runImport,
+	"status":  runStatus,
+	"analyze": runAnalyze,
+	"list":    runList,
+	"show":    runShow,
+	"export":  runExport,
+	"search":  runSearch,
+	"purge":   runPurge,
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "log service activity to stderr")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: autopsy [-v] import | status | analyze | list dates | show analysis | export | search | purge")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "autopsy: unknown command: %s\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := initLogger(*verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "autopsy: failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.Initialize(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "autopsy: failed to initialize database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close(db)
+
+	a, err := newApp(cfg, logger, db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "autopsy: %v\n", err)
+		database.Close(db)
+		os.Exit(1)
+	}
+
+	if err := command(a, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "autopsy: %v\n", err)
+		database.Close(db)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// errUsage marks errors caused by bad arguments
+var errUsage = errors.New("usage")
+
+// usageError reports bad arguments to a command
+func usageError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{errUsage}, args...)...)
+}
+
+// newApp wires the services the way the server does
+func newApp(cfg *config.Config, logger *zap.Logger, db *gorm.DB) (*app, error) {
+	store := repository.New(db)
+	uploadService := services.NewUploadService(cfg, logger, store)
+	progressService := services.NewImportProgressService(cfg, logger, store)
+	extractionService := services.NewExtractionService(cfg, logger, store, progressService)
+	parserService := services.NewParserService(cfg, logger, store, progressService, extractionService)
+	threadService := services.NewThreadService(cfg, logger, store, progressService)
+	promptService := services.NewPromptService(cfg, logger, store)
+	aiCacheService := services.NewAICacheService(cfg, logger, store)
+	usageService := services.NewUsageService(cfg, logger, store)
+	redactionService := services.NewRedactionService(cfg, logger)
+	secretScanService := services.NewSecretScanService(cfg, logger, store)
+	analysisService := services.NewAnalysisService(cfg, logger, store, promptService, aiCacheService, usageService, redactionService)
+	mediaService := services.NewMediaService(cfg, logger, store, extractionService)
+	pipelineService := services.NewPipelineService(cfg, logger, store, extractionService, parserService, threadService, analysisService, secretScanService, mediaService, progressService)
+	searchService := services.NewSearchService(cfg, logger, store)
+
+	if err := redactionService.LoadKey(); err != nil {
+		return nil, fmt.Errorf("failed to load redaction key: %w", err)
+	}
+
+	return &app{
+		cfg:      cfg,
+		log:      logger,
+		store:    store,
+		uploads:  uploadService,
+		pipeline: pipelineService,
+		analysis: analysisService,
+		search:   searchService,
+	}, nil
+}
+
+// initLogger logs warnings and errors only, unless verbose
+func initLogger(verbose bool) (*zap.Logger, error) {
+	config := zap.NewDevelopmentConfig()
+	if !verbose {
+		config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	}
+	config.OutputPaths = []string{"stderr"}
+	config.ErrorOutputPaths = []string{"stderr"}
+
+	return config.Build()
+}
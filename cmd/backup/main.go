@@ -0,0 +1,118 @@
+// Command backup creates, checks and restores backup archives of the
+// database and data directories, using the same configuration as the
+// server. Stop the server before restoring.
+//
+//	backup create              write a new archive to the backups directory
+//	backup list                list stored archives, newest first
+//	backup verify <archive>    check an archive without restoring it
+//	backup restore <archive>   verify an archive, then replace everything with it
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/repository"
+	"chatgpt-autopsy-go/internal/services"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: backup create | list | verify <archive> | restore <archive>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		logger.Fatal("Failed to open database", zap.Error(err))
+	}
+	defer database.Close(db)
+
+	backupService := services.NewBackupService(cfg, logger, repository.New(db), nil)
+	if err := run(backupService, flag.Args()); err != nil {
+		logger.Error("Backup command failed", zap.Error(err))
+		database.Close(db)
+		os.Exit(1)
+	}
+}
+
+// run executes one backup subcommand
+func run(backupService *services.BackupService, args []string) error {
+	switch args[0] {
+	case "create":
+		backup, manifest, err := backupService.CreateBackup()
+		if err != nil {
+			return err
+		}
+		path, err := backupService.BackupPath(backup.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s (%d files, %d bytes)\n", path, len(manifest.Files), backup.Size)
+		return nil
+	case "list":
+		return printBackups(backupService)
+	case "verify":
+		if len(args) < 2 {
+			return errors.New("verify needs an archive")
+		}
+		manifest, err := backupService.VerifyBackup(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is intact: %d files, schema version %d, created %s\n",
+			args[1], len(manifest.Files), manifest.SchemaVersion, manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+		return nil
+	case "restore":
+		if len(args) < 2 {
+			return errors.New("restore needs an archive")
+		}
+		report, err := backupService.RestoreBackup(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d files from %s (%d stored paths updated)\n", report.FilesRestored, args[1], report.PathsRewritten)
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// printBackups lists the stored archives
+func printBackups(backupService *services.BackupService) error {
+	backups, err := backupService.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tCREATED")
+	for _, backup := range backups {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", backup.Name, backup.Size, backup.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
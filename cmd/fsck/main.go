@@ -0,0 +1,101 @@
+// Command fsck checks that database rows and files on disk agree, using the
+// same configuration as the server, and prints every inconsistency by
+// category. It exits with status 1 if any remain. Stop the server before
+// repairing.
+//
+//	fsck            report inconsistencies
+//	fsck -repair    repair what can be rebuilt from the other side
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/repository"
+	"chatgpt-autopsy-go/internal/services"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "repair inconsistencies where possible")
+	flag.Parse()
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	db, err := database.Initialize(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize database", zap.Error(err))
+	}
+	defer database.Close(db)
+	store := repository.New(db)
+
+	// The parser rewrites message files during repair
+	progressService := services.NewImportProgressService(cfg, logger, store)
+	extractionService := services.NewExtractionService(cfg, logger, store, progressService)
+	parserService := services.NewParserService(cfg, logger, store, progressService, extractionService)
+	fsckService := services.NewFsckService(cfg, logger, store, parserService, nil)
+
+	report, err := fsckService.Run(*repair)
+	if err != nil {
+		logger.Error("Consistency check failed", zap.Error(err))
+		database.Close(db)
+		os.Exit(1)
+	}
+
+	if printReport(report) > 0 {
+		database.Close(db)
+		os.Exit(1)
+	}
+}
+
+// printReport lists the issues by category and returns how many remain
+// unrepaired
+func printReport(report *services.FsckReport) int {
+	categories := make([]string, 0, len(report.Issues))
+	for category := range report.Issues {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		issues := report.Issues[category]
+		fmt.Printf("%s (%d)\n", category, len(issues))
+		for _, issue := range issues {
+			line := "  "
+			if issue.ID != 0 {
+				line += fmt.Sprintf("#%d ", issue.ID)
+			}
+			if issue.Path != "" {
+				line += issue.Path + ": "
+			}
+			line += issue.Detail
+			if issue.Repaired {
+				line += " [repaired]"
+			} else if issue.RepairError != "" {
+				line += " [repair failed: " + issue.RepairError + "]"
+			}
+			fmt.Println(line)
+		}
+	}
+	for _, message := range report.Errors {
+		fmt.Println("error:", message)
+	}
+
+	fmt.Printf("%d issues found, %d repaired\n", report.Found, report.Repaired)
+	return report.Found - report.Repaired + len(report.Errors)
+}
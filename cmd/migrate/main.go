@@ -0,0 +1,119 @@
+// Command migrate applies and rolls back database schema migrations, using
+// the same configuration as the server.
+//
+//	migrate status         list migrations and whether they are applied
+//	migrate up             apply every pending migration
+//	migrate down [steps]   roll back the newest migrations (default 1)
+//	migrate to <version>   migrate up or down to a version; 0 drops everything
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/database"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migrate status | up | down [steps] | to <version>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		logger.Fatal("Failed to open database", zap.Error(err))
+	}
+	defer database.Close(db)
+
+	if err := run(db, logger, flag.Args()); err != nil {
+		logger.Error("Migration failed", zap.Error(err))
+		database.Close(db)
+		os.Exit(1)
+	}
+}
+
+// run executes one migrate subcommand
+func run(db *gorm.DB, logger *zap.Logger, args []string) error {
+	switch args[0] {
+	case "status":
+		return printStatus(db)
+	case "up":
+		return database.Migrate(db, logger)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		return database.Rollback(db, logger, steps)
+	case "to":
+		if len(args) < 2 {
+			return errors.New("to needs a version")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		return database.MigrateTo(db, logger, version)
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// printStatus lists every migration with when it was applied
+func printStatus(db *gorm.DB) error {
+	states, err := database.MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, state := range states {
+		applied := "pending"
+		if state.AppliedAt != nil {
+			applied = state.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		if state.Unknown {
+			applied += " (unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", state.Version, state.Name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err := database.CheckSchema(db); err != nil {
+		fmt.Println(err)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"chatgpt-autopsy-go/internal/api"
 	"chatgpt-autopsy-go/internal/config"
 	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/repository"
 	"chatgpt-autopsy-go/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -36,25 +37,82 @@ func main() {
 	}
 
 	// Initialize database
-	if err := database.Initialize(cfg, logger); err != nil {
+	db, err := database.Initialize(cfg, logger)
+	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
-	defer database.Close()
+	defer database.Close(db)
+	store := repository.New(db)
 
 	// Initialize services
-	uploadService := services.NewUploadService(cfg, logger)
-	extractionService := services.NewExtractionService(cfg, logger)
-	parserService := services.NewParserService(cfg, logger)
-	threadService := services.NewThreadService(cfg, logger)
-	analysisService := services.NewAnalysisService(cfg, logger)
+	uploadService := services.NewUploadService(cfg, logger, store)
+	uploadSessionService := services.NewUploadSessionService(cfg, logger, store, uploadService)
+	progressService := services.NewImportProgressService(cfg, logger, store)
+	extractionService := services.NewExtractionService(cfg, logger, store, progressService)
+	parserService := services.NewParserService(cfg, logger, store, progressService, extractionService)
+	threadService := services.NewThreadService(cfg, logger, store, progressService)
+	promptService := services.NewPromptService(cfg, logger, store)
+	aiCacheService := services.NewAICacheService(cfg, logger, store)
+	usageService := services.NewUsageService(cfg, logger, store)
+	redactionService := services.NewRedactionService(cfg, logger)
+	secretScanService := services.NewSecretScanService(cfg, logger, store)
+	analysisService := services.NewAnalysisService(cfg, logger, store, promptService, aiCacheService, usageService, redactionService)
+	mediaService := services.NewMediaService(cfg, logger, store, extractionService)
+	pipelineService := services.NewPipelineService(cfg, logger, store, extractionService, parserService, threadService, analysisService, secretScanService, mediaService, progressService)
+	gcService := services.NewGCService(cfg, logger, store, pipelineService, uploadSessionService)
+	inboxService := services.NewInboxService(cfg, logger, uploadService, pipelineService)
+	exportDataService := services.NewExportDataService(cfg, logger, store)
+	searchService := services.NewSearchService(cfg, logger, store)
+	backupService := services.NewBackupService(cfg, logger, store, pipelineService)
+	fsckService := services.NewFsckService(cfg, logger, store, parserService, pipelineService)
+
+	// Load the key that makes redaction placeholders unguessable
+	if err := redactionService.LoadKey(); err != nil {
+		logger.Fatal("Failed to load redaction key", zap.Error(err))
+	}
+
+	// Seed default prompt templates
+	if err := promptService.SeedDefaults(); err != nil {
+		logger.Fatal("Failed to seed prompt templates", zap.Error(err))
+	}
+
+	// Drop expired AI cache entries
+	if removed, err := aiCacheService.PurgeExpired(); err != nil {
+		logger.Warn("Failed to purge expired AI cache entries", zap.Error(err))
+	} else if removed > 0 {
+		logger.Info("Purged expired AI cache entries", zap.Int64("removed", removed))
+	}
+
+	// Start background workers: scheduled garbage collection and the inbox watcher
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	gcService.Start(workerCtx)
+	if err := inboxService.Start(workerCtx); err != nil {
+		logger.Fatal("Failed to start inbox watcher", zap.Error(err))
+	}
 
 	// Initialize handlers
 	handler := api.NewHandler(
+		store,
 		uploadService,
 		extractionService,
 		parserService,
 		threadService,
 		analysisService,
+		promptService,
+		aiCacheService,
+		usageService,
+		redactionService,
+		secretScanService,
+		progressService,
+		pipelineService,
+		gcService,
+		uploadSessionService,
+		exportDataService,
+		mediaService,
+		searchService,
+		backupService,
+		fsckService,
 		logger,
 	)
 
@@ -102,6 +160,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server")
+	stopWorkers()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeAICache removes cached AI completions, optionally filtered by date and provider
+func (h *Handler) PurgeAICache(c *gin.Context) {
+	date := c.Query("date")
+	provider := c.Query("provider")
+
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Date must be in YYYY-MM-DD format", err)
+			return
+		}
+	}
+
+	removed, err := h.aiCacheService.Purge(date, provider)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "PURGE_ERROR", "Failed to purge AI cache", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"removed":  removed,
+		"date":     date,
+		"provider": provider,
+	})
+}
+
+// RunGC runs the garbage collector now and returns what it reclaimed
+func (h *Handler) RunGC(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"report": h.gcService.Run(),
+	})
+}
+
+// GetGC returns the report of the last garbage collection run
+func (h *Handler) GetGC(c *gin.Context) {
+	report := h.gcService.LastRun()
+	if report == nil {
+		h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Garbage collection has not run yet", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// RebuildMessageFiles regenerates the per-date message files from the
+// database, for every date or just the one given in ?date=
+func (h *Handler) RebuildMessageFiles(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		report, err := h.parserService.RebuildMessageFiles()
+		if err != nil {
+			h.errorResponse(c, http.StatusInternalServerError, "REBUILD_ERROR", "Failed to rebuild message files", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"report": report,
+		})
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Date must be in YYYY-MM-DD format", err)
+		return
+	}
+	if err := h.parserService.RewriteMessageFiles([]string{date}); err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "REBUILD_ERROR", "Failed to rebuild message files", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"date": date,
+	})
+}
+
+// RunFsck checks that database rows and files agree and returns every
+// inconsistency by category. With ?repair=true fixable issues are repaired.
+func (h *Handler) RunFsck(c *gin.Context) {
+	report, err := h.fsckService.Run(c.Query("repair") == "true")
+	if err != nil {
+		if contains(err.Error(), "cannot repair while") {
+			h.errorResponse(c, http.StatusConflict, "PIPELINE_BUSY", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "FSCK_ERROR", "Failed to check consistency", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// GetFsck returns the report of the last consistency check
+func (h *Handler) GetFsck(c *gin.Context) {
+	report := h.fsckService.LastRun()
+	if report == nil {
+		h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Consistency check has not run yet", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBackup snapshots the database and data directories into a new
+// archive in the backups directory
+func (h *Handler) CreateBackup(c *gin.Context) {
+	backup, manifest, err := h.backupService.CreateBackup()
+	if err != nil {
+		if contains(err.Error(), "only supported for SQLite") {
+			h.errorResponse(c, http.StatusNotImplemented, "UNSUPPORTED_DATABASE", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "already exists") {
+			h.errorResponse(c, http.StatusConflict, "BACKUP_EXISTS", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "cannot back up while") {
+			h.errorResponse(c, http.StatusConflict, "PIPELINE_BUSY", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "BACKUP_ERROR", "Failed to create backup", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"backup": backup,
+		"files":  len(manifest.Files),
+	})
+}
+
+// ListBackups lists the stored backup archives, newest first
+func (h *Handler) ListBackups(c *gin.Context) {
+	backups, err := h.backupService.ListBackups()
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "BACKUP_ERROR", "Failed to list backups", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+	})
+}
+
+// DownloadBackup streams a stored backup archive
+func (h *Handler) DownloadBackup(c *gin.Context) {
+	archivePath, ok := h.backupPath(c)
+	if !ok {
+		return
+	}
+	c.FileAttachment(archivePath, c.Param("name"))
+}
+
+// RestoreBackup verifies a stored backup archive and, if it is intact,
+// replaces the database and data directories with its contents
+func (h *Handler) RestoreBackup(c *gin.Context) {
+	archivePath, ok := h.backupPath(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.backupService.RestoreBackup(archivePath)
+	if err != nil {
+		switch {
+		case contains(err.Error(), "only supported for SQLite"):
+			h.errorResponse(c, http.StatusNotImplemented, "UNSUPPORTED_DATABASE", err.Error(), err)
+		case contains(err.Error(), "cannot restore while"):
+			h.errorResponse(c, http.StatusConflict, "PIPELINE_BUSY", err.Error(), err)
+		case report == nil && (contains(err.Error(), "invalid backup") || contains(err.Error(), "backup is") ||
+			contains(err.Error(), "unsupported backup") || contains(err.Error(), "backup database") ||
+			contains(err.Error(), "newer than this build")):
+			h.errorResponse(c, http.StatusUnprocessableEntity, "INVALID_BACKUP", err.Error(), err)
+		default:
+			h.errorResponse(c, http.StatusInternalServerError, "RESTORE_ERROR", "Failed to restore backup", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// backupPath resolves the :name parameter to a stored archive, writing the
+// error response if there is none
+func (h *Handler) backupPath(c *gin.Context) (string, bool) {
+	archivePath, err := h.backupService.BackupPath(c.Param("name"))
+	if err != nil {
+		if contains(err.Error(), "invalid backup name") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_NAME", err.Error(), err)
+			return "", false
+		}
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Backup not found", err)
+			return "", false
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "BACKUP_ERROR", "Failed to read backup", err)
+		return "", false
+	}
+	return archivePath, true
+}
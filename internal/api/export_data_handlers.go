@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"chatgpt-autopsy-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccountProfile returns the account profile imported from user.json
+func (h *Handler) GetAccountProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+
+	profile, err := h.exportDataService.GetAccountProfile(uint(id))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Account profile not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "QUERY_ERROR", "Failed to get account profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListFeedback lists thumbs-up/down ratings with counts per rating
+func (h *Handler) ListFeedback(c *gin.Context) {
+	filter, ok := h.exportDataFilter(c)
+	if !ok {
+		return
+	}
+	filter.Rating = c.Query("rating")
+	page, limit := exportDataPage(c)
+
+	feedback, total, err := h.exportDataService.ListFeedback(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list feedback", err)
+		return
+	}
+
+	// Counts cover every rating, whichever one was filtered on
+	summaryFilter := filter
+	summaryFilter.Rating = ""
+	summary, err := h.exportDataService.FeedbackSummary(summaryFilter)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to summarise feedback", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feedback":  feedback,
+		"by_rating": summary,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// ListComparisons lists model comparison choices
+func (h *Handler) ListComparisons(c *gin.Context) {
+	filter, ok := h.exportDataFilter(c)
+	if !ok {
+		return
+	}
+	page, limit := exportDataPage(c)
+
+	comparisons, total, err := h.exportDataService.ListComparisons(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list comparisons", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comparisons": comparisons,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// ListSharedConversations lists conversations shared by public link
+func (h *Handler) ListSharedConversations(c *gin.Context) {
+	filter, ok := h.exportDataFilter(c)
+	if !ok {
+		return
+	}
+	page, limit := exportDataPage(c)
+
+	shares, total, err := h.exportDataService.ListSharedConversations(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list shared conversations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shared_conversations": shares,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// exportDataFilter reads the upload_id and conversation_id query filters
+func (h *Handler) exportDataFilter(c *gin.Context) (services.ExportDataFilter, bool) {
+	var filter services.ExportDataFilter
+	if uploadID := c.Query("upload_id"); uploadID != "" {
+		id, err := strconv.ParseUint(uploadID, 10, 32)
+		if err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+			return filter, false
+		}
+		filter.UploadID = uint(id)
+	}
+	if conversationID := c.Query("conversation_id"); conversationID != "" {
+		id, err := strconv.ParseUint(conversationID, 10, 32)
+		if err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid conversation ID", err)
+			return filter, false
+		}
+		filter.ConversationID = uint(id)
+	}
+	return filter, true
+}
+
+// exportDataPage reads the page and limit query parameters
+func exportDataPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	if limit > 500 {
+		limit = 500
+	}
+	return page, limit
+}
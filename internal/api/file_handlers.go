@@ -0,0 +1,96 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListUploadFiles lists the files catalogued or extracted from an upload
+func (h *Handler) ListUploadFiles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	if limit > 500 {
+		limit = 500
+	}
+
+	files, total, err := h.extractionService.ListFiles(uint(id), c.Query("type"), c.Query("status"), page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list files", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files": files,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetUploadFile serves a file from an upload, extracting it from the stored
+// archive first if it was only catalogued
+func (h *Handler) GetUploadFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+	fileID, err := strconv.ParseUint(c.Param("file_id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid file ID", err)
+		return
+	}
+
+	file, err := h.extractionService.ExtractFile(uint(id), uint(fileID))
+	if err != nil {
+		switch {
+		case contains(err.Error(), "not found"):
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "File not found", err)
+		case contains(err.Error(), "not extracted"), contains(err.Error(), "exceeds"):
+			h.errorResponse(c, http.StatusUnprocessableEntity, "FILE_REJECTED", err.Error(), err)
+		default:
+			h.errorResponse(c, http.StatusInternalServerError, "EXTRACT_ERROR", "Failed to extract file", err)
+		}
+		return
+	}
+
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "File not found", err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "READ_ERROR", "Failed to read file", err)
+		return
+	}
+
+	// Sniff the type from the content rather than trusting the entry's
+	// extension, which comes from the export
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "READ_ERROR", "Failed to read file", err)
+		return
+	}
+
+	name := filepath.Base(file.FilePath)
+	setContentHeaders(c, http.DetectContentType(head[:n]), name)
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+}
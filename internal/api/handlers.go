@@ -5,40 +5,84 @@ import (
 	"strconv"
 	"time"
 
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 	"chatgpt-autopsy-go/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 // Handler holds all handlers and dependencies
 type Handler struct {
+	store            *repository.Store
 	uploadService    *services.UploadService
 	extractionService *services.ExtractionService
 	parserService    *services.ParserService
 	threadService    *services.ThreadService
 	analysisService  *services.AnalysisService
+	promptService    *services.PromptService
+	aiCacheService   *services.AICacheService
+	usageService     *services.UsageService
+	redactionService *services.RedactionService
+	secretScanService *services.SecretScanService
+	progressService  *services.ImportProgressService
+	pipelineService  *services.PipelineService
+	gcService        *services.GCService
+	uploadSessionService *services.UploadSessionService
+	exportDataService *services.ExportDataService
+	mediaService     *services.MediaService
+	searchService    *services.SearchService
+	backupService    *services.BackupService
+	fsckService      *services.FsckService
 	log              *zap.Logger
 }
 
 // NewHandler creates a new handler instance
 func NewHandler(
+	store *repository.Store,
 	uploadService *services.UploadService,
 	extractionService *services.ExtractionService,
 	parserService *services.ParserService,
 	threadService *services.ThreadService,
 	analysisService *services.AnalysisService,
+	promptService *services.PromptService,
+	aiCacheService *services.AICacheService,
+	usageService *services.UsageService,
+	redactionService *services.RedactionService,
+	secretScanService *services.SecretScanService,
+	progressService *services.ImportProgressService,
+	pipelineService *services.PipelineService,
+	gcService *services.GCService,
+	uploadSessionService *services.UploadSessionService,
+	exportDataService *services.ExportDataService,
+	mediaService *services.MediaService,
+	searchService *services.SearchService,
+	backupService *services.BackupService,
+	fsckService *services.FsckService,
 	log *zap.Logger,
 ) *Handler {
 	return &Handler{
+		store:            store,
 		uploadService:    uploadService,
 		extractionService: extractionService,
 		parserService:    parserService,
 		threadService:    threadService,
 		analysisService:  analysisService,
+		promptService:    promptService,
+		aiCacheService:   aiCacheService,
+		usageService:     usageService,
+		redactionService: redactionService,
+		secretScanService: secretScanService,
+		progressService:  progressService,
+		pipelineService:  pipelineService,
+		gcService:        gcService,
+		uploadSessionService: uploadSessionService,
+		exportDataService: exportDataService,
+		mediaService:     mediaService,
+		searchService:    searchService,
+		backupService:    backupService,
+		fsckService:      fsckService,
 		log:              log,
 	}
 }
@@ -54,7 +98,7 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 // ReadyCheck handles readiness check endpoint
 func (h *Handler) ReadyCheck(c *gin.Context) {
 	// Check database connection
-	sqlDB, err := database.DB.DB()
+	sqlDB, err := h.store.DB().DB()
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"status": "not_ready",
@@ -78,15 +122,13 @@ func (h *Handler) ReadyCheck(c *gin.Context) {
 
 // UploadFile handles file upload
 func (h *Handler) UploadFile(c *gin.Context) {
-	file, err := c.FormFile("file")
-	if err != nil {
-		h.errorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided", err)
+	if h.pipelinePaused(c) {
 		return
 	}
 
-	// Validate file extension
-	if !isValidZipFile(file.Filename) {
-		h.errorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a ZIP file", nil)
+	file, err := c.FormFile("file")
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided", err)
 		return
 	}
 
@@ -105,29 +147,18 @@ func (h *Handler) UploadFile(c *gin.Context) {
 			h.errorResponse(c, http.StatusConflict, "DUPLICATE_UPLOAD", err.Error(), err)
 			return
 		}
+		if contains(err.Error(), "invalid file type") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", err.Error(), err)
+			return
+		}
 		h.errorResponse(c, http.StatusInternalServerError, "UPLOAD_ERROR", "Failed to upload file", err)
 		return
 	}
 
-	// Trigger async extraction
-	go func() {
-		if err := h.extractionService.ExtractUpload(upload.ID); err != nil {
-			h.log.Error("Extraction failed", zap.Uint("upload_id", upload.ID), zap.Error(err))
-			return
-		}
-
-		// Trigger parsing
-		if err := h.parserService.ParseUpload(upload.ID); err != nil {
-			h.log.Error("Parsing failed", zap.Uint("upload_id", upload.ID), zap.Error(err))
-			return
-		}
-
-		// Trigger thread creation
-		if err := h.threadService.CreateThreadsForUpload(upload.ID); err != nil {
-			h.log.Error("Thread creation failed", zap.Uint("upload_id", upload.ID), zap.Error(err))
-			return
-		}
-	}()
+	// Trigger async extraction, parsing and thread creation
+	if err := h.pipelineService.Start(upload.ID); err != nil {
+		h.log.Error("Failed to start import pipeline", zap.Uint("upload_id", upload.ID), zap.Error(err))
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"upload": upload,
@@ -182,7 +213,8 @@ func (h *Handler) GetUpload(c *gin.Context) {
 	})
 }
 
-// DeleteUpload deletes an upload
+// DeleteUpload deletes an upload. With purge=true everything derived from
+// it is removed immediately instead of waiting for garbage collection.
 func (h *Handler) DeleteUpload(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -190,6 +222,32 @@ func (h *Handler) DeleteUpload(c *gin.Context) {
 		return
 	}
 
+	if c.Query("purge") == "true" {
+		report, err := h.pipelineService.Purge(uint(id))
+		if err != nil {
+			if contains(err.Error(), "not found") {
+				h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload not found", err)
+				return
+			}
+			if contains(err.Error(), "already being processed") {
+				h.errorResponse(c, http.StatusConflict, "ALREADY_PROCESSING", err.Error(), err)
+				return
+			}
+			if contains(err.Error(), "cannot be processed while") {
+				h.errorResponse(c, http.StatusConflict, "PIPELINE_PAUSED", err.Error(), err)
+				return
+			}
+			h.errorResponse(c, http.StatusInternalServerError, "DELETE_ERROR", "Failed to purge upload", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Upload purged successfully",
+			"report":  report,
+		})
+		return
+	}
+
 	if err := h.uploadService.DeleteUpload(uint(id)); err != nil {
 		if contains(err.Error(), "not found") {
 			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload not found", err)
@@ -216,13 +274,18 @@ func (h *Handler) ListConversations(c *gin.Context) {
 	var conversations []models.Conversation
 	var total int64
 
-	query := database.DB.Model(&models.Conversation{})
+	query := h.store.Conversations.Query()
 
 	// Filter by upload_id if provided
 	if uploadID := c.Query("upload_id"); uploadID != "" {
 		query = query.Where("upload_id = ?", uploadID)
 	}
 
+	// Filter by source if provided
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+
 	// Get total count
 	if err := query.Count(&total).Error; err != nil {
 		h.errorResponse(c, http.StatusInternalServerError, "COUNT_ERROR", "Failed to count conversations", err)
@@ -255,8 +318,8 @@ func (h *Handler) GetConversation(c *gin.Context) {
 	}
 
 	var conversation models.Conversation
-	if err := database.DB.Preload("Messages").First(&conversation, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	if err := h.store.Conversations.Query().Preload("Messages").First(&conversation, id).Error; err != nil {
+		if err == repository.ErrNotFound {
 			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Conversation not found", err)
 			return
 		}
@@ -272,7 +335,7 @@ func (h *Handler) GetConversation(c *gin.Context) {
 // ListDates lists all analysis dates
 func (h *Handler) ListDates(c *gin.Context) {
 	var dates []string
-	if err := database.DB.Model(&models.Thread{}).
+	if err := h.store.Threads.Query().
 		Distinct("date").
 		Order("date DESC").
 		Pluck("date", &dates).Error; err != nil {
@@ -290,9 +353,9 @@ func (h *Handler) GetAnalysis(c *gin.Context) {
 	date := c.Param("date")
 	analysisType := c.Param("type")
 
-	var analysis models.Analysis
-	if err := database.DB.Where("date = ? AND analysis_type = ?", date, analysisType).First(&analysis).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	analysis, err := h.store.Analyses.ForDateAndType(date, analysisType)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Analysis not found", err)
 			return
 		}
@@ -305,7 +368,19 @@ func (h *Handler) GetAnalysis(c *gin.Context) {
 	})
 }
 
-// errorResponse sends a standardized error response
+// pipelinePaused writes a 503 response while a backup or restore holds the
+// pipeline, so no upload is written into data that is being snapshotted or
+// replaced
+func (h *Handler) pipelinePaused(c *gin.Context) bool {
+	reason := h.pipelineService.Paused()
+	if reason == "" {
+		return false
+	}
+	c.Header("Retry-After", "30")
+	h.errorResponse(c, http.StatusServiceUnavailable, "PIPELINE_PAUSED", "Uploads cannot be accepted while "+reason, nil)
+	return true
+}
+
 func (h *Handler) errorResponse(c *gin.Context, status int, code, message string, err error) {
 	requestID, _ := c.Get("request_id")
 	
@@ -330,10 +405,6 @@ func (h *Handler) errorResponse(c *gin.Context, status int, code, message string
 }
 
 // Helper functions
-func isValidZipFile(filename string) bool {
-	return len(filename) > 4 && filename[len(filename)-4:] == ".zip"
-}
-
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && 
 		(s == substr || 
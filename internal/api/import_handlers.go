@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chatgpt-autopsy-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// importHeartbeatInterval keeps idle event streams open through proxies
+const importHeartbeatInterval = 15 * time.Second
+
+// GetImport returns a snapshot of an upload's import progress
+func (h *Handler) GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+
+	importRecord, err := h.progressService.GetImport(uint(id))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Import not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "GET_ERROR", "Failed to get import", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"import": services.NewImportEvent(importRecord),
+	})
+}
+
+// StreamImportEvents streams import progress as Server-Sent Events. The
+// current state is sent first; the stream ends once the import completes or
+// fails.
+func (h *Handler) StreamImportEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+	uploadID := uint(id)
+
+	// Subscribe before reading the snapshot so no transition is missed
+	events, unsubscribe := h.progressService.Watch(uploadID)
+	defer unsubscribe()
+
+	importRecord, err := h.progressService.GetImport(uploadID)
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Import not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "GET_ERROR", "Failed to get import", err)
+		return
+	}
+
+	// Streams outlive the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Debug("Failed to clear write deadline for event stream", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	snapshot := services.NewImportEvent(importRecord)
+	c.SSEvent("progress", snapshot)
+	c.Writer.Flush()
+	if snapshot.Terminal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(importHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent("progress", event)
+			c.Writer.Flush()
+			if event.Terminal() {
+				return
+			}
+		}
+	}
+}
+
+// reprocessRequest is the body for reprocessing an upload
+type reprocessRequest struct {
+	Stages []string `json:"stages"`
+}
+
+// ReprocessUpload rebuilds the selected stages of an upload in the background
+func (h *Handler) ReprocessUpload(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+		return
+	}
+
+	var req reprocessRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+			return
+		}
+	}
+	if len(req.Stages) == 0 {
+		req.Stages = []string{services.StageExtract}
+	}
+
+	stages, err := h.pipelineService.Reprocess(uint(id), req.Stages)
+	if err != nil {
+		if contains(err.Error(), "invalid") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload not found", err)
+			return
+		}
+		if contains(err.Error(), "already being processed") {
+			h.errorResponse(c, http.StatusConflict, "ALREADY_PROCESSING", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "cannot be processed while") {
+			h.errorResponse(c, http.StatusConflict, "PIPELINE_PAUSED", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "missing") {
+			h.errorResponse(c, http.StatusConflict, "FILE_MISSING", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "REPROCESS_ERROR", "Failed to start reprocessing", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":   "Reprocessing started",
+		"upload_id": id,
+		"stages":    stages,
+	})
+}
+
+// importPathRequest is the body for importing a file already on the server
+type importPathRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// ImportFromPath uploads an export file from the server's import directory
+func (h *Handler) ImportFromPath(c *gin.Context) {
+	if h.pipelinePaused(c) {
+		return
+	}
+
+	var req importPathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	upload, err := h.uploadService.ImportFromPath(req.Path)
+	if err != nil {
+		switch {
+		case contains(err.Error(), "disabled"):
+			h.errorResponse(c, http.StatusForbidden, "IMPORT_DISABLED", err.Error(), err)
+		case contains(err.Error(), "invalid"), contains(err.Error(), "exceeds"):
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_PATH", err.Error(), err)
+		case contains(err.Error(), "not found"):
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), err)
+		case contains(err.Error(), "already uploaded"):
+			h.errorResponse(c, http.StatusConflict, "DUPLICATE_UPLOAD", err.Error(), err)
+		default:
+			h.errorResponse(c, http.StatusInternalServerError, "UPLOAD_ERROR", "Failed to import file", err)
+		}
+		return
+	}
+
+	if err := h.pipelineService.Start(upload.ID); err != nil {
+		h.log.Error("Failed to start import pipeline", zap.Uint("upload_id", upload.ID), zap.Error(err))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"upload": upload,
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMedia lists the images and audio catalogued from uploads
+func (h *Handler) ListMedia(c *gin.Context) {
+	filter := services.MediaFilter{
+		Date: c.Query("date"),
+		Kind: c.Query("kind"),
+	}
+	if uploadID := c.Query("upload_id"); uploadID != "" {
+		id, err := strconv.ParseUint(uploadID, 10, 32)
+		if err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid upload ID", err)
+			return
+		}
+		filter.UploadID = uint(id)
+	}
+	if conversationID := c.Query("conversation_id"); conversationID != "" {
+		id, err := strconv.ParseUint(conversationID, 10, 32)
+		if err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid conversation ID", err)
+			return
+		}
+		filter.ConversationID = uint(id)
+	}
+	h.listMedia(c, filter)
+}
+
+// ListConversationMedia lists the media referenced by a conversation's messages
+func (h *Handler) ListConversationMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid conversation ID", err)
+		return
+	}
+	h.listMedia(c, services.MediaFilter{
+		ConversationID: uint(id),
+		Date:           c.Query("date"),
+		Kind:           c.Query("kind"),
+	})
+}
+
+// listMedia writes one page of media matching filter
+func (h *Handler) listMedia(c *gin.Context, filter services.MediaFilter) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	if limit > 500 {
+		limit = 500
+	}
+
+	if filter.Date != "" {
+		if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Date must be in YYYY-MM-DD format", err)
+			return
+		}
+	}
+
+	media, total, err := h.mediaService.ListMedia(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list media", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"media": media,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetMedia returns a media file's details and the messages referencing it
+func (h *Handler) GetMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid media ID", err)
+		return
+	}
+
+	media, messages, err := h.mediaService.GetMedia(uint(id))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Media not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "QUERY_ERROR", "Failed to get media", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"media":    media,
+		"messages": messages,
+	})
+}
+
+// StreamMedia serves a media file with its sniffed Content-Type. Range and
+// conditional requests are supported.
+func (h *Handler) StreamMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid media ID", err)
+		return
+	}
+
+	media, file, err := h.mediaService.OpenMedia(uint(id))
+	if err != nil {
+		switch {
+		case contains(err.Error(), "not found"):
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Media not found", err)
+		case contains(err.Error(), "not extracted"), contains(err.Error(), "exceeds"):
+			h.errorResponse(c, http.StatusUnprocessableEntity, "FILE_REJECTED", err.Error(), err)
+		default:
+			h.errorResponse(c, http.StatusInternalServerError, "EXTRACT_ERROR", "Failed to extract media file", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "READ_ERROR", "Failed to read media file", err)
+		return
+	}
+
+	setContentHeaders(c, media.MimeType, media.FileName)
+	c.Header("ETag", `"`+media.ContentHash+`"`)
+	http.ServeContent(c.Writer, c.Request, media.FileName, info.ModTime(), file)
+}
+
+// setContentHeaders sets the type of a file served from an export. Only
+// media is shown inline; anything else, such as HTML, is sent as a download
+// so it cannot run in the API's origin.
+func setContentHeaders(c *gin.Context, mimeType, fileName string) {
+	c.Header("X-Content-Type-Options", "nosniff")
+	if inlineMediaType(mimeType) {
+		c.Header("Content-Type", mimeType)
+		return
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+}
+
+// inlineMediaType reports whether a sniffed MIME type is safe to serve
+// inline. SVG is an image type that can carry script, so it never is.
+func inlineMediaType(mimeType string) bool {
+	if mimeType == "image/svg+xml" {
+		return false
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return mimeType == "application/pdf"
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promptRequest is the body for creating or updating a prompt template
+type promptRequest struct {
+	Name        string  `json:"name"`
+	Body        string  `json:"body" binding:"required"`
+	Description *string `json:"description"`
+	Activate    *bool   `json:"activate"`
+}
+
+// dryRunRequest is the body for rendering prompts without calling a provider
+type dryRunRequest struct {
+	Date       string `json:"date" binding:"required"`
+	Name       string `json:"name"`
+	TemplateID *uint  `json:"template_id"`
+}
+
+// ListPrompts lists prompt templates
+func (h *Handler) ListPrompts(c *gin.Context) {
+	templates, err := h.promptService.ListTemplates(c.Query("name"))
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list prompt templates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompts": templates,
+	})
+}
+
+// GetPrompt gets a prompt template version
+func (h *Handler) GetPrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid prompt template ID", err)
+		return
+	}
+
+	tmpl, err := h.promptService.GetTemplate(uint(id))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Prompt template not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "GET_ERROR", "Failed to get prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompt": tmpl,
+	})
+}
+
+// CreatePrompt creates a new version of a named prompt template
+func (h *Handler) CreatePrompt(c *gin.Context) {
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	activate := req.Activate == nil || *req.Activate
+	tmpl, err := h.promptService.CreateVersion(req.Name, req.Body, req.Description, activate)
+	if err != nil {
+		if contains(err.Error(), "invalid") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_TEMPLATE", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "CREATE_ERROR", "Failed to create prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"prompt": tmpl,
+	})
+}
+
+// UpdatePrompt creates a new version from an existing prompt template
+func (h *Handler) UpdatePrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid prompt template ID", err)
+		return
+	}
+
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	activate := req.Activate == nil || *req.Activate
+	tmpl, err := h.promptService.UpdateTemplate(uint(id), req.Body, req.Description, activate)
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Prompt template not found", err)
+			return
+		}
+		if contains(err.Error(), "invalid") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_TEMPLATE", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "UPDATE_ERROR", "Failed to update prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"prompt": tmpl,
+	})
+}
+
+// ActivatePrompt makes a prompt template version the active one
+func (h *Handler) ActivatePrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid prompt template ID", err)
+		return
+	}
+
+	tmpl, err := h.promptService.ActivateTemplate(uint(id))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Prompt template not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "UPDATE_ERROR", "Failed to activate prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompt": tmpl,
+	})
+}
+
+// DeletePrompt deletes a prompt template version
+func (h *Handler) DeletePrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid prompt template ID", err)
+		return
+	}
+
+	if err := h.promptService.DeleteTemplate(uint(id)); err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Prompt template not found", err)
+			return
+		}
+		if contains(err.Error(), "cannot delete") {
+			h.errorResponse(c, http.StatusConflict, "LAST_VERSION", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "DELETE_ERROR", "Failed to delete prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Prompt template deleted successfully",
+	})
+}
+
+// DryRunPrompt renders the final prompts for a date without calling a provider
+func (h *Handler) DryRunPrompt(c *gin.Context) {
+	var req dryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Date must be in YYYY-MM-DD format", err)
+		return
+	}
+
+	if req.TemplateID != nil && req.Name == "" {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "name is required when template_id is set", nil)
+		return
+	}
+
+	previews, err := h.analysisService.PreviewPrompts(req.Date, req.Name, req.TemplateID)
+	if err != nil {
+		if contains(err.Error(), "not found") || contains(err.Error(), "no threads") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "invalid") || contains(err.Error(), "is not a") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "RENDER_ERROR", "Failed to render prompts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":    req.Date,
+		"prompts": previews,
+	})
+}
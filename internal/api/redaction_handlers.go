@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactionPreviewRequest is the body for a redaction preview. Either text or
+// date must be set; name limits a date preview to one prompt template.
+type redactionPreviewRequest struct {
+	Text string `json:"text"`
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// PreviewRedaction shows exactly what would be sent to an AI provider
+func (h *Handler) PreviewRedaction(c *gin.Context) {
+	var req redactionPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	if req.Text != "" {
+		redacted, stats := h.redactionService.Preview(req.Text)
+		c.JSON(http.StatusOK, gin.H{
+			"redacted": redacted,
+			"stats":    stats,
+		})
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Provide text or a date in YYYY-MM-DD format", err)
+		return
+	}
+
+	previews, err := h.analysisService.PreviewRedaction(req.Date, req.Name)
+	if err != nil {
+		if contains(err.Error(), "not found") || contains(err.Error(), "no threads") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), err)
+			return
+		}
+		if contains(err.Error(), "invalid") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "RENDER_ERROR", "Failed to preview redaction", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":    req.Date,
+		"prompts": previews,
+	})
+}
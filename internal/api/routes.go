@@ -18,11 +18,28 @@ func SetupRoutes(router *gin.Engine, handler *Handler, cfg *config.Config) {
 
 		// Upload endpoints
 		v1.POST("/upload", handler.UploadFile)
+		v1.POST("/upload/path", handler.ImportFromPath)
 		uploads := v1.Group("/uploads")
 		{
 			uploads.GET("", handler.ListUploads)
 			uploads.GET("/:id", handler.GetUpload)
+			uploads.GET("/:id/import", handler.GetImport)
+			uploads.GET("/:id/import/events", handler.StreamImportEvents)
 			uploads.DELETE("/:id", handler.DeleteUpload)
+			uploads.POST("/:id/reprocess", handler.ReprocessUpload)
+			uploads.GET("/:id/files", handler.ListUploadFiles)
+			uploads.GET("/:id/files/:file_id", handler.GetUploadFile)
+			uploads.GET("/:id/account", handler.GetAccountProfile)
+		}
+
+		// Resumable upload endpoints
+		sessions := v1.Group("/upload-sessions")
+		{
+			sessions.POST("", handler.CreateUploadSession)
+			sessions.HEAD("/:id", handler.GetUploadSession)
+			sessions.GET("/:id", handler.GetUploadSession)
+			sessions.PATCH("/:id", handler.WriteUploadChunk)
+			sessions.DELETE("/:id", handler.CancelUploadSession)
 		}
 
 		// Conversation endpoints
@@ -30,6 +47,23 @@ func SetupRoutes(router *gin.Engine, handler *Handler, cfg *config.Config) {
 		{
 			conversations.GET("", handler.ListConversations)
 			conversations.GET("/:id", handler.GetConversation)
+			conversations.GET("/:id/media", handler.ListConversationMedia)
+		}
+
+		// Search endpoints
+		v1.GET("/search", handler.SearchMessages)
+
+		// Export data endpoints
+		v1.GET("/feedback", handler.ListFeedback)
+		v1.GET("/comparisons", handler.ListComparisons)
+		v1.GET("/shared-conversations", handler.ListSharedConversations)
+
+		// Media endpoints
+		media := v1.Group("/media")
+		{
+			media.GET("", handler.ListMedia)
+			media.GET("/:id", handler.GetMedia)
+			media.GET("/:id/content", handler.StreamMedia)
 		}
 
 		// Analysis endpoints
@@ -39,6 +73,52 @@ func SetupRoutes(router *gin.Engine, handler *Handler, cfg *config.Config) {
 		{
 			analysis.GET("/:date/:type", handler.GetAnalysis)
 		}
+
+		// Prompt template endpoints
+		prompts := v1.Group("/prompts")
+		{
+			prompts.GET("", handler.ListPrompts)
+			prompts.POST("", handler.CreatePrompt)
+			prompts.POST("/dry-run", handler.DryRunPrompt)
+			prompts.GET("/:id", handler.GetPrompt)
+			prompts.PUT("/:id", handler.UpdatePrompt)
+			prompts.DELETE("/:id", handler.DeletePrompt)
+			prompts.POST("/:id/activate", handler.ActivatePrompt)
+		}
+
+		// Redaction endpoints
+		v1.POST("/redaction/preview", handler.PreviewRedaction)
+
+		// Security endpoints
+		security := v1.Group("/security")
+		{
+			security.GET("/secrets", handler.ListSecretFindings)
+			security.POST("/secrets/scan", handler.ScanSecrets)
+		}
+
+		// AI usage endpoints
+		usage := v1.Group("/usage")
+		{
+			usage.GET("", handler.GetUsage)
+			usage.GET("/daily", handler.GetUsageByGroup("day"))
+			usage.GET("/providers", handler.GetUsageByGroup("provider"))
+			usage.GET("/dimensions", handler.GetUsageByGroup("dimension"))
+		}
+
+		// Admin endpoints
+		admin := v1.Group("/admin")
+		{
+			admin.DELETE("/ai-cache", handler.PurgeAICache)
+			admin.GET("/gc", handler.GetGC)
+			admin.POST("/gc", handler.RunGC)
+			admin.POST("/messages/rebuild", handler.RebuildMessageFiles)
+			admin.GET("/fsck", handler.GetFsck)
+			admin.POST("/fsck", handler.RunFsck)
+			admin.GET("/backups", handler.ListBackups)
+			admin.POST("/backups", handler.CreateBackup)
+			admin.GET("/backups/:name", handler.DownloadBackup)
+			admin.POST("/backups/:name/restore", handler.RestoreBackup)
+		}
 	}
 }
 
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"chatgpt-autopsy-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchMessages searches message content for every word of ?q=
+func (h *Handler) SearchMessages(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_QUERY", "Search query is required", nil)
+		return
+	}
+
+	exportFilter, ok := h.exportDataFilter(c)
+	if !ok {
+		return
+	}
+	filter := services.SearchFilter{
+		Query:          query,
+		UploadID:       exportFilter.UploadID,
+		ConversationID: exportFilter.ConversationID,
+		Role:           c.Query("role"),
+	}
+	page, limit := exportDataPage(c)
+
+	messages, total, err := h.searchService.SearchMessages(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "SEARCH_ERROR", "Failed to search messages", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":    query,
+		"messages": messages,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
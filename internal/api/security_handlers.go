@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"chatgpt-autopsy-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListSecretFindings lists credentials found in the message archive
+func (h *Handler) ListSecretFindings(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	if limit > 500 {
+		limit = 500
+	}
+
+	filter := services.SecretFindingFilter{
+		SecretType: c.Query("type"),
+		Date:       c.Query("date"),
+	}
+	if filter.Date != "" {
+		if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Date must be in YYYY-MM-DD format", err)
+			return
+		}
+	}
+	if conversationID := c.Query("conversation_id"); conversationID != "" {
+		id, err := strconv.ParseUint(conversationID, 10, 32)
+		if err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid conversation ID", err)
+			return
+		}
+		filter.ConversationID = uint(id)
+	}
+
+	findings, total, err := h.secretScanService.ListFindings(filter, page, limit)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to list secret findings", err)
+		return
+	}
+
+	summary, err := h.secretScanService.Summary()
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "LIST_ERROR", "Failed to summarise secret findings", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"findings": findings,
+		"by_type":  summary,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// ScanSecrets rescans the whole archive for leaked credentials
+func (h *Handler) ScanSecrets(c *gin.Context) {
+	go func() {
+		if _, err := h.secretScanService.ScanAll(); err != nil {
+			h.log.Error("Secret scan failed", zap.Error(err))
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Secret scan started",
+	})
+}
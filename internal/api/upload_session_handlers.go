@@ -0,0 +1,160 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// createUploadSessionRequest is the body for starting a resumable upload
+type createUploadSessionRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+	SHA256   string `json:"sha256"`
+}
+
+// CreateUploadSession starts a resumable upload
+func (h *Handler) CreateUploadSession(c *gin.Context) {
+	if h.pipelinePaused(c) {
+		return
+	}
+
+	var req createUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", err)
+		return
+	}
+
+	session, err := h.uploadSessionService.CreateSession(req.Filename, req.Size, req.SHA256)
+	if err != nil {
+		if contains(err.Error(), "invalid") || contains(err.Error(), "exceeds") {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "SESSION_ERROR", "Failed to create upload session", err)
+		return
+	}
+
+	setUploadSessionHeaders(c, session)
+	c.Header("Location", "/api/v1/upload-sessions/"+session.UUID)
+	c.JSON(http.StatusCreated, gin.H{
+		"session": session,
+	})
+}
+
+// GetUploadSession returns a session; HEAD requests get only the offset headers
+func (h *Handler) GetUploadSession(c *gin.Context) {
+	session, err := h.uploadSessionService.GetSession(c.Param("id"))
+	if err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload session not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "GET_ERROR", "Failed to get upload session", err)
+		return
+	}
+
+	setUploadSessionHeaders(c, session)
+	if c.Request.Method == http.MethodHead {
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+	})
+}
+
+// WriteUploadChunk appends the request body at the Upload-Offset header.
+// The upload is finalised and processing starts when the last byte arrives.
+func (h *Handler) WriteUploadChunk(c *gin.Context) {
+	if h.pipelinePaused(c) {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		h.errorResponse(c, http.StatusBadRequest, "INVALID_OFFSET", "Upload-Offset header must be a non-negative integer", err)
+		return
+	}
+
+	// Large chunks on slow links outlive the server timeouts
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.SetReadDeadline(time.Time{}); err != nil {
+		h.log.Debug("Failed to clear read deadline for upload chunk", zap.Error(err))
+	}
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Debug("Failed to clear write deadline for upload chunk", zap.Error(err))
+	}
+
+	session, err := h.uploadSessionService.WriteChunk(c.Param("id"), offset, c.Request.Body)
+	if session != nil {
+		setUploadSessionHeaders(c, session)
+	}
+	if err != nil {
+		switch {
+		case contains(err.Error(), "not found"):
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload session not found", err)
+		case contains(err.Error(), "offset mismatch"):
+			h.errorResponse(c, http.StatusConflict, "OFFSET_MISMATCH", err.Error(), err)
+		case contains(err.Error(), "expired"):
+			h.errorResponse(c, http.StatusGone, "SESSION_EXPIRED", err.Error(), err)
+		case contains(err.Error(), "already uploaded"):
+			h.errorResponse(c, http.StatusConflict, "DUPLICATE_UPLOAD", err.Error(), err)
+		case contains(err.Error(), "upload session is"), contains(err.Error(), "mismatch"), contains(err.Error(), "exceeds"), contains(err.Error(), "invalid file type"):
+			h.errorResponse(c, http.StatusUnprocessableEntity, "UPLOAD_REJECTED", err.Error(), err)
+		case contains(err.Error(), "interrupted"):
+			h.errorResponse(c, http.StatusBadRequest, "CHUNK_INTERRUPTED", err.Error(), err)
+		default:
+			h.errorResponse(c, http.StatusInternalServerError, "UPLOAD_ERROR", "Failed to write upload chunk", err)
+		}
+		return
+	}
+
+	if session.UploadID != nil {
+		if err := h.pipelineService.Start(*session.UploadID); err != nil {
+			h.log.Error("Failed to start import pipeline", zap.Uint("upload_id", *session.UploadID), zap.Error(err))
+		}
+		upload, err := h.uploadService.GetUpload(*session.UploadID)
+		if err != nil {
+			h.errorResponse(c, http.StatusInternalServerError, "GET_ERROR", "Failed to get upload", err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"session": session,
+			"upload":  upload,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+	})
+}
+
+// CancelUploadSession aborts a resumable upload
+func (h *Handler) CancelUploadSession(c *gin.Context) {
+	if err := h.uploadSessionService.CancelSession(c.Param("id")); err != nil {
+		if contains(err.Error(), "not found") {
+			h.errorResponse(c, http.StatusNotFound, "NOT_FOUND", "Upload session not found", err)
+			return
+		}
+		h.errorResponse(c, http.StatusInternalServerError, "DELETE_ERROR", "Failed to cancel upload session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Upload session cancelled",
+	})
+}
+
+// setUploadSessionHeaders sets the tus-style offset headers for a session
+func setUploadSessionHeaders(c *gin.Context, session *models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.TotalSize, 10))
+	c.Header("Cache-Control", "no-store")
+}
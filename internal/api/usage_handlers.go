@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsage returns total AI usage and the monthly budget status
+func (h *Handler) GetUsage(c *gin.Context) {
+	from, to, ok := h.usageRange(c)
+	if !ok {
+		return
+	}
+
+	totals, err := h.usageService.Totals(from, to)
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "USAGE_ERROR", "Failed to get AI usage", err)
+		return
+	}
+
+	budget, err := h.usageService.Budget()
+	if err != nil {
+		h.errorResponse(c, http.StatusInternalServerError, "USAGE_ERROR", "Failed to get AI budget", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totals": totals,
+		"budget": budget,
+		"from":   from,
+		"to":     to,
+	})
+}
+
+// GetUsageByGroup returns a handler that aggregates AI usage by the given grouping
+func (h *Handler) GetUsageByGroup(groupBy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := h.usageRange(c)
+		if !ok {
+			return
+		}
+
+		groups, err := h.usageService.Aggregate(groupBy, from, to)
+		if err != nil {
+			h.errorResponse(c, http.StatusInternalServerError, "USAGE_ERROR", "Failed to aggregate AI usage", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"group_by": groupBy,
+			"usage":    groups,
+			"from":     from,
+			"to":       to,
+		})
+	}
+}
+
+// usageRange validates the optional from and to query parameters
+func (h *Handler) usageRange(c *gin.Context) (string, string, bool) {
+	from := c.Query("from")
+	to := c.Query("to")
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			h.errorResponse(c, http.StatusBadRequest, "INVALID_DATE", "Dates must be in YYYY-MM-DD format", err)
+			return "", "", false
+		}
+	}
+	return from, to, true
+}
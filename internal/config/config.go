@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,8 +15,11 @@ type Config struct {
 	Database    DatabaseConfig
 	Upload      UploadConfig
 	Directories DirectoriesConfig
+	GC          GCConfig
 	AI          AIConfig
+	Redaction   RedactionConfig
 	Analysis    AnalysisConfig
+	Messages    MessagesConfig
 	RateLimit   RateLimitConfig
 	Logging     LoggingConfig
 }
@@ -31,17 +35,28 @@ type ServerConfig struct {
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	Path            string
+	Driver          string // sqlite or postgres
+	Path            string // SQLite database file
+	DSN             string // PostgreSQL connection string
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	AutoMigrate     bool // apply pending migrations at startup
 }
 
 // UploadConfig holds upload configuration
 type UploadConfig struct {
-	MaxFileSize       int64
-	MaxExtractionSize int64
-	MaxExtractedFiles int
+	MaxFileSize         int64
+	MaxExtractionSize   int64
+	MaxExtractedFiles   int
+	MaxEntrySize        int64         // largest single file extracted from an archive
+	MaxCompressionRatio int64         // archive entries inflating beyond this ratio are rejected
+	ExtractMode         string        // "archive" reads files out of the stored upload, "disk" extracts everything
+	MaxChunkSize        int64         // largest chunk accepted by a resumable upload request
+	SessionTTL          time.Duration // idle resumable upload sessions expire after this
+	ImportBaseDir       string        // server-side imports are limited to this directory, empty disables them
+	InboxDir            string        // watched for new export files, empty disables watching
+	InboxPollInterval   time.Duration
 }
 
 // DirectoriesConfig holds directory paths
@@ -50,6 +65,13 @@ type DirectoriesConfig struct {
 	ExtractedDir string
 	AnalysisDir  string
 	MessagesDir  string
+	BackupsDir   string
+}
+
+// GCConfig holds garbage collector settings
+type GCConfig struct {
+	Interval    time.Duration // 0 disables scheduled runs
+	GracePeriod time.Duration // unreferenced files younger than this are kept
 }
 
 // AIConfig holds AI enhancement configuration
@@ -59,7 +81,31 @@ type AIConfig struct {
 	PreferredProvider   string
 	EnhancementEnabled  bool
 	MaxTokensPerRequest int
+	MaxOutputTokens     int
+	MaxChunks           int
 	Temperature         float64
+	OpenAIModel         string
+	AnthropicModel      string
+	RequestTimeout      time.Duration
+	CacheEnabled        bool
+	CacheTTL            time.Duration
+	MonthlyBudget       float64               // USD per calendar month, 0 for unlimited
+	Prices              map[string]ModelPrice // keyed by model name
+}
+
+// ModelPrice holds the USD price per million tokens for a model
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// RedactionConfig holds redaction settings for content sent to AI providers
+type RedactionConfig struct {
+	Enabled          bool
+	Categories       []string // enabled detector categories
+	EntropyThreshold float64  // bits per character for the secret detector
+	MinSecretLength  int
+	KeyPath          string // per-install HMAC key for placeholders, never sent anywhere
 }
 
 // AnalysisConfig holds analysis configuration
@@ -68,6 +114,11 @@ type AnalysisConfig struct {
 	NoiseDetectionThreshold float64
 }
 
+// MessagesConfig holds settings for the per-date message files
+type MessagesConfig struct {
+	IncludeAssistant bool // write assistant replies alongside user messages
+}
+
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
 	RequestsPerMinute int
@@ -83,6 +134,13 @@ type LoggingConfig struct {
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
+	// SQLite allows a single writer; PostgreSQL can use a real pool
+	dbDriver := getEnv("CHATGPT_AUTOPSY_DB_DRIVER", "sqlite")
+	defaultConns := 1
+	if dbDriver == "postgres" {
+		defaultConns = 10
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:         getEnvInt("CHATGPT_AUTOPSY_PORT", 8080),
@@ -92,21 +150,37 @@ func Load() (*Config, error) {
 			IdleTimeout:  getEnvDuration("CHATGPT_AUTOPSY_IDLE_TIMEOUT", 120*time.Second),
 		},
 		Database: DatabaseConfig{
+			Driver:          dbDriver,
 			Path:            getEnv("CHATGPT_AUTOPSY_DB_PATH", "data/chatgpt_autopsy.db"),
-			MaxOpenConns:    getEnvInt("CHATGPT_AUTOPSY_MAX_OPEN_CONNS", 1),
-			MaxIdleConns:    getEnvInt("CHATGPT_AUTOPSY_MAX_IDLE_CONNS", 1),
+			DSN:             getEnv("CHATGPT_AUTOPSY_DB_DSN", ""),
+			MaxOpenConns:    getEnvInt("CHATGPT_AUTOPSY_MAX_OPEN_CONNS", defaultConns),
+			MaxIdleConns:    getEnvInt("CHATGPT_AUTOPSY_MAX_IDLE_CONNS", defaultConns),
 			ConnMaxLifetime: getEnvDuration("CHATGPT_AUTOPSY_CONN_MAX_LIFETIME", 1*time.Hour),
+			AutoMigrate:     getEnvBool("CHATGPT_AUTOPSY_DB_AUTO_MIGRATE", true),
 		},
 		Upload: UploadConfig{
-			MaxFileSize:       getEnvInt64("CHATGPT_AUTOPSY_MAX_FILE_SIZE", 524288000), // 500MB
-			MaxExtractionSize: getEnvInt64("CHATGPT_AUTOPSY_MAX_EXTRACTION_SIZE", 2147483648), // 2GB
-			MaxExtractedFiles: getEnvInt("CHATGPT_AUTOPSY_MAX_EXTRACTED_FILES", 10000),
+			MaxFileSize:         getEnvInt64("CHATGPT_AUTOPSY_MAX_FILE_SIZE", 524288000), // 500MB
+			MaxExtractionSize:   getEnvInt64("CHATGPT_AUTOPSY_MAX_EXTRACTION_SIZE", 2147483648), // 2GB
+			MaxExtractedFiles:   getEnvInt("CHATGPT_AUTOPSY_MAX_EXTRACTED_FILES", 10000),
+			MaxEntrySize:        getEnvInt64("CHATGPT_AUTOPSY_MAX_ENTRY_SIZE", 1073741824), // 1GB
+			MaxCompressionRatio: getEnvInt64("CHATGPT_AUTOPSY_MAX_COMPRESSION_RATIO", 100),
+			ExtractMode:         getEnv("CHATGPT_AUTOPSY_EXTRACT_MODE", "archive"),
+			MaxChunkSize:        getEnvInt64("CHATGPT_AUTOPSY_UPLOAD_MAX_CHUNK_SIZE", 67108864), // 64MB
+			SessionTTL:          getEnvDuration("CHATGPT_AUTOPSY_UPLOAD_SESSION_TTL", 24*time.Hour),
+			ImportBaseDir:       getEnv("CHATGPT_AUTOPSY_IMPORT_BASE_DIR", ""),
+			InboxDir:            getEnv("CHATGPT_AUTOPSY_INBOX_DIR", ""),
+			InboxPollInterval:   getEnvDuration("CHATGPT_AUTOPSY_INBOX_POLL_INTERVAL", 30*time.Second),
 		},
 		Directories: DirectoriesConfig{
 			UploadsDir:   getEnv("CHATGPT_AUTOPSY_UPLOADS_DIR", "data/uploads"),
 			ExtractedDir: getEnv("CHATGPT_AUTOPSY_EXTRACTED_DIR", "data/extracted"),
 			AnalysisDir:  getEnv("CHATGPT_AUTOPSY_ANALYSIS_DIR", "data/analysis"),
 			MessagesDir:  getEnv("CHATGPT_AUTOPSY_MESSAGES_DIR", "data/messages"),
+			BackupsDir:   getEnv("CHATGPT_AUTOPSY_BACKUPS_DIR", "data/backups"),
+		},
+		GC: GCConfig{
+			Interval:    getEnvDuration("CHATGPT_AUTOPSY_GC_INTERVAL", 24*time.Hour),
+			GracePeriod: getEnvDuration("CHATGPT_AUTOPSY_GC_GRACE_PERIOD", 1*time.Hour),
 		},
 		AI: AIConfig{
 			OpenAIAPIKey:        getEnv("OPENAI_API_KEY", ""),
@@ -114,12 +188,39 @@ func Load() (*Config, error) {
 			PreferredProvider:   getEnv("CHATGPT_AUTOPSY_PREFERRED_AI_PROVIDER", "anthropic"),
 			EnhancementEnabled:  getEnvBool("CHATGPT_AUTOPSY_AI_ENHANCEMENT_ENABLED", false),
 			MaxTokensPerRequest: getEnvInt("CHATGPT_AUTOPSY_MAX_TOKENS_PER_REQUEST", 4000),
+			MaxOutputTokens:     getEnvInt("CHATGPT_AUTOPSY_AI_MAX_OUTPUT_TOKENS", 1024),
+			MaxChunks:           getEnvInt("CHATGPT_AUTOPSY_AI_MAX_CHUNKS", 8),
 			Temperature:         getEnvFloat64("CHATGPT_AUTOPSY_AI_TEMPERATURE", 0.7),
+			OpenAIModel:         getEnv("CHATGPT_AUTOPSY_OPENAI_MODEL", "gpt-4o-mini"),
+			AnthropicModel:      getEnv("CHATGPT_AUTOPSY_ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+			RequestTimeout:      getEnvDuration("CHATGPT_AUTOPSY_AI_REQUEST_TIMEOUT", 120*time.Second),
+			CacheEnabled:        getEnvBool("CHATGPT_AUTOPSY_AI_CACHE_ENABLED", true),
+			CacheTTL:            getEnvDuration("CHATGPT_AUTOPSY_AI_CACHE_TTL", 30*24*time.Hour),
+			MonthlyBudget:       getEnvFloat64("CHATGPT_AUTOPSY_AI_MONTHLY_BUDGET", 0),
+			Prices: getEnvPrices("CHATGPT_AUTOPSY_AI_PRICES", map[string]ModelPrice{
+				"gpt-4o-mini":              {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+				"gpt-4o":                   {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+				"claude-3-5-haiku-latest":  {InputPerMillion: 0.80, OutputPerMillion: 4.00},
+				"claude-3-5-sonnet-latest": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+			}),
+		},
+		Redaction: RedactionConfig{
+			Enabled: getEnvBool("CHATGPT_AUTOPSY_REDACTION_ENABLED", true),
+			Categories: getEnvList("CHATGPT_AUTOPSY_REDACTION_CATEGORIES", []string{
+				"email", "phone", "address", "ip_address", "credit_card",
+				"private_key", "connection_string", "api_key", "jwt", "password", "secret",
+			}),
+			EntropyThreshold: getEnvFloat64("CHATGPT_AUTOPSY_REDACTION_ENTROPY_THRESHOLD", 4.0),
+			MinSecretLength:  getEnvInt("CHATGPT_AUTOPSY_REDACTION_MIN_SECRET_LENGTH", 24),
+			KeyPath:          getEnv("CHATGPT_AUTOPSY_REDACTION_KEY_PATH", "data/redaction.key"),
 		},
 		Analysis: AnalysisConfig{
 			EnableNoiseDetection:  getEnvBool("CHATGPT_AUTOPSY_ENABLE_NOISE_DETECTION", true),
 			NoiseDetectionThreshold: getEnvFloat64("CHATGPT_AUTOPSY_NOISE_DETECTION_THRESHOLD", 0.3),
 		},
+		Messages: MessagesConfig{
+			IncludeAssistant: getEnvBool("CHATGPT_AUTOPSY_MESSAGES_INCLUDE_ASSISTANT", false),
+		},
 		RateLimit: RateLimitConfig{
 			RequestsPerMinute: getEnvInt("CHATGPT_AUTOPSY_REQUESTS_PER_MINUTE", 100),
 			BurstSize:         getEnvInt("CHATGPT_AUTOPSY_BURST_SIZE", 10),
@@ -158,6 +259,53 @@ func (c *Config) Validate() error {
 	if c.Upload.MaxExtractionSize <= 0 {
 		return fmt.Errorf("max extraction size must be positive, got %d", c.Upload.MaxExtractionSize)
 	}
+	if c.Upload.MaxEntrySize <= 0 {
+		return fmt.Errorf("max entry size must be positive, got %d", c.Upload.MaxEntrySize)
+	}
+	if c.Upload.MaxCompressionRatio < 1 {
+		return fmt.Errorf("max compression ratio must be at least 1, got %d", c.Upload.MaxCompressionRatio)
+	}
+	if c.Upload.ExtractMode != "archive" && c.Upload.ExtractMode != "disk" {
+		return fmt.Errorf("extract mode must be archive or disk, got %q", c.Upload.ExtractMode)
+	}
+
+	if c.Upload.MaxChunkSize <= 0 {
+		return fmt.Errorf("max chunk size must be positive, got %d", c.Upload.MaxChunkSize)
+	}
+	if c.Upload.SessionTTL <= 0 {
+		return fmt.Errorf("upload session TTL must be positive, got %s", c.Upload.SessionTTL)
+	}
+
+	if c.Upload.InboxDir != "" && c.Upload.InboxPollInterval <= 0 {
+		return fmt.Errorf("inbox poll interval must be positive, got %s", c.Upload.InboxPollInterval)
+	}
+
+	// Validate garbage collector settings
+	if c.GC.Interval < 0 || c.GC.GracePeriod < 0 {
+		return fmt.Errorf("GC interval and grace period must not be negative")
+	}
+
+	// Validate AI token budget
+	if c.AI.MaxOutputTokens <= 0 || c.AI.MaxOutputTokens >= c.AI.MaxTokensPerRequest {
+		return fmt.Errorf("max output tokens must be positive and below max tokens per request (%d), got %d", c.AI.MaxTokensPerRequest, c.AI.MaxOutputTokens)
+	}
+	if c.AI.MaxChunks < 1 {
+		return fmt.Errorf("max chunks must be at least 1, got %d", c.AI.MaxChunks)
+	}
+	if c.AI.MonthlyBudget < 0 {
+		return fmt.Errorf("monthly AI budget must not be negative, got %f", c.AI.MonthlyBudget)
+	}
+
+	// Validate database driver
+	switch c.Database.Driver {
+	case "sqlite":
+	case "postgres":
+		if c.Database.DSN == "" {
+			return fmt.Errorf("a DSN is required for the postgres database driver")
+		}
+	default:
+		return fmt.Errorf("database driver must be sqlite or postgres, got %q", c.Database.Driver)
+	}
 
 	// Validate database path parent exists (or can be created)
 	dbDir := filepath.Dir(c.Database.Path)
@@ -194,11 +342,35 @@ func (c *Config) resolvePaths() error {
 		return fmt.Errorf("failed to resolve messages directory: %w", err)
 	}
 
+	c.Directories.BackupsDir, err = filepath.Abs(c.Directories.BackupsDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve backups directory: %w", err)
+	}
+
 	c.Database.Path, err = filepath.Abs(c.Database.Path)
 	if err != nil {
 		return fmt.Errorf("failed to resolve database path: %w", err)
 	}
 
+	c.Redaction.KeyPath, err = filepath.Abs(c.Redaction.KeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve redaction key path: %w", err)
+	}
+
+	if c.Upload.ImportBaseDir != "" {
+		c.Upload.ImportBaseDir, err = filepath.Abs(c.Upload.ImportBaseDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve import base directory: %w", err)
+		}
+	}
+
+	if c.Upload.InboxDir != "" {
+		c.Upload.InboxDir, err = filepath.Abs(c.Upload.InboxDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve inbox directory: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -255,3 +427,53 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// getEnvList parses a comma-separated list, dropping empty entries
+func getEnvList(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvPrices parses a price table of the form
+// "model=input:output,model2=input:output" with USD prices per million
+// tokens. Entries override the defaults; malformed entries are ignored.
+func getEnvPrices(key string, defaultValue map[string]ModelPrice) map[string]ModelPrice {
+	prices := make(map[string]ModelPrice, len(defaultValue))
+	for model, price := range defaultValue {
+		prices[model] = price
+	}
+
+	value := os.Getenv(key)
+	if value == "" {
+		return prices
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		model, rates, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		input, output, ok := strings.Cut(rates, ":")
+		if !ok {
+			continue
+		}
+		inputPrice, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			continue
+		}
+		outputPrice, err := strconv.ParseFloat(output, 64)
+		if err != nil {
+			continue
+		}
+		prices[model] = ModelPrice{InputPerMillion: inputPrice, OutputPerMillion: outputPrice}
+	}
+	return prices
+}
@@ -7,42 +7,79 @@ import (
 	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/models"
 
 	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
+// Initialize opens the database, runs migrations and creates the data
+// directories. The connection is returned to be wrapped in a
+// repository.Store; there is no package-level handle.
+func Initialize(cfg *config.Config, log *zap.Logger) (*gorm.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-// Initialize initializes the database connection and runs migrations
-func Initialize(cfg *config.Config, log *zap.Logger) error {
-	// Ensure database directory exists
-	dbDir := filepath.Dir(cfg.Database.Path)
-	if dbDir != "." && dbDir != "" {
-		if err := os.MkdirAll(dbDir, 0755); err != nil {
-			return fmt.Errorf("failed to create database directory: %w", err)
+	// Run migrations, or only check the schema when they are run by hand
+	if cfg.Database.AutoMigrate {
+		if err := Migrate(db, log); err != nil {
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
 		}
+	} else if err := CheckSchema(db); err != nil {
+		return nil, err
+	}
+
+	// Initialize data directories
+	if err := initializeDirectories(cfg, log); err != nil {
+		return nil, fmt.Errorf("failed to initialize directories: %w", err)
+	}
+
+	log.Info("Database initialized successfully",
+		zap.String("driver", cfg.Database.Driver),
+		zap.String("path", cfg.Database.Path),
+	)
+	return db, nil
+}
+
+// Open connects to the configured database and applies the connection
+// settings, without migrating
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Database.Driver {
+	case "postgres":
+		dialector = postgres.Open(cfg.Database.DSN)
+	case "sqlite", "":
+		// Ensure database directory exists
+		dbDir := filepath.Dir(cfg.Database.Path)
+		if dbDir != "." && dbDir != "" {
+			if err := os.MkdirAll(dbDir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %w", err)
+			}
+		}
+		dialector = sqlite.Open(cfg.Database.Path)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Database.Driver)
 	}
 
 	// Open database connection
-	var err error
-	DB, err = gorm.Open(sqlite.Open(cfg.Database.Path), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent), // We'll use zap for logging
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Get underlying SQLite connection to configure
-	sqlDB, err := DB.DB()
+	// Get underlying connection to configure
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database instance: %w", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
 	// Configure connection pool
@@ -50,85 +87,19 @@ func Initialize(cfg *config.Config, log *zap.Logger) error {
 	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
-	// Enable WAL mode for better concurrency
-	if err := DB.Exec("PRAGMA journal_mode = WAL").Error; err != nil {
-		return fmt.Errorf("failed to enable WAL mode: %w", err)
-	}
-
-	// Enable foreign key constraints
-	if err := DB.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
-	// Run migrations
-	if err := runMigrations(log); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	// Initialize data directories
-	if err := initializeDirectories(cfg, log); err != nil {
-		return fmt.Errorf("failed to initialize directories: %w", err)
-	}
-
-	log.Info("Database initialized successfully", zap.String("path", cfg.Database.Path))
-	return nil
-}
-
-// runMigrations runs database migrations
-func runMigrations(log *zap.Logger) error {
-	models := []interface{}{
-		&models.Upload{},
-		&models.Import{},
-		&models.Conversation{},
-		&models.Message{},
-		&models.Thread{},
-		&models.Extraction{},
-		&models.Analysis{},
-		&models.SeenStatus{},
-		&models.ActionableItem{},
-		&models.Question{},
-		&models.NoiseFlag{},
-	}
-
-	for _, model := range models {
-		if err := DB.AutoMigrate(model); err != nil {
-			return fmt.Errorf("failed to migrate model %T: %w", model, err)
+	if db.Dialector.Name() == "sqlite" {
+		// Enable WAL mode for better concurrency
+		if err := db.Exec("PRAGMA journal_mode = WAL").Error; err != nil {
+			return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 		}
-	}
-
-	// Create composite indexes
-	if err := createIndexes(); err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
-	}
 
-	log.Info("Database migrations completed")
-	return nil
-}
-
-// createIndexes creates composite indexes that GORM doesn't create automatically
-func createIndexes() error {
-	indexes := []string{
-		// Thread composite index
-		"CREATE INDEX IF NOT EXISTS idx_threads_conversation_date ON threads(conversation_id, date)",
-		
-		// Message composite index
-		"CREATE INDEX IF NOT EXISTS idx_messages_conversation_index ON messages(conversation_id, message_index)",
-		
-		// Analysis composite index
-		"CREATE INDEX IF NOT EXISTS idx_analyses_date_type ON analyses(date, analysis_type)",
-		
-		// SeenStatus composite indexes
-		"CREATE INDEX IF NOT EXISTS idx_seen_status_date_type ON seen_statuses(date, analysis_type)",
-		"CREATE INDEX IF NOT EXISTS idx_seen_status_result_type_name ON seen_statuses(result_type, result_name)",
-	}
-
-	for _, indexSQL := range indexes {
-		if err := DB.Exec(indexSQL).Error; err != nil {
-			return fmt.Errorf("failed to create index: %w", err)
+		// Enable foreign key constraints
+		if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+			return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 		}
 	}
 
-	return nil
+	return db, nil
 }
 
 // initializeDirectories creates all required data directories
@@ -161,9 +132,9 @@ func initializeDirectories(cfg *config.Config, log *zap.Logger) error {
 }
 
 // Close closes the database connection
-func Close() error {
-	if DB != nil {
-		sqlDB, err := DB.DB()
+func Close(db *gorm.DB) error {
+	if db != nil {
+		sqlDB, err := db.DB()
 		if err != nil {
 			return err
 		}
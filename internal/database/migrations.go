@@ -0,0 +1,307 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change. Up and Down run in a transaction
+// together with the schema_migrations bookkeeping, so a failed step leaves
+// the version unchanged.
+//
+// Migrations are append-only: once released, a step is never edited, and
+// later changes (new columns, renames, dropped columns, backfills) get a new
+// version. Steps never reference the live models, whose shape changes; the
+// first one creates the frozen snapshot in schema_v1.go.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// TableName names the bookkeeping table
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationState is a migration with when it was applied, if it was
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Unknown   bool       `json:"unknown,omitempty"` // applied by a newer build
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// build than this one
+var ErrSchemaTooNew = errors.New("database schema is newer than this build supports")
+
+// ErrSchemaOutdated is returned when migrations are pending and automatic
+// migration is disabled
+var ErrSchemaOutdated = errors.New("database schema has pending migrations")
+
+// migrationLockID keys the PostgreSQL advisory lock that keeps two
+// instances from applying the same migration at once
+const migrationLockID = 7242026
+
+// compositeIndexes are the multi-column indexes GORM doesn't create from
+// the model tags
+var compositeIndexes = []struct{ name, columns string }{
+	{"idx_threads_conversation_date", "threads(conversation_id, date)"},
+	{"idx_messages_conversation_index", "messages(conversation_id, message_index)"},
+	{"idx_analyses_date_type", "analyses(date, analysis_type)"},
+	{"idx_seen_status_date_type", "seen_statuses(date, analysis_type)"},
+	{"idx_seen_status_result_type_name", "seen_statuses(result_type, result_name)"},
+}
+
+// migrations lists every schema change in version order. Databases created
+// before versioning already have the tables and indexes; the first steps
+// are idempotent so they are adopted on first start.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range schemaV1 {
+				if err := tx.AutoMigrate(model); err != nil {
+					return fmt.Errorf("failed to create table for %T: %w", model, err)
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(schemaV1) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(schemaV1[i]); err != nil {
+					return fmt.Errorf("failed to drop table for %T: %w", schemaV1[i], err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "composite_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, index := range compositeIndexes {
+				if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s", index.name, index.columns)).Error; err != nil {
+					return fmt.Errorf("failed to create index %s: %w", index.name, err)
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, index := range compositeIndexes {
+				if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %s", index.name)).Error; err != nil {
+					return fmt.Errorf("failed to drop index %s: %w", index.name, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Full-text search over message content; SQLite searches with LIKE
+		Version: 3,
+		Name:    "message_search_index",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_messages_content_fts ON messages USING GIN (to_tsvector('simple', content))").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			return tx.Exec("DROP INDEX IF EXISTS idx_messages_content_fts").Error
+		},
+	},
+}
+
+// LatestVersion is the schema version this build migrates to
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the highest applied migration, 0 for an empty
+// database
+func SchemaVersion(db *gorm.DB) (int, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var version int
+	if err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// CheckSchema returns ErrSchemaTooNew if the database is ahead of this
+// build, or ErrSchemaOutdated if migrations are pending
+func CheckSchema(db *gorm.DB) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > LatestVersion() {
+		return fmt.Errorf("%w: database is at version %d, this build supports up to %d", ErrSchemaTooNew, version, LatestVersion())
+	}
+	if version < LatestVersion() {
+		return fmt.Errorf("%w: database is at version %d, this build expects %d", ErrSchemaOutdated, version, LatestVersion())
+	}
+	return nil
+}
+
+// Migrate applies every pending migration
+func Migrate(db *gorm.DB, log *zap.Logger) error {
+	return MigrateTo(db, log, LatestVersion())
+}
+
+// Rollback reverts the given number of applied migrations, newest first
+func Rollback(db *gorm.DB, log *zap.Logger, steps int) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	target := version
+	for ; steps > 0 && target > 0; steps-- {
+		target = previousVersion(target)
+	}
+	return MigrateTo(db, log, target)
+}
+
+// MigrateTo applies or reverts migrations until the database is at target.
+// It refuses to touch a database migrated by a newer build.
+func MigrateTo(db *gorm.DB, log *zap.Logger, target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("unknown schema version %d, this build supports 0 to %d", target, LatestVersion())
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > LatestVersion() {
+		return fmt.Errorf("%w: database is at version %d, this build supports up to %d", ErrSchemaTooNew, version, LatestVersion())
+	}
+
+	if target >= version {
+		for _, migration := range migrations {
+			if migration.Version <= version || migration.Version > target {
+				continue
+			}
+			if err := applyMigration(db, migration, true); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Info("Applied migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		}
+	} else {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if migration.Version > version || migration.Version <= target {
+				continue
+			}
+			if err := applyMigration(db, migration, false); err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Info("Rolled back migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		}
+	}
+
+	log.Info("Database schema is up to date", zap.Int("version", target))
+	return nil
+}
+
+// MigrationStatus lists every known migration and any applied ones this
+// build doesn't know, in version order
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	if _, err := SchemaVersion(db); err != nil {
+		return nil, err
+	}
+	var applied []SchemaMigration
+	if err := db.Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	appliedAt := make(map[int]SchemaMigration, len(applied))
+	for _, record := range applied {
+		appliedAt[record.Version] = record
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if record, ok := appliedAt[migration.Version]; ok {
+			at := record.AppliedAt
+			state.AppliedAt = &at
+			delete(appliedAt, migration.Version)
+		}
+		states = append(states, state)
+	}
+	for _, record := range appliedAt {
+		at := record.AppliedAt
+		states = append(states, MigrationState{Version: record.Version, Name: record.Name, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// applyMigration runs one step and records it. On PostgreSQL an advisory
+// lock serialises instances, and a step another instance finished while
+// this one waited is skipped.
+func applyMigration(db *gorm.DB, migration Migration, up bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+				return fmt.Errorf("failed to lock schema_migrations: %w", err)
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&SchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		if up {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		}
+
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+	})
+}
+
+// previousVersion returns the version before the given one, 0 before the
+// first
+func previousVersion(version int) int {
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version < version {
+			previous = migration.Version
+		}
+	}
+	return previous
+}
@@ -0,0 +1,136 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository/repotest"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// liveModels are the models the services read and write
+var liveModels = []interface{}{
+	&models.Upload{},
+	&models.Import{},
+	&models.Conversation{},
+	&models.Message{},
+	&models.Thread{},
+	&models.Extraction{},
+	&models.Analysis{},
+	&models.SeenStatus{},
+	&models.ActionableItem{},
+	&models.Question{},
+	&models.NoiseFlag{},
+	&models.PromptTemplate{},
+	&models.AICacheEntry{},
+	&models.AIUsage{},
+	&models.SecretFinding{},
+	&models.UploadSession{},
+	&models.AccountProfile{},
+	&models.MessageFeedback{},
+	&models.ModelComparison{},
+	&models.SharedConversation{},
+	&models.MediaAsset{},
+	&models.MessageAsset{},
+}
+
+// A model field without a migration creating its column fails here rather
+// than at runtime
+func TestMigrationsCoverModels(t *testing.T) {
+	db := repotest.NewStore(t).DB()
+
+	for _, model := range liveModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("failed to parse %T: %v", model, err)
+		}
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("no migration creates table %s", stmt.Schema.Table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("no migration creates column %s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestRollbackAndMigrateAgain(t *testing.T) {
+	db := repotest.NewStore(t).DB()
+	log := zap.NewNop()
+
+	if err := database.Rollback(db, log, database.LatestVersion()); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if version, err := database.SchemaVersion(db); err != nil || version != 0 {
+		t.Fatalf("schema version after rollback = %d, %v; want 0", version, err)
+	}
+	if db.Migrator().HasTable(&models.Upload{}) {
+		t.Fatal("uploads table survived a full rollback")
+	}
+
+	if err := database.Migrate(db, log); err != nil {
+		t.Fatalf("migrate after rollback failed: %v", err)
+	}
+	if err := database.CheckSchema(db); err != nil {
+		t.Fatalf("schema not current after migrate: %v", err)
+	}
+}
+
+// The full-text index only exists on PostgreSQL, and comes and goes with its
+// migration
+func TestMessageSearchIndex(t *testing.T) {
+	for _, backend := range repotest.Backends {
+		t.Run(backend.Name, func(t *testing.T) {
+			db := backend.New(t).DB()
+			log := zap.NewNop()
+			postgres := db.Dialector.Name() == "postgres"
+
+			if got := db.Migrator().HasIndex(&models.Message{}, "idx_messages_content_fts"); got != postgres {
+				t.Fatalf("search index present = %v, want %v", got, postgres)
+			}
+			if !postgres {
+				return
+			}
+
+			var definition string
+			if err := db.Raw("SELECT indexdef FROM pg_indexes WHERE schemaname = current_schema() AND indexname = ?", "idx_messages_content_fts").Scan(&definition).Error; err != nil {
+				t.Fatalf("failed to read index definition: %v", err)
+			}
+			if !strings.Contains(definition, "USING gin") {
+				t.Errorf("search index definition = %q, want a GIN index", definition)
+			}
+
+			states, err := database.MigrationStatus(db)
+			if err != nil {
+				t.Fatalf("failed to read migration status: %v", err)
+			}
+			version := 0
+			for _, state := range states {
+				if state.Name == "message_search_index" {
+					version = state.Version
+				}
+			}
+			if err := database.MigrateTo(db, log, version-1); err != nil {
+				t.Fatalf("rollback failed: %v", err)
+			}
+			if db.Migrator().HasIndex(&models.Message{}, "idx_messages_content_fts") {
+				t.Error("search index survived its rollback")
+			}
+			if err := database.Migrate(db, log); err != nil {
+				t.Fatalf("migrate after rollback failed: %v", err)
+			}
+			if !db.Migrator().HasIndex(&models.Message{}, "idx_messages_content_fts") {
+				t.Error("search index missing after migrating again")
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The structs below are the schema created by migration 1, frozen when
+// migrations were introduced. They are private to the migration so that
+// editing a model never changes what version 1 creates; schema changes go
+// in new migrations instead. Do not edit them.
+
+// schemaV1 are the tables created by migration 1, parents first
+var schemaV1 = []interface{}{
+	&v1Upload{},
+	&v1Import{},
+	&v1Conversation{},
+	&v1Message{},
+	&v1Thread{},
+	&v1Extraction{},
+	&v1Analysis{},
+	&v1SeenStatus{},
+	&v1ActionableItem{},
+	&v1Question{},
+	&v1NoiseFlag{},
+	&v1PromptTemplate{},
+	&v1AICacheEntry{},
+	&v1AIUsage{},
+	&v1SecretFinding{},
+	&v1UploadSession{},
+	&v1AccountProfile{},
+	&v1MessageFeedback{},
+	&v1ModelComparison{},
+	&v1SharedConversation{},
+	&v1MediaAsset{},
+	&v1MessageAsset{},
+}
+
+type v1Upload struct {
+	ID               uint   `gorm:"primaryKey"`
+	UUID             string `gorm:"uniqueIndex;not null"`
+	OriginalFilename string `gorm:"type:varchar(255);not null"`
+	StoredPath       string `gorm:"not null"`
+	FileSize         int64
+	FileHash         string    `gorm:"uniqueIndex;not null"`
+	MimeType         string    `gorm:"default:'application/zip'"`
+	UploadedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	Status           string    `gorm:"type:varchar(50);not null;index"`
+	ErrorMessage     *string
+	Metadata         string         `gorm:"type:text"`
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+
+	Import        v1Import         `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+	Extractions   []v1Extraction   `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+	Conversations []v1Conversation `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+	Analyses      []v1Analysis     `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE"`
+}
+
+func (v1Upload) TableName() string { return "uploads" }
+
+type v1Import struct {
+	ID              uint      `gorm:"primaryKey"`
+	UploadID        uint      `gorm:"uniqueIndex;not null"`
+	StartedAt       time.Time `gorm:"not null"`
+	CompletedAt     *time.Time
+	Status          string `gorm:"type:varchar(50);not null;index"`
+	Stage           string `gorm:"type:varchar(50);default:'queued'"`
+	ProgressPercent int    `gorm:"default:0"`
+	ErrorMessage    *string
+	Stats           string `gorm:"type:text"`
+
+	Upload *v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1Import) TableName() string { return "imports" }
+
+type v1Conversation struct {
+	ID             uint      `gorm:"primaryKey"`
+	UploadID       uint      `gorm:"not null;index"`
+	ConversationID string    `gorm:"not null;index"`
+	ExportID       string    `gorm:"type:varchar(100);index"`
+	Source         string    `gorm:"type:varchar(50);not null;default:'chatgpt';index"`
+	Title          *string   `gorm:"type:varchar(500)"`
+	CreatedAt      time.Time `gorm:"index"`
+	UpdatedAt      time.Time
+	SourceFilePath string
+	MessageCount   int    `gorm:"default:0"`
+	Metadata       string `gorm:"type:text"`
+
+	Upload   v1Upload     `gorm:"constraint:OnDelete:CASCADE"`
+	Messages []v1Message  `gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+	Threads  []v1Thread   `gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+	Analyses []v1Analysis `gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+}
+
+func (v1Conversation) TableName() string { return "conversations" }
+
+type v1Message struct {
+	ID             uint `gorm:"primaryKey"`
+	ConversationID uint `gorm:"not null;index"`
+	MessageID      *string
+	Role           string    `gorm:"type:varchar(50);not null;index"`
+	Content        string    `gorm:"type:text;not null"`
+	Timestamp      time.Time `gorm:"not null;index"`
+	MessageIndex   int       `gorm:"not null;index"`
+	Metadata       string    `gorm:"type:text"`
+
+	Conversation v1Conversation `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1Message) TableName() string { return "messages" }
+
+type v1Thread struct {
+	ID             uint      `gorm:"primaryKey"`
+	ConversationID uint      `gorm:"not null;index"`
+	Date           string    `gorm:"type:date;not null;index"`
+	MessageCount   int       `gorm:"not null"`
+	StartMessageID *uint     `gorm:"index"`
+	EndMessageID   *uint     `gorm:"index"`
+	StartTimestamp time.Time `gorm:"not null"`
+	EndTimestamp   time.Time `gorm:"not null"`
+
+	Conversation v1Conversation `gorm:"constraint:OnDelete:CASCADE"`
+	Analyses     []v1Analysis   `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"`
+}
+
+func (v1Thread) TableName() string { return "threads" }
+
+type v1Extraction struct {
+	ID           uint   `gorm:"primaryKey"`
+	UploadID     uint   `gorm:"not null;index"`
+	FilePath     string `gorm:"not null"`
+	FileType     string `gorm:"type:varchar(50);index"`
+	InArchive    bool   `gorm:"not null;default:false"`
+	FileSize     int64
+	ExtractedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	Status       string    `gorm:"type:varchar(50);index"`
+	ErrorMessage *string
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1Extraction) TableName() string { return "extractions" }
+
+type v1Analysis struct {
+	ID               uint      `gorm:"primaryKey"`
+	UploadID         *uint     `gorm:"index"`
+	ConversationID   *uint     `gorm:"index"`
+	ThreadID         *uint     `gorm:"index"`
+	Date             *string   `gorm:"type:date;index"`
+	AnalysisType     string    `gorm:"type:varchar(100);not null;index"`
+	AnalysisData     string    `gorm:"type:text;not null"`
+	MarkdownContent  string    `gorm:"type:text"`
+	IsAIEnhanced     bool      `gorm:"default:false"`
+	AIProvider       *string   `gorm:"type:varchar(50)"`
+	CreatedAt        time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+	UpdatedAt        *time.Time
+	Version          *string `gorm:"type:varchar(50)"`
+	PromptTemplateID *uint   `gorm:"index"`
+	PromptVersion    *int
+	InputTokens      int    `gorm:"default:0"`
+	OutputTokens     int    `gorm:"default:0"`
+	ChunkCount       int    `gorm:"default:0"`
+	CacheHit         bool   `gorm:"default:false"`
+	RedactionStats   string `gorm:"type:text"`
+
+	Upload       *v1Upload       `gorm:"constraint:OnDelete:CASCADE"`
+	Conversation *v1Conversation `gorm:"constraint:OnDelete:CASCADE"`
+	Thread       *v1Thread       `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1Analysis) TableName() string { return "analyses" }
+
+type v1SeenStatus struct {
+	ID           uint      `gorm:"primaryKey"`
+	Date         *string   `gorm:"type:date;index"`
+	AnalysisType *string   `gorm:"type:varchar(100);index"`
+	ResultType   *string   `gorm:"type:varchar(50);index"`
+	ResultName   *string   `gorm:"type:varchar(255);index"`
+	SeenAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+	UserID       *string   `gorm:"type:varchar(100);index"`
+}
+
+func (v1SeenStatus) TableName() string { return "seen_statuses" }
+
+type v1ActionableItem struct {
+	ID             uint      `gorm:"primaryKey"`
+	UploadID       *uint     `gorm:"index"`
+	ConversationID *uint     `gorm:"index"`
+	MessageID      *uint     `gorm:"index"`
+	AnalysisID     *uint     `gorm:"index"`
+	Category       string    `gorm:"type:varchar(50);not null;index"`
+	Content        string    `gorm:"type:text;not null"`
+	Source         string    `gorm:"type:varchar(50);index"`
+	ExtractedAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+	Metadata       string    `gorm:"type:text"`
+}
+
+func (v1ActionableItem) TableName() string { return "actionable_items" }
+
+type v1Question struct {
+	ID             uint      `gorm:"primaryKey"`
+	UploadID       *uint     `gorm:"index"`
+	ConversationID *uint     `gorm:"index"`
+	MessageID      *uint     `gorm:"index"`
+	QuestionText   string    `gorm:"type:text;not null"`
+	Asker          string    `gorm:"type:varchar(50);index"`
+	ExtractedAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+	Metadata       string    `gorm:"type:text"`
+}
+
+func (v1Question) TableName() string { return "questions" }
+
+type v1NoiseFlag struct {
+	ID             uint `gorm:"primaryKey"`
+	ConversationID uint `gorm:"uniqueIndex;not null"`
+	IsNoise        bool `gorm:"not null;default:false;index"`
+	Confidence     *float64
+	Reason         *string   `gorm:"type:text"`
+	FlaggedAt      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	Conversation v1Conversation `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1NoiseFlag) TableName() string { return "noise_flags" }
+
+type v1PromptTemplate struct {
+	ID          uint      `gorm:"primaryKey"`
+	Name        string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_prompt_templates_name_version"`
+	Version     int       `gorm:"not null;uniqueIndex:idx_prompt_templates_name_version"`
+	Body        string    `gorm:"type:text;not null"`
+	Description *string   `gorm:"type:text"`
+	IsActive    bool      `gorm:"not null;default:false;index"`
+	CreatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   *time.Time
+}
+
+func (v1PromptTemplate) TableName() string { return "prompt_templates" }
+
+type v1AICacheEntry struct {
+	ID               uint   `gorm:"primaryKey"`
+	CacheKey         string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Provider         string `gorm:"type:varchar(50);not null;index"`
+	Model            string `gorm:"type:varchar(100);not null"`
+	PromptTemplateID uint   `gorm:"index"`
+	PromptVersion    int
+	ContentHash      string  `gorm:"type:varchar(64);not null"`
+	Date             *string `gorm:"type:varchar(10);index"`
+	Response         string  `gorm:"type:text;not null"`
+	InputTokens      int
+	OutputTokens     int
+	HitCount         int        `gorm:"default:0"`
+	CreatedAt        time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	ExpiresAt        *time.Time `gorm:"index"`
+}
+
+func (v1AICacheEntry) TableName() string { return "ai_cache_entries" }
+
+type v1AIUsage struct {
+	ID               uint    `gorm:"primaryKey"`
+	AnalysisID       *uint   `gorm:"index"`
+	Provider         string  `gorm:"type:varchar(50);not null;index"`
+	Model            string  `gorm:"type:varchar(100);not null;index"`
+	Dimension        string  `gorm:"type:varchar(100);not null;index"`
+	Date             *string `gorm:"type:varchar(10);index"`
+	PromptTemplateID *uint   `gorm:"index"`
+	InputTokens      int
+	OutputTokens     int
+	LatencyMs        int64
+	EstimatedCost    float64
+	Success          bool `gorm:"not null;index"`
+	ErrorMessage     *string
+	CalledOn         string    `gorm:"type:varchar(10);not null;index"`
+	CreatedAt        time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+
+	Analysis *v1Analysis `gorm:"constraint:OnDelete:SET NULL"`
+}
+
+func (v1AIUsage) TableName() string { return "ai_usages" }
+
+type v1SecretFinding struct {
+	ID             uint      `gorm:"primaryKey"`
+	ConversationID uint      `gorm:"not null;index"`
+	MessageID      uint      `gorm:"not null;uniqueIndex:idx_secret_findings_message_fingerprint"`
+	Date           string    `gorm:"type:varchar(10);not null;index"`
+	Role           string    `gorm:"type:varchar(50)"`
+	SecretType     string    `gorm:"type:varchar(50);not null;index"`
+	MaskedPreview  string    `gorm:"type:text;not null"`
+	Fingerprint    string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_secret_findings_message_fingerprint;index"`
+	DetectedAt     time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	Conversation v1Conversation `gorm:"constraint:OnDelete:CASCADE"`
+	Message      v1Message      `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1SecretFinding) TableName() string { return "secret_findings" }
+
+type v1UploadSession struct {
+	ID               uint   `gorm:"primaryKey"`
+	UUID             string `gorm:"uniqueIndex;not null"`
+	OriginalFilename string `gorm:"type:varchar(255);not null"`
+	TotalSize        int64  `gorm:"not null"`
+	Offset           int64  `gorm:"not null;default:0"`
+	ExpectedHash     string `gorm:"type:varchar(64)"`
+	HashState        []byte
+	TempPath         string `gorm:"not null"`
+	Status           string `gorm:"type:varchar(50);not null;index"`
+	ErrorMessage     *string
+	UploadID         *uint     `gorm:"index"`
+	CreatedAt        time.Time `gorm:"not null"`
+	UpdatedAt        time.Time
+	ExpiresAt        time.Time `gorm:"not null;index"`
+}
+
+func (v1UploadSession) TableName() string { return "upload_sessions" }
+
+type v1AccountProfile struct {
+	ID          uint   `gorm:"primaryKey"`
+	UploadID    uint   `gorm:"not null;uniqueIndex"`
+	AccountID   string `gorm:"type:varchar(100);index"`
+	Email       *string
+	PhoneNumber *string
+	PlusUser    bool
+	BirthYear   *int
+	Raw         string    `gorm:"type:text"`
+	ImportedAt  time.Time `gorm:"not null"`
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1AccountProfile) TableName() string { return "account_profiles" }
+
+type v1MessageFeedback struct {
+	ID                   uint      `gorm:"primaryKey"`
+	UploadID             uint      `gorm:"not null;index"`
+	FeedbackID           string    `gorm:"type:varchar(100);index"`
+	ExportConversationID string    `gorm:"type:varchar(100);index"`
+	ExportMessageID      string    `gorm:"type:varchar(100);index"`
+	ConversationID       *uint     `gorm:"index"`
+	MessageID            *uint     `gorm:"index"`
+	Rating               string    `gorm:"type:varchar(20);not null;index"`
+	Tags                 string    `gorm:"type:text"`
+	Text                 *string   `gorm:"type:text"`
+	Content              string    `gorm:"type:text"`
+	CreatedAt            time.Time `gorm:"index;autoCreateTime:false"`
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1MessageFeedback) TableName() string { return "message_feedbacks" }
+
+type v1ModelComparison struct {
+	ID                   uint      `gorm:"primaryKey"`
+	UploadID             uint      `gorm:"not null;index"`
+	ComparisonID         string    `gorm:"type:varchar(100);index"`
+	ExportConversationID string    `gorm:"type:varchar(100);index"`
+	ConversationID       *uint     `gorm:"index"`
+	Input                string    `gorm:"type:text"`
+	Output               string    `gorm:"type:text"`
+	Metadata             string    `gorm:"type:text"`
+	CreatedAt            time.Time `gorm:"index;autoCreateTime:false"`
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1ModelComparison) TableName() string { return "model_comparisons" }
+
+type v1SharedConversation struct {
+	ID                   uint   `gorm:"primaryKey"`
+	UploadID             uint   `gorm:"not null;index"`
+	ShareID              string `gorm:"type:varchar(100);index"`
+	ExportConversationID string `gorm:"type:varchar(100);index"`
+	ConversationID       *uint  `gorm:"index"`
+	Title                string `gorm:"type:varchar(500)"`
+	IsAnonymous          bool
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1SharedConversation) TableName() string { return "shared_conversations" }
+
+type v1MediaAsset struct {
+	ID           uint   `gorm:"primaryKey"`
+	UploadID     uint   `gorm:"not null;index"`
+	ExtractionID uint   `gorm:"not null;index"`
+	AssetID      string `gorm:"type:varchar(100);index"`
+	FileName     string `gorm:"type:varchar(500)"`
+	ContentHash  string `gorm:"type:varchar(64);index"`
+	MimeType     string `gorm:"type:varchar(100);index"`
+	FileSize     int64
+	Width        *int
+	Height       *int
+	Duration     *float64
+	CataloguedAt time.Time `gorm:"not null"`
+
+	Upload v1Upload `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1MediaAsset) TableName() string { return "media_assets" }
+
+type v1MessageAsset struct {
+	ID             uint   `gorm:"primaryKey"`
+	UploadID       uint   `gorm:"not null;index"`
+	ConversationID uint   `gorm:"not null;index"`
+	MessageID      uint   `gorm:"not null;index"`
+	AssetID        string `gorm:"type:varchar(100);not null;index"`
+	PointerType    string `gorm:"type:varchar(100)"`
+
+	Message v1Message `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (v1MessageAsset) TableName() string { return "message_assets" }
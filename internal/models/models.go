@@ -6,7 +6,7 @@ import (
 	"gorm.io/gorm"
 )
 
-// Upload tracks uploaded ZIP files
+// Upload tracks uploaded export files
 type Upload struct {
 	ID              uint           `gorm:"primaryKey" json:"id"`
 	UUID            string         `gorm:"uniqueIndex;not null" json:"uuid"`
@@ -34,20 +34,23 @@ type Import struct {
 	UploadID        uint       `gorm:"uniqueIndex;not null" json:"upload_id"`
 	StartedAt       time.Time  `gorm:"not null" json:"started_at"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
-	Status          string     `gorm:"type:varchar(50);not null;index" json:"status"` // pending, extracting, parsing, importing, completed, failed
+	Status          string     `gorm:"type:varchar(50);not null;index" json:"status"` // pending, extracting, parsing, importing, analyzing, completed, failed
+	Stage           string     `gorm:"type:varchar(50);default:'queued'" json:"stage"` // queued, extract, parse, thread, analyze, done
 	ProgressPercent int        `gorm:"default:0" json:"progress_percent"`            // 0-100
 	ErrorMessage    *string    `json:"error_message,omitempty"`
-	Stats           string     `gorm:"type:text" json:"stats"` // JSON: conversations_count, messages_count, threads_count, files_extracted
+	Stats           string     `gorm:"type:text" json:"stats"` // JSON: files_extracted, conversation_files, conversations_count, messages_count, threads_count
 
 	// Relationships
 	Upload Upload `gorm:"constraint:OnDelete:CASCADE"`
 }
 
-// Conversation represents individual conversations from ChatGPT export
+// Conversation represents individual conversations from a chat export
 type Conversation struct {
 	ID              uint       `gorm:"primaryKey" json:"id"`
 	UploadID        uint       `gorm:"not null;index" json:"upload_id"`
 	ConversationID  string     `gorm:"not null;index" json:"conversation_id"` // ChatGPT's original ID
+	ExportID        string     `gorm:"type:varchar(100);index" json:"export_id,omitempty"` // Conversation UUID from the export, used by feedback and shared links
+	Source          string     `gorm:"type:varchar(50);not null;default:'chatgpt';index" json:"source"` // chatgpt, claude, gemini, jsonl
 	Title           *string    `gorm:"type:varchar(500)" json:"title,omitempty"`
 	CreatedAt       time.Time  `gorm:"index" json:"created_at"` // From ChatGPT export
 	UpdatedAt       time.Time  `json:"updated_at"`               // From ChatGPT export
@@ -93,15 +96,16 @@ type Thread struct {
 	Analyses     []Analysis   `gorm:"constraint:OnDelete:CASCADE"`
 }
 
-// Extraction tracks extracted files from ZIP
+// Extraction tracks files extracted from an upload
 type Extraction struct {
 	ID           uint       `gorm:"primaryKey" json:"id"`
 	UploadID     uint       `gorm:"not null;index" json:"upload_id"`
-	FilePath     string     `gorm:"not null" json:"file_path"`
-	FileType     string     `gorm:"type:varchar(50);index" json:"file_type"` // conversation, media, other
+	FilePath     string     `gorm:"not null" json:"file_path"` // path inside the upload when InArchive
+	FileType     string     `gorm:"type:varchar(50);index" json:"file_type"` // conversation, account, feedback, comparisons, shared_conversations, chat_html, media, other
+	InArchive    bool       `gorm:"not null;default:false" json:"in_archive"` // read from the stored upload, not extracted to disk
 	FileSize     int64      `json:"file_size"`
 	ExtractedAt  time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"extracted_at"`
-	Status       string     `gorm:"type:varchar(50);index" json:"status"` // extracted, parsed, failed
+	Status       string     `gorm:"type:varchar(50);index" json:"status"` // extracted, parsed, rejected, failed
 	ErrorMessage *string    `json:"error_message,omitempty"`
 
 	// Relationships
@@ -123,6 +127,13 @@ type Analysis struct {
 	CreatedAt        time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
 	UpdatedAt        *time.Time  `json:"updated_at,omitempty"`
 	Version          *string     `gorm:"type:varchar(50)" json:"version,omitempty"`
+	PromptTemplateID *uint       `gorm:"index" json:"prompt_template_id,omitempty"`
+	PromptVersion    *int        `json:"prompt_version,omitempty"`
+	InputTokens      int         `gorm:"default:0" json:"input_tokens"`  // provider-reported, summed over all calls
+	OutputTokens     int         `gorm:"default:0" json:"output_tokens"` // provider-reported, summed over all calls
+	ChunkCount       int         `gorm:"default:0" json:"chunk_count"`   // message chunks used for map-reduce
+	CacheHit         bool        `gorm:"default:false" json:"cache_hit"` // every provider call was served from the AI cache
+	RedactionStats   string      `gorm:"type:text" json:"redaction_stats,omitempty"` // JSON: category -> values redacted before sending
 
 	// Relationships
 	Upload       *Upload       `gorm:"constraint:OnDelete:CASCADE"`
@@ -180,3 +191,188 @@ type NoiseFlag struct {
 	Conversation Conversation `gorm:"constraint:OnDelete:CASCADE"`
 }
 
+// PromptTemplate stores versioned prompt templates used for AI-enhanced analysis
+type PromptTemplate struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_prompt_templates_name_version" json:"name"` // analysis dimension, synthesis, summary
+	Version     int        `gorm:"not null;uniqueIndex:idx_prompt_templates_name_version" json:"version"`
+	Body        string     `gorm:"type:text;not null" json:"body"` // Go text/template source
+	Description *string    `gorm:"type:text" json:"description,omitempty"`
+	IsActive    bool       `gorm:"not null;default:false;index" json:"is_active"`
+	CreatedAt   time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// AICacheEntry caches an AI completion keyed by provider, model, prompt
+// template version and a hash of the rendered prompt
+type AICacheEntry struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	CacheKey         string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"cache_key"` // SHA256 of provider, model, template version and content hash
+	Provider         string     `gorm:"type:varchar(50);not null;index" json:"provider"`
+	Model            string     `gorm:"type:varchar(100);not null" json:"model"`
+	PromptTemplateID uint       `gorm:"index" json:"prompt_template_id"`
+	PromptVersion    int        `json:"prompt_version"`
+	ContentHash      string     `gorm:"type:varchar(64);not null" json:"content_hash"` // SHA256 of the rendered prompt
+	Date             *string    `gorm:"type:varchar(10);index" json:"date,omitempty"`  // YYYY-MM-DD the prompt was built for
+	Response         string     `gorm:"type:text;not null" json:"response"`
+	InputTokens      int        `json:"input_tokens"`
+	OutputTokens     int        `json:"output_tokens"`
+	HitCount         int        `gorm:"default:0" json:"hit_count"`
+	CreatedAt        time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt        *time.Time `gorm:"index" json:"expires_at,omitempty"`
+}
+
+// AIUsage is a ledger row recording a single AI provider call
+type AIUsage struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	AnalysisID       *uint     `gorm:"index" json:"analysis_id,omitempty"`
+	Provider         string    `gorm:"type:varchar(50);not null;index" json:"provider"`
+	Model            string    `gorm:"type:varchar(100);not null;index" json:"model"`
+	Dimension        string    `gorm:"type:varchar(100);not null;index" json:"dimension"` // analysis type the call was made for
+	Date             *string   `gorm:"type:varchar(10);index" json:"date,omitempty"`       // YYYY-MM-DD being analyzed
+	PromptTemplateID *uint     `gorm:"index" json:"prompt_template_id,omitempty"`
+	InputTokens      int       `json:"input_tokens"`
+	OutputTokens     int       `json:"output_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	EstimatedCost    float64   `json:"estimated_cost"` // USD, from the configured price table
+	Success          bool      `gorm:"not null;index" json:"success"`
+	ErrorMessage     *string   `json:"error_message,omitempty"`
+	CalledOn         string    `gorm:"type:varchar(10);not null;index" json:"called_on"` // YYYY-MM-DD (UTC) of the call
+	CreatedAt        time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+
+	// Relationships
+	Analysis *Analysis `gorm:"constraint:OnDelete:SET NULL"`
+}
+
+// SecretFinding records a credential found in a message by the secret scanner
+type SecretFinding struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;index" json:"conversation_id"`
+	MessageID      uint      `gorm:"not null;uniqueIndex:idx_secret_findings_message_fingerprint" json:"message_id"`
+	Date           string    `gorm:"type:varchar(10);not null;index" json:"date"` // YYYY-MM-DD of the message
+	Role           string    `gorm:"type:varchar(50)" json:"role"`
+	SecretType     string    `gorm:"type:varchar(50);not null;index" json:"secret_type"` // api_key, private_key, jwt, connection_string, password, secret
+	MaskedPreview  string    `gorm:"type:text;not null" json:"masked_preview"`
+	Fingerprint    string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_secret_findings_message_fingerprint;index" json:"fingerprint"` // SHA256 of the secret, to spot reuse
+	DetectedAt     time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"detected_at"`
+
+	// Relationships
+	Conversation Conversation `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Message      Message      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// UploadSession tracks a resumable chunked upload until it becomes an Upload
+type UploadSession struct {
+	ID               uint      `gorm:"primaryKey" json:"-"`
+	UUID             string    `gorm:"uniqueIndex;not null" json:"id"`
+	OriginalFilename string    `gorm:"type:varchar(255);not null" json:"filename"`
+	TotalSize        int64     `gorm:"not null" json:"size"`
+	Offset           int64     `gorm:"not null;default:0" json:"offset"`
+	ExpectedHash     string    `gorm:"type:varchar(64)" json:"sha256,omitempty"` // SHA256 hex digest supplied by the client
+	HashState        []byte    `json:"-"`                                        // marshalled SHA256 state after Offset bytes
+	TempPath         string    `gorm:"not null" json:"-"`
+	Status           string    `gorm:"type:varchar(50);not null;index" json:"status"` // active, completed, failed, expired
+	ErrorMessage     *string   `json:"error_message,omitempty"`
+	UploadID         *uint     `gorm:"index" json:"upload_id,omitempty"`
+	CreatedAt        time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	ExpiresAt        time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// AccountProfile is the account owner described by an export's user.json
+type AccountProfile struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UploadID    uint      `gorm:"not null;uniqueIndex" json:"upload_id"`
+	AccountID   string    `gorm:"type:varchar(100);index" json:"account_id"`
+	Email       *string   `json:"email,omitempty"`
+	PhoneNumber *string   `json:"phone_number,omitempty"`
+	PlusUser    bool      `json:"chatgpt_plus_user"`
+	BirthYear   *int      `json:"birth_year,omitempty"`
+	Raw         string    `gorm:"type:text" json:"raw"` // JSON as exported
+	ImportedAt  time.Time `gorm:"not null" json:"imported_at"`
+
+	// Relationships
+	Upload Upload `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// MessageFeedback is a thumbs-up/down rating from message_feedback.json
+type MessageFeedback struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	UploadID             uint      `gorm:"not null;index" json:"upload_id"`
+	FeedbackID           string    `gorm:"type:varchar(100);index" json:"feedback_id"`
+	ExportConversationID string    `gorm:"type:varchar(100);index" json:"export_conversation_id"`
+	ExportMessageID      string    `gorm:"type:varchar(100);index" json:"export_message_id"`
+	ConversationID       *uint     `gorm:"index" json:"conversation_id,omitempty"`        // set when the conversation was imported
+	MessageID            *uint     `gorm:"index" json:"message_id,omitempty"`             // set when the rated message was imported
+	Rating               string    `gorm:"type:varchar(20);not null;index" json:"rating"` // thumbs_up, thumbs_down
+	Tags                 string    `gorm:"type:text" json:"tags"`                         // JSON array
+	Text                 *string   `gorm:"type:text" json:"text,omitempty"`
+	Content              string    `gorm:"type:text" json:"content"`                     // feedback content as exported
+	CreatedAt            time.Time `gorm:"index;autoCreateTime:false" json:"created_at"` // From ChatGPT export
+
+	// Relationships
+	Upload Upload `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ModelComparison is a side-by-side response choice from model_comparisons.json
+type ModelComparison struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	UploadID             uint      `gorm:"not null;index" json:"upload_id"`
+	ComparisonID         string    `gorm:"type:varchar(100);index" json:"comparison_id"`
+	ExportConversationID string    `gorm:"type:varchar(100);index" json:"export_conversation_id"`
+	ConversationID       *uint     `gorm:"index" json:"conversation_id,omitempty"`
+	Input                string    `gorm:"type:text" json:"input"`                       // JSON
+	Output               string    `gorm:"type:text" json:"output"`                      // JSON, includes the chosen response
+	Metadata             string    `gorm:"type:text" json:"metadata"`                    // JSON
+	CreatedAt            time.Time `gorm:"index;autoCreateTime:false" json:"created_at"` // From ChatGPT export
+
+	// Relationships
+	Upload Upload `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SharedConversation is a public share link from shared_conversations.json
+type SharedConversation struct {
+	ID                   uint   `gorm:"primaryKey" json:"id"`
+	UploadID             uint   `gorm:"not null;index" json:"upload_id"`
+	ShareID              string `gorm:"type:varchar(100);index" json:"share_id"`
+	ExportConversationID string `gorm:"type:varchar(100);index" json:"export_conversation_id"`
+	ConversationID       *uint  `gorm:"index" json:"conversation_id,omitempty"`
+	Title                string `gorm:"type:varchar(500)" json:"title"`
+	IsAnonymous          bool   `json:"is_anonymous"`
+
+	// Relationships
+	Upload Upload `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// MediaAsset is an image, audio or other media file catalogued from an upload
+type MediaAsset struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UploadID     uint      `gorm:"not null;index" json:"upload_id"`
+	ExtractionID uint      `gorm:"not null;index" json:"extraction_id"`
+	AssetID      string    `gorm:"type:varchar(100);index" json:"asset_id,omitempty"` // file ID that asset pointers in messages refer to
+	FileName     string    `gorm:"type:varchar(500)" json:"file_name"`
+	ContentHash  string    `gorm:"type:varchar(64);index" json:"content_hash"` // SHA256 hex digest
+	MimeType     string    `gorm:"type:varchar(100);index" json:"mime_type"`   // sniffed from the content
+	FileSize     int64     `json:"file_size"`
+	Width        *int      `json:"width,omitempty"`
+	Height       *int      `json:"height,omitempty"`
+	Duration     *float64  `json:"duration_seconds,omitempty"`
+	CataloguedAt time.Time `gorm:"not null" json:"catalogued_at"`
+
+	// Relationships
+	Upload Upload `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// MessageAsset links a message to a file it references by asset pointer or
+// attachment
+type MessageAsset struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	UploadID       uint   `gorm:"not null;index" json:"upload_id"`
+	ConversationID uint   `gorm:"not null;index" json:"conversation_id"`
+	MessageID      uint   `gorm:"not null;index" json:"message_id"`
+	AssetID        string `gorm:"type:varchar(100);not null;index" json:"asset_id"`
+	PointerType    string `gorm:"type:varchar(100)" json:"pointer_type"` // image_asset_pointer, audio_asset_pointer, attachment, ...
+
+	// Relationships
+	Message Message `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
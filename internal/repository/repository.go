@@ -0,0 +1,89 @@
+// Package repository gives services typed access to the database tables.
+// A Store is built once from an open connection and injected into the
+// services that need it, so tests can hand each service its own database.
+package repository
+
+import "gorm.io/gorm"
+
+// ErrNotFound is returned when a lookup matches no record
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Repository provides the operations common to every table
+type Repository[T any] struct {
+	db *gorm.DB
+}
+
+// Query starts a query on the table, for anything the typed methods do
+// not cover
+func (r Repository[T]) Query() *gorm.DB {
+	return r.db.Model(new(T))
+}
+
+// Unscoped returns the repository with soft-deleted records included
+func (r Repository[T]) Unscoped() Repository[T] {
+	return Repository[T]{db: r.db.Unscoped()}
+}
+
+// Get loads a record by primary key
+func (r Repository[T]) Get(id uint) (*T, error) {
+	var record T
+	if err := r.db.First(&record, id).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// First loads the first record matching the condition, by primary key
+func (r Repository[T]) First(query interface{}, args ...interface{}) (*T, error) {
+	var record T
+	if err := r.db.Where(query, args...).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Find loads every record matching the condition
+func (r Repository[T]) Find(query interface{}, args ...interface{}) ([]T, error) {
+	var records []T
+	if err := r.db.Where(query, args...).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Count counts the records matching the condition
+func (r Repository[T]) Count(query interface{}, args ...interface{}) (int64, error) {
+	var count int64
+	err := r.Query().Where(query, args...).Count(&count).Error
+	return count, err
+}
+
+// Create inserts a record
+func (r Repository[T]) Create(record *T) error {
+	return r.db.Create(record).Error
+}
+
+// CreateInBatches inserts records batchSize at a time
+func (r Repository[T]) CreateInBatches(records []T, batchSize int) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(records, batchSize).Error
+}
+
+// Save inserts a record, or updates every column of an existing one
+func (r Repository[T]) Save(record *T) error {
+	return r.db.Save(record).Error
+}
+
+// Delete removes a record
+func (r Repository[T]) Delete(record *T) error {
+	return r.db.Delete(record).Error
+}
+
+// DeleteWhere removes the records matching the condition and returns how
+// many there were
+func (r Repository[T]) DeleteWhere(query interface{}, args ...interface{}) (int64, error) {
+	result := r.db.Where(query, args...).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,152 @@
+package repotest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Fixtures builds records with sensible defaults. Each builder takes
+// optional functions to adjust the record before it is saved, and fails the
+// test if it cannot be saved.
+type Fixtures struct {
+	tb    testing.TB
+	store *repository.Store
+}
+
+// NewFixtures creates fixture builders writing to store
+func NewFixtures(tb testing.TB, store *repository.Store) *Fixtures {
+	return &Fixtures{tb: tb, store: store}
+}
+
+// Upload creates a completed upload
+func (f *Fixtures) Upload(opts ...func(*models.Upload)) *models.Upload {
+	f.tb.Helper()
+	id := uuid.New().String()
+	upload := &models.Upload{
+		UUID:             id,
+		OriginalFilename: "export.zip",
+		StoredPath:       id + ".zip",
+		FileHash:         id,
+		MimeType:         "application/zip",
+		UploadedAt:       time.Now().UTC(),
+		Status:           "completed",
+	}
+	for _, opt := range opts {
+		opt(upload)
+	}
+	f.check("upload", f.store.Uploads.Create(upload))
+	return upload
+}
+
+// Import creates the import record of an upload
+func (f *Fixtures) Import(upload *models.Upload, opts ...func(*models.Import)) *models.Import {
+	f.tb.Helper()
+	record := &models.Import{
+		UploadID:  upload.ID,
+		StartedAt: time.Now().UTC(),
+		Status:    "completed",
+		Stage:     "done",
+		Stats:     "{}",
+	}
+	for _, opt := range opts {
+		opt(record)
+	}
+	f.check("import", f.store.Imports.Create(record))
+	return record
+}
+
+// Conversation creates a ChatGPT conversation in an upload
+func (f *Fixtures) Conversation(upload *models.Upload, opts ...func(*models.Conversation)) *models.Conversation {
+	f.tb.Helper()
+	id := uuid.New().String()
+	title := "Conversation " + id[:8]
+	conversation := &models.Conversation{
+		UploadID:       upload.ID,
+		ConversationID: id,
+		ExportID:       id,
+		Source:         "chatgpt",
+		Title:          &title,
+		CreatedAt:      time.Now().UTC(),
+		UpdatedAt:      time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(conversation)
+	}
+	f.check("conversation", f.store.Conversations.Create(conversation))
+	return conversation
+}
+
+// Message adds a message to a conversation and keeps the conversation's
+// message count in step
+func (f *Fixtures) Message(conversation *models.Conversation, role, content string, timestamp time.Time, opts ...func(*models.Message)) *models.Message {
+	f.tb.Helper()
+	messageID := uuid.New().String()
+	message := &models.Message{
+		ConversationID: conversation.ID,
+		MessageID:      &messageID,
+		Role:           role,
+		Content:        content,
+		Timestamp:      timestamp.UTC(),
+		MessageIndex:   conversation.MessageCount,
+	}
+	for _, opt := range opts {
+		opt(message)
+	}
+	f.check("message", f.store.Messages.Create(message))
+
+	conversation.MessageCount++
+	f.check("conversation", f.store.Conversations.Save(conversation))
+	return message
+}
+
+// Thread creates the thread for the given messages, which must share a
+// conversation and a UTC date
+func (f *Fixtures) Thread(messages ...*models.Message) *models.Thread {
+	f.tb.Helper()
+	if len(messages) == 0 {
+		f.tb.Fatalf("thread fixture needs at least one message")
+	}
+	start, end := messages[0], messages[len(messages)-1]
+	thread := &models.Thread{
+		ConversationID: start.ConversationID,
+		Date:           start.Timestamp.UTC().Format("2006-01-02"),
+		MessageCount:   len(messages),
+		StartMessageID: &start.ID,
+		EndMessageID:   &end.ID,
+		StartTimestamp: start.Timestamp.UTC(),
+		EndTimestamp:   end.Timestamp.UTC(),
+	}
+	f.check("thread", f.store.Threads.Create(thread))
+	return thread
+}
+
+// Analysis creates an analysis of one type for a date
+func (f *Fixtures) Analysis(date, analysisType string, opts ...func(*models.Analysis)) *models.Analysis {
+	f.tb.Helper()
+	analysis := &models.Analysis{
+		Date:            &date,
+		AnalysisType:    analysisType,
+		AnalysisData:    "{}",
+		MarkdownContent: fmt.Sprintf("# %s for %s\n", analysisType, date),
+		CreatedAt:       time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(analysis)
+	}
+	f.check("analysis", f.store.Analyses.Create(analysis))
+	return analysis
+}
+
+// check fails the test if a fixture could not be saved
+func (f *Fixtures) check(kind string, err error) {
+	f.tb.Helper()
+	if err != nil {
+		f.tb.Fatalf("failed to create %s fixture: %v", kind, err)
+	}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"strings"
+
+	"chatgpt-autopsy-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Store holds a repository for every table, all sharing one connection or
+// transaction
+type Store struct {
+	db *gorm.DB
+
+	Uploads             UploadRepository
+	Imports             ImportRepository
+	Extractions         ExtractionRepository
+	Conversations       ConversationRepository
+	Messages            MessageRepository
+	Threads             ThreadRepository
+	Analyses            AnalysisRepository
+	SeenStatuses        Repository[models.SeenStatus]
+	ActionableItems     Repository[models.ActionableItem]
+	Questions           Repository[models.Question]
+	NoiseFlags          Repository[models.NoiseFlag]
+	PromptTemplates     Repository[models.PromptTemplate]
+	AICache             Repository[models.AICacheEntry]
+	AIUsage             Repository[models.AIUsage]
+	SecretFindings      Repository[models.SecretFinding]
+	UploadSessions      Repository[models.UploadSession]
+	AccountProfiles     Repository[models.AccountProfile]
+	MessageFeedback     Repository[models.MessageFeedback]
+	ModelComparisons    Repository[models.ModelComparison]
+	SharedConversations Repository[models.SharedConversation]
+	MediaAssets         Repository[models.MediaAsset]
+	MessageAssets       Repository[models.MessageAsset]
+}
+
+// New creates a store on an open database
+func New(db *gorm.DB) *Store {
+	return &Store{
+		db:                  db,
+		Uploads:             UploadRepository{Repository[models.Upload]{db}},
+		Imports:             ImportRepository{Repository[models.Import]{db}},
+		Extractions:         ExtractionRepository{Repository[models.Extraction]{db}},
+		Conversations:       ConversationRepository{Repository[models.Conversation]{db}},
+		Messages:            MessageRepository{Repository[models.Message]{db}},
+		Threads:             ThreadRepository{Repository[models.Thread]{db}},
+		Analyses:            AnalysisRepository{Repository[models.Analysis]{db}},
+		SeenStatuses:        Repository[models.SeenStatus]{db},
+		ActionableItems:     Repository[models.ActionableItem]{db},
+		Questions:           Repository[models.Question]{db},
+		NoiseFlags:          Repository[models.NoiseFlag]{db},
+		PromptTemplates:     Repository[models.PromptTemplate]{db},
+		AICache:             Repository[models.AICacheEntry]{db},
+		AIUsage:             Repository[models.AIUsage]{db},
+		SecretFindings:      Repository[models.SecretFinding]{db},
+		UploadSessions:      Repository[models.UploadSession]{db},
+		AccountProfiles:     Repository[models.AccountProfile]{db},
+		MessageFeedback:     Repository[models.MessageFeedback]{db},
+		ModelComparisons:    Repository[models.ModelComparison]{db},
+		SharedConversations: Repository[models.SharedConversation]{db},
+		MediaAssets:         Repository[models.MediaAsset]{db},
+		MessageAssets:       Repository[models.MessageAsset]{db},
+	}
+}
+
+// DB returns the underlying connection, for raw SQL and health checks
+func (s *Store) DB() *gorm.DB {
+	return s.db
+}
+
+// Transaction runs fn with a store bound to a single transaction, committing
+// if it returns nil and rolling back otherwise
+func (s *Store) Transaction(fn func(tx *Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
+
+// UploadRepository accesses uploads
+type UploadRepository struct {
+	Repository[models.Upload]
+}
+
+// ByHash finds the upload of a file by its SHA256
+func (r UploadRepository) ByHash(hash string) (*models.Upload, error) {
+	return r.First("file_hash = ?", hash)
+}
+
+// SetStatus updates an upload's status and error message
+func (r UploadRepository) SetStatus(id uint, status string, errorMessage *string) error {
+	return r.Query().Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "error_message": errorMessage}).Error
+}
+
+// ImportRepository accesses import records
+type ImportRepository struct {
+	Repository[models.Import]
+}
+
+// ForUpload returns the import record of an upload
+func (r ImportRepository) ForUpload(uploadID uint) (*models.Import, error) {
+	return r.First("upload_id = ?", uploadID)
+}
+
+// ExtractionRepository accesses the files found in uploads
+type ExtractionRepository struct {
+	Repository[models.Extraction]
+}
+
+// ForUpload returns an upload's files of the given types and statuses
+func (r ExtractionRepository) ForUpload(uploadID uint, fileTypes, statuses []string) ([]models.Extraction, error) {
+	return r.Find("upload_id = ? AND file_type IN ? AND status IN ?", uploadID, fileTypes, statuses)
+}
+
+// ConversationRepository accesses conversations
+type ConversationRepository struct {
+	Repository[models.Conversation]
+}
+
+// ForUpload returns the conversations of an upload
+func (r ConversationRepository) ForUpload(uploadID uint) ([]models.Conversation, error) {
+	return r.Find("upload_id = ?", uploadID)
+}
+
+// IDsForUpload is a subquery selecting the IDs of an upload's
+// conversations, for use in IN conditions
+func (r ConversationRepository) IDsForUpload(uploadID uint) *gorm.DB {
+	return r.Query().Select("id").Where("upload_id = ?", uploadID)
+}
+
+// MessageRepository accesses messages
+type MessageRepository struct {
+	Repository[models.Message]
+}
+
+// ForConversation returns a conversation's messages in time order
+func (r MessageRepository) ForConversation(conversationID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.Query().Where("conversation_id = ?", conversationID).Order("timestamp ASC").Find(&messages).Error
+	return messages, err
+}
+
+// Search narrows a query to messages containing every word of text. On
+// PostgreSQL this is a full-text match served by the content index; on
+// SQLite each word is matched case-insensitively with LIKE.
+func (r MessageRepository) Search(text string) *gorm.DB {
+	query := r.Query()
+	if r.db.Dialector.Name() == "postgres" {
+		return query.Where("to_tsvector('simple', content) @@ plainto_tsquery('simple', ?)", text)
+	}
+	for _, word := range strings.Fields(text) {
+		query = query.Where(`content LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(word)+"%")
+	}
+	return query
+}
+
+// likeEscaper escapes the LIKE wildcards in a search word
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ThreadRepository accesses threads
+type ThreadRepository struct {
+	Repository[models.Thread]
+}
+
+// ForDate returns the threads of a date
+func (r ThreadRepository) ForDate(date string) ([]models.Thread, error) {
+	return r.Find("date = ?", date)
+}
+
+// ForConversation returns a conversation's threads in date order
+func (r ThreadRepository) ForConversation(conversationID uint) ([]models.Thread, error) {
+	var threads []models.Thread
+	err := r.Query().Where("conversation_id = ?", conversationID).Order("date ASC").Find(&threads).Error
+	return threads, err
+}
+
+// AnalysisRepository accesses analyses
+type AnalysisRepository struct {
+	Repository[models.Analysis]
+}
+
+// ForDate returns every analysis of a date
+func (r AnalysisRepository) ForDate(date string) ([]models.Analysis, error) {
+	return r.Find("date = ?", date)
+}
+
+// ForDateAndType returns the analysis of one type for a date
+func (r AnalysisRepository) ForDateAndType(date, analysisType string) (*models.Analysis, error) {
+	return r.First("date = ? AND analysis_type = ?", date, analysisType)
+}
@@ -0,0 +1,59 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository/repotest"
+)
+
+func TestMessageSearch(t *testing.T) {
+	for _, backend := range repotest.Backends {
+		t.Run(backend.Name, func(t *testing.T) {
+			store := backend.New(t)
+			fixtures := repotest.NewFixtures(t, store)
+			conversation := fixtures.Conversation(fixtures.Upload())
+			at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			for _, content := range []string{
+				"The quick brown fox",
+				"A lazy dog sleeps",
+				"Quick, the dog!",
+				"Save 50% today",
+				"Order 500 items",
+			} {
+				fixtures.Message(conversation, "user", content, at)
+			}
+
+			tests := []struct {
+				text string
+				want []string
+			}{
+				{"quick", []string{"The quick brown fox", "Quick, the dog!"}},
+				{"QUICK dog", []string{"Quick, the dog!"}},
+				{"50%", []string{"Save 50% today"}},
+				{"cat", nil},
+			}
+			for _, tt := range tests {
+				var messages []models.Message
+				if err := store.Messages.Search(tt.text).Order("id ASC").Find(&messages).Error; err != nil {
+					t.Fatalf("Search(%q) failed: %v", tt.text, err)
+				}
+				var got []string
+				for _, msg := range messages {
+					got = append(got, msg.Content)
+				}
+				if len(got) != len(tt.want) {
+					t.Errorf("Search(%q) = %q, want %q", tt.text, got, tt.want)
+					continue
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("Search(%q) = %q, want %q", tt.text, got, tt.want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"chatgpt-autopsy-go/internal/config"
+)
+
+// AIProvider is implemented by the AI backends used for enhanced analysis
+type AIProvider interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, prompt string) (*AICompletion, error)
+}
+
+// AICompletion holds the result of a single provider call
+type AICompletion struct {
+	Text         string `json:"text"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+}
+
+// NewAIProvider returns the configured AI provider, or nil when AI enhancement
+// is disabled or no API key is available for any provider
+func NewAIProvider(cfg *config.Config) AIProvider {
+	if !cfg.AI.EnhancementEnabled {
+		return nil
+	}
+
+	client := &http.Client{Timeout: cfg.AI.RequestTimeout}
+	openai := func() AIProvider {
+		if cfg.AI.OpenAIAPIKey == "" {
+			return nil
+		}
+		return &openAIProvider{cfg: cfg, client: client}
+	}
+	anthropic := func() AIProvider {
+		if cfg.AI.AnthropicAPIKey == "" {
+			return nil
+		}
+		return &anthropicProvider{cfg: cfg, client: client}
+	}
+
+	// Prefer the configured provider, fall back to whichever has a key
+	order := []func() AIProvider{anthropic, openai}
+	if cfg.AI.PreferredProvider == "openai" {
+		order = []func() AIProvider{openai, anthropic}
+	}
+	for _, build := range order {
+		if provider := build(); provider != nil {
+			return provider
+		}
+	}
+	return nil
+}
+
+// openAIProvider calls the OpenAI chat completions API
+type openAIProvider struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+func (p *openAIProvider) Name() string  { return "openai" }
+func (p *openAIProvider) Model() string { return p.cfg.AI.OpenAIModel }
+
+func (p *openAIProvider) Complete(ctx context.Context, prompt string) (*AICompletion, error) {
+	payload := map[string]interface{}{
+		"model":       p.cfg.AI.OpenAIModel,
+		"max_tokens":  p.cfg.AI.MaxOutputTokens,
+		"temperature": p.cfg.AI.Temperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + p.cfg.AI.OpenAIAPIKey,
+	}
+	if err := postJSON(ctx, p.client, "https://api.openai.com/v1/chat/completions", headers, payload, &response); err != nil {
+		return nil, fmt.Errorf("openai request failed: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("openai returned no choices")
+	}
+
+	return &AICompletion{
+		Text:         strings.TrimSpace(response.Choices[0].Message.Content),
+		InputTokens:  response.Usage.PromptTokens,
+		OutputTokens: response.Usage.CompletionTokens,
+	}, nil
+}
+
+// anthropicProvider calls the Anthropic messages API
+type anthropicProvider struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+func (p *anthropicProvider) Name() string  { return "anthropic" }
+func (p *anthropicProvider) Model() string { return p.cfg.AI.AnthropicModel }
+
+func (p *anthropicProvider) Complete(ctx context.Context, prompt string) (*AICompletion, error) {
+	payload := map[string]interface{}{
+		"model":       p.cfg.AI.AnthropicModel,
+		"max_tokens":  p.cfg.AI.MaxOutputTokens,
+		"temperature": p.cfg.AI.Temperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	}
+
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+
+	headers := map[string]string{
+		"x-api-key":         p.cfg.AI.AnthropicAPIKey,
+		"anthropic-version": "2023-06-01",
+	}
+	if err := postJSON(ctx, p.client, "https://api.anthropic.com/v1/messages", headers, payload, &response); err != nil {
+		return nil, fmt.Errorf("anthropic request failed: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &AICompletion{
+		Text:         strings.TrimSpace(text.String()),
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+	}, nil
+}
+
+// postJSON sends a JSON POST request and decodes the JSON response into out
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, truncate(string(respBody), 500))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most max bytes without splitting a UTF-8 rune
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && (s[cut]&0xC0) == 0x80 {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AICacheService caches AI completions so identical prompts are not re-sent
+type AICacheService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+}
+
+// NewAICacheService creates a new AI cache service
+func NewAICacheService(cfg *config.Config, log *zap.Logger, store *repository.Store) *AICacheService {
+	return &AICacheService{
+		cfg:   cfg,
+		log:   log,
+		store: store,
+	}
+}
+
+// CacheRequest identifies a provider call for caching
+type CacheRequest struct {
+	Provider string
+	Model    string
+	Template *models.PromptTemplate
+	Date     string
+	Prompt   string
+}
+
+// contentHash returns the SHA256 hex digest of the prompt
+func (r CacheRequest) contentHash() string {
+	sum := sha256.Sum256([]byte(r.Prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// key returns the cache key for the request
+func (r CacheRequest) key() string {
+	raw := fmt.Sprintf("%s|%s|%s|%d|%s", r.Provider, r.Model, r.Template.Name, r.Template.Version, r.contentHash())
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached completion for a request, or nil on a miss
+func (s *AICacheService) Get(req CacheRequest) (*AICompletion, error) {
+	if !s.cfg.AI.CacheEnabled {
+		return nil, nil
+	}
+
+	entry, err := s.store.AICache.First("cache_key = ?", req.key())
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read AI cache: %w", err)
+	}
+
+	// Expired entries count as a miss and are removed
+	if entry.ExpiresAt != nil && entry.ExpiresAt.Before(time.Now().UTC()) {
+		if err := s.store.AICache.Delete(entry); err != nil {
+			s.log.Warn("Failed to delete expired AI cache entry", zap.Uint("id", entry.ID), zap.Error(err))
+		}
+		return nil, nil
+	}
+
+	if err := s.store.AICache.Query().Where("id = ?", entry.ID).Update("hit_count", gorm.Expr("hit_count + 1")).Error; err != nil {
+		s.log.Warn("Failed to update AI cache hit count", zap.Uint("id", entry.ID), zap.Error(err))
+	}
+
+	return &AICompletion{
+		Text:         entry.Response,
+		InputTokens:  entry.InputTokens,
+		OutputTokens: entry.OutputTokens,
+	}, nil
+}
+
+// Put stores a completion for a request, replacing any previous entry
+func (s *AICacheService) Put(req CacheRequest, completion *AICompletion) error {
+	if !s.cfg.AI.CacheEnabled {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	entry := models.AICacheEntry{
+		CacheKey:         req.key(),
+		Provider:         req.Provider,
+		Model:            req.Model,
+		PromptTemplateID: req.Template.ID,
+		PromptVersion:    req.Template.Version,
+		ContentHash:      req.contentHash(),
+		Response:         completion.Text,
+		InputTokens:      completion.InputTokens,
+		OutputTokens:     completion.OutputTokens,
+		CreatedAt:        now,
+	}
+	if req.Date != "" {
+		entry.Date = &req.Date
+	}
+	if s.cfg.AI.CacheTTL > 0 {
+		expiresAt := now.Add(s.cfg.AI.CacheTTL)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	return s.store.Transaction(func(tx *repository.Store) error {
+		if _, err := tx.AICache.DeleteWhere("cache_key = ?", entry.CacheKey); err != nil {
+			return fmt.Errorf("failed to replace AI cache entry: %w", err)
+		}
+		if err := tx.AICache.Create(&entry); err != nil {
+			return fmt.Errorf("failed to write AI cache entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// Purge deletes cache entries matching the optional date and provider
+// filters and returns how many were removed. With no filters every entry is
+// removed.
+func (s *AICacheService) Purge(date, provider string) (int64, error) {
+	query := s.store.AICache.Query().Session(&gorm.Session{AllowGlobalUpdate: true})
+	if date != "" {
+		query = query.Where("date = ?", date)
+	}
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	result := query.Delete(&models.AICacheEntry{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge AI cache: %w", result.Error)
+	}
+
+	s.log.Info("AI cache purged",
+		zap.String("date", date),
+		zap.String("provider", provider),
+		zap.Int64("removed", result.RowsAffected),
+	)
+	return result.RowsAffected, nil
+}
+
+// PurgeExpired deletes entries past their expiry time
+func (s *AICacheService) PurgeExpired() (int64, error) {
+	removed, err := s.store.AICache.DeleteWhere("expires_at IS NOT NULL AND expires_at < ?", time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired AI cache entries: %w", err)
+	}
+	return removed, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,27 +9,58 @@ import (
 	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 // AnalysisService handles analysis generation
 type AnalysisService struct {
-	cfg *config.Config
-	log *zap.Logger
+	cfg     *config.Config
+	log     *zap.Logger
+	store   *repository.Store
+	prompts *PromptService
+	cache   *AICacheService
+	usage   *UsageService
+	redact  *RedactionService
+	ai      AIProvider
 }
 
 // NewAnalysisService creates a new analysis service
-func NewAnalysisService(cfg *config.Config, log *zap.Logger) *AnalysisService {
+func NewAnalysisService(cfg *config.Config, log *zap.Logger, store *repository.Store, prompts *PromptService, cache *AICacheService, usage *UsageService, redact *RedactionService) *AnalysisService {
 	return &AnalysisService{
-		cfg: cfg,
-		log: log,
+		cfg:     cfg,
+		log:     log,
+		store:   store,
+		prompts: prompts,
+		cache:   cache,
+		usage:   usage,
+		redact:  redact,
+		ai:      NewAIProvider(cfg),
 	}
 }
 
+// promptExcerptLength caps how much of each message is included in prompts
+const promptExcerptLength = 1000
+
+// minChunkBudget is the smallest per-chunk message budget worth chunking for
+const minChunkBudget = 256
+
+// aiResult records how an AI-enhanced analysis was produced
+type aiResult struct {
+	Content      string
+	Provider     string
+	Template     *models.PromptTemplate
+	InputTokens  int
+	OutputTokens int
+	Chunks       int
+	Calls        int
+	CacheHits    int
+	UsageIDs     []uint    // ledger rows to link once the analysis is saved
+	Redactor     *Redactor // pseudonymises content sent to the provider
+}
+
 // AnalysisDimensions are the 9 core analysis dimensions
 var AnalysisDimensions = []string{
 	"meaning",
@@ -44,37 +76,182 @@ var AnalysisDimensions = []string{
 
 // GenerateAnalysisForDate generates 9-dimensional analysis for a specific date
 func (s *AnalysisService) GenerateAnalysisForDate(date string, force bool) error {
-	// Verify Thread records exist for the date
-	var threads []models.Thread
-	if err := database.DB.Where("date = ?", date).Find(&threads).Error; err != nil {
-		return fmt.Errorf("failed to get threads for date: %w", err)
-	}
-
-	if len(threads) == 0 {
-		return fmt.Errorf("no threads found for date: %s", date)
-	}
-
 	// Check if analysis already exists
 	if !force {
-		var existing []models.Analysis
-		if err := database.DB.Where("date = ?", date).Find(&existing).Error; err == nil && len(existing) > 0 {
+		if existing, err := s.store.Analyses.ForDate(date); err == nil && len(existing) > 0 {
 			s.log.Info("Analysis already exists for date", zap.String("date", date))
 			return nil
 		}
 	}
 
+	threads, messages, err := s.loadDateMessages(date)
+	if err != nil {
+		return err
+	}
+
 	// Create analysis directory
 	analysisDir := filepath.Join(s.cfg.Directories.AnalysisDir, date)
 	if err := os.MkdirAll(analysisDir, 0755); err != nil {
 		return fmt.Errorf("failed to create analysis directory: %w", err)
 	}
 
+	// Generate analyses for each dimension, collecting findings for later prompts
+	findings := make(map[string]string)
+	for _, dimension := range AnalysisDimensions {
+		content, err := s.generateDimensionAnalysis(date, dimension, messages, threads, findings)
+		if err != nil {
+			s.log.Warn("Failed to generate dimension analysis",
+				zap.String("date", date),
+				zap.String("dimension", dimension),
+				zap.Error(err),
+			)
+			continue
+		}
+		findings[dimension] = content
+	}
+
+	// Generate synthesis and summary
+	if err := s.generateSynthesis(date, messages, threads, findings); err != nil {
+		s.log.Warn("Failed to generate synthesis", zap.String("date", date), zap.Error(err))
+	}
+
+	if err := s.generateSummary(date, messages, threads, findings); err != nil {
+		s.log.Warn("Failed to generate summary", zap.String("date", date), zap.Error(err))
+	}
+
+	s.log.Info("Analysis generation completed", zap.String("date", date))
+	return nil
+}
+
+// PreviewPrompts renders the prompts that would be sent for a date without
+// calling a provider. An empty name renders every template; templateID
+// overrides the active version for that name and requires a name.
+func (s *AnalysisService) PreviewPrompts(date, name string, templateID *uint) ([]PromptPreview, error) {
+	if templateID != nil && name == "" {
+		return nil, fmt.Errorf("invalid request: name is required when template_id is set")
+	}
+
+	threads, messages, err := s.loadDateMessages(date)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use stored analyses as prior findings
+	existing, err := s.store.Analyses.ForDate(date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing analyses: %w", err)
+	}
+	findings := make(map[string]string)
+	for _, analysis := range existing {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(analysis.AnalysisData), &data); err == nil {
+			if content, ok := data["content"].(string); ok {
+				findings[analysis.AnalysisType] = content
+			}
+		}
+	}
+
+	names := analysisPromptNames()
+	if name != "" {
+		if !isPromptName(name) {
+			return nil, fmt.Errorf("invalid prompt template name: %s", name)
+		}
+		names = []string{name}
+	}
+
+	var previews []PromptPreview
+	for _, promptName := range names {
+		var tmpl *models.PromptTemplate
+		if templateID != nil {
+			tmpl, err = s.prompts.GetTemplate(*templateID)
+			if err == nil && tmpl.Name != promptName {
+				err = fmt.Errorf("prompt template %d is not a %s template", *templateID, promptName)
+			}
+		} else {
+			tmpl, err = s.prompts.GetActiveTemplate(promptName)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data := s.buildPromptData(date, promptName, messages, threads, priorFindings(promptName, findings))
+		prompt, err := s.prompts.Render(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+
+		plan, err := s.planChunks(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+
+		preview := PromptPreview{
+			Name:             promptName,
+			PromptTemplateID: tmpl.ID,
+			PromptVersion:    tmpl.Version,
+			Prompt:           prompt,
+			EstimatedTokens:  EstimateTokens(prompt),
+			ChunkCount:       len(plan),
+		}
+		if len(plan) > 1 {
+			for _, chunk := range plan {
+				chunkPrompt, err := s.prompts.Render(tmpl, chunk)
+				if err != nil {
+					return nil, err
+				}
+				preview.ChunkPrompts = append(preview.ChunkPrompts, chunkPrompt)
+			}
+		}
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
+
+// PreviewRedaction shows exactly what would be sent to the provider for a
+// date: the map-stage prompts after redaction, with per-prompt stats
+func (s *AnalysisService) PreviewRedaction(date, name string) ([]RedactionPreview, error) {
+	prompts, err := s.PreviewPrompts(date, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var previews []RedactionPreview
+	for _, prompt := range prompts {
+		redactor := s.redact.NewRedactor()
+		preview := RedactionPreview{Name: prompt.Name}
+
+		sent := prompt.ChunkPrompts
+		if len(sent) == 0 {
+			sent = []string{prompt.Prompt}
+		}
+		for _, text := range sent {
+			preview.Prompts = append(preview.Prompts, redactor.Redact(text))
+		}
+		preview.Stats = redactor.Stats()
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+// loadDateMessages loads the threads for a date and their messages
+func (s *AnalysisService) loadDateMessages(date string) ([]models.Thread, []models.Message, error) {
+	// Verify Thread records exist for the date
+	threads, err := s.store.Threads.ForDate(date)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get threads for date: %w", err)
+	}
+
+	if len(threads) == 0 {
+		return nil, nil, fmt.Errorf("no threads found for date: %s", date)
+	}
+
 	// Get messages for this date from all threads
 	var messages []models.Message
 	for _, thread := range threads {
 		var threadMessages []models.Message
 		if thread.StartMessageID != nil && thread.EndMessageID != nil {
-			if err := database.DB.Where("id >= ? AND id <= ?", *thread.StartMessageID, *thread.EndMessageID).
+			if err := s.store.Messages.Query().Where("id >= ? AND id <= ?", *thread.StartMessageID, *thread.EndMessageID).
 				Order("timestamp ASC").
 				Find(&threadMessages).Error; err != nil {
 				s.log.Warn("Failed to get messages for thread",
@@ -87,87 +264,398 @@ func (s *AnalysisService) GenerateAnalysisForDate(date string, force bool) error
 		messages = append(messages, threadMessages...)
 	}
 
-	// Generate analyses for each dimension
+	return threads, messages, nil
+}
+
+// buildPromptData assembles template variables for a date
+func (s *AnalysisService) buildPromptData(date, name string, messages []models.Message, threads []models.Thread, findings map[string]string) PromptData {
+	data := PromptData{
+		Date:          date,
+		Name:          name,
+		MessageCount:  len(messages),
+		ThreadCount:   len(threads),
+		PriorFindings: findings,
+	}
+	for _, msg := range messages {
+		// Assistant replies get a shorter excerpt so user messages dominate the budget
+		length := promptExcerptLength
+		if msg.Role != "user" {
+			length = assistantExcerptLength
+		}
+		data.Messages = append(data.Messages, PromptMessage{
+			Role:    msg.Role,
+			Time:    msg.Timestamp.UTC().Format("15:04"),
+			Content: truncate(msg.Content, length),
+		})
+	}
+	return data
+}
+
+// priorFindings returns the findings visible to a template. Dimensions only
+// see dimensions generated before them; synthesis and summary see all.
+func priorFindings(name string, findings map[string]string) map[string]string {
+	visible := make(map[string]string)
 	for _, dimension := range AnalysisDimensions {
-		if err := s.generateDimensionAnalysis(date, dimension, messages, threads); err != nil {
-			s.log.Warn("Failed to generate dimension analysis",
-				zap.String("date", date),
-				zap.String("dimension", dimension),
-				zap.Error(err),
-			)
-			continue
+		if dimension == name {
+			break
+		}
+		if content, ok := findings[dimension]; ok {
+			visible[dimension] = content
 		}
 	}
+	if name == "summary" {
+		if content, ok := findings["synthesis"]; ok {
+			visible["synthesis"] = content
+		}
+	}
+	return visible
+}
 
-	// Generate synthesis and summary
-	if err := s.generateSynthesis(date, messages); err != nil {
-		s.log.Warn("Failed to generate synthesis", zap.String("date", date), zap.Error(err))
+// enhance renders the active template for name and sends it to the AI
+// provider, map-reducing over message chunks when the day does not fit in a
+// single request. It returns nil when AI enhancement is unavailable or fails,
+// in which case callers keep their heuristic content.
+func (s *AnalysisService) enhance(name string, data PromptData) *aiResult {
+	if s.ai == nil {
+		return nil
 	}
 
-	if err := s.generateSummary(date, messages); err != nil {
-		s.log.Warn("Failed to generate summary", zap.String("date", date), zap.Error(err))
+	tmpl, err := s.prompts.GetActiveTemplate(name)
+	if err != nil {
+		s.log.Warn("No prompt template for AI enhancement", zap.String("name", name), zap.Error(err))
+		return nil
 	}
 
-	s.log.Info("Analysis generation completed", zap.String("date", date))
-	return nil
+	plan, err := s.planChunks(tmpl, data)
+	if err != nil {
+		s.log.Warn("Failed to render prompt", zap.String("name", name), zap.Error(err))
+		return nil
+	}
+
+	result := &aiResult{
+		Provider: s.ai.Name(),
+		Template: tmpl,
+		Chunks:   len(plan),
+		Redactor: s.redact.NewRedactor(),
+	}
+
+	// Map: analyze each chunk independently
+	var findings []string
+	for _, chunk := range plan {
+		prompt, err := s.prompts.Render(tmpl, chunk)
+		if err == nil {
+			var text string
+			if text, err = s.complete(tmpl, data.Date, prompt, result); err == nil {
+				findings = append(findings, text)
+				continue
+			}
+		}
+		s.log.Warn("AI enhancement failed, falling back to heuristics",
+			zap.String("name", name),
+			zap.String("provider", s.ai.Name()),
+			zap.Int("chunk", chunk.Chunk),
+			zap.Int("chunks", len(plan)),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	// Reduce: merge chunk findings into a single answer
+	content, err := s.reduceFindings(data, findings, result)
+	if err != nil {
+		s.log.Warn("AI enhancement failed, falling back to heuristics",
+			zap.String("name", name),
+			zap.String("provider", s.ai.Name()),
+			zap.Error(err),
+		)
+		return nil
+	}
+	result.Content = content
+
+	s.log.Info("AI enhancement completed",
+		zap.String("name", name),
+		zap.String("provider", result.Provider),
+		zap.Int("chunks", result.Chunks),
+		zap.Int("input_tokens", result.InputTokens),
+		zap.Int("output_tokens", result.OutputTokens),
+		zap.Int("cache_hits", result.CacheHits),
+		zap.Int("redacted_values", result.Redactor.Total()),
+		zap.Any("redaction_stats", result.Redactor.Stats()),
+	)
+	return result
 }
 
-// generateDimensionAnalysis generates analysis for a specific dimension
-func (s *AnalysisService) generateDimensionAnalysis(date, dimension string, messages []models.Message, threads []models.Thread) error {
-	// Create template analysis data
-	analysisData := map[string]interface{}{
-		"dimension": dimension,
-		"date":      date,
-		"message_count": len(messages),
-		"thread_count": len(threads),
-		"content":   s.generateTemplateContent(dimension, messages),
+// promptBudget returns the input token budget for a single provider call
+func (s *AnalysisService) promptBudget() int {
+	return s.cfg.AI.MaxTokensPerRequest - s.cfg.AI.MaxOutputTokens
+}
+
+// planChunks splits prompt data into chunks whose rendered prompts fit the
+// prompt budget. It returns a single chunk when the full prompt already fits
+// or when the prompt size is not driven by the messages.
+func (s *AnalysisService) planChunks(tmpl *models.PromptTemplate, data PromptData) ([]PromptData, error) {
+	data.Chunk, data.ChunkCount = 1, 1
+
+	full, err := s.prompts.Render(tmpl, data)
+	if err != nil {
+		return nil, err
 	}
 
-	analysisDataJSON, _ := json.Marshal(analysisData)
+	budget := s.promptBudget()
+	if EstimateTokens(full) <= budget || len(data.Messages) == 0 {
+		return []PromptData{data}, nil
+	}
 
-	// Create markdown content
-	markdownContent := s.generateMarkdownContent(dimension, analysisData)
+	// Measure the template overhead without any messages
+	empty := data
+	empty.Messages = nil
+	empty.ChunkCount = s.cfg.AI.MaxChunks + 1
+	base, err := s.prompts.Render(tmpl, empty)
+	if err != nil {
+		return nil, err
+	}
 
-	// Get first thread for thread_id reference
-	var threadID *uint
-	if len(threads) > 0 {
-		threadID = &threads[0].ID
+	chunkBudget := budget - EstimateTokens(base)
+	if chunkBudget < minChunkBudget || EstimateTokens(base) >= EstimateTokens(full) {
+		s.log.Warn("Prompt exceeds token budget and cannot be chunked",
+			zap.String("name", tmpl.Name),
+			zap.Int("estimated_tokens", EstimateTokens(full)),
+			zap.Int("budget", budget),
+		)
+		return []PromptData{data}, nil
 	}
 
-	// Create or update analysis record
-	var analysis models.Analysis
-	err := database.DB.Where("date = ? AND analysis_type = ?", date, dimension).First(&analysis).Error
-	
-	if err == gorm.ErrRecordNotFound {
-		// Create new analysis
-		analysis = models.Analysis{
-			Date:            &date,
-			ThreadID:        threadID,
-			AnalysisType:    dimension,
-			AnalysisData:    string(analysisDataJSON),
-			MarkdownContent: markdownContent,
-			IsAIEnhanced:    false,
-			CreatedAt:       time.Now().UTC(),
+	messages, dropped := fitMessages(data.Messages, chunkBudget, s.cfg.AI.MaxChunks)
+	if dropped > 0 {
+		s.log.Info("Dropped messages to fit token budget",
+			zap.String("date", data.Date),
+			zap.String("name", tmpl.Name),
+			zap.Int("dropped", dropped),
+			zap.Int("kept", len(messages)),
+		)
+	}
+
+	chunks := chunkMessages(messages, chunkBudget)
+	plan := make([]PromptData, len(chunks))
+	for i, chunk := range chunks {
+		plan[i] = data
+		plan[i].Messages = chunk
+		plan[i].Chunk = i + 1
+		plan[i].ChunkCount = len(chunks)
+	}
+	return plan, nil
+}
+
+// reduceFindings merges per-chunk findings with the reduce template. Findings
+// are merged in groups that fit the prompt budget, over several rounds if
+// needed.
+func (s *AnalysisService) reduceFindings(data PromptData, findings []string, result *aiResult) (string, error) {
+	if len(findings) == 1 {
+		return findings[0], nil
+	}
+
+	tmpl, err := s.prompts.GetActiveTemplate(chunkReducePrompt)
+	if err != nil {
+		return "", err
+	}
+
+	render := func(group []string) (string, error) {
+		reduce := data
+		reduce.Messages = nil
+		reduce.ChunkCount = result.Chunks
+		reduce.ChunkFindings = group
+		return s.prompts.Render(tmpl, reduce)
+	}
+
+	budget := s.promptBudget()
+	for len(findings) > 1 {
+		var merged []string
+		for start := 0; start < len(findings); {
+			// Always merge at least two findings so every round makes progress
+			end := start + 2
+			if end > len(findings) {
+				end = len(findings)
+			}
+			for end < len(findings) {
+				prompt, err := render(findings[start : end+1])
+				if err != nil {
+					return "", err
+				}
+				if EstimateTokens(prompt) > budget {
+					break
+				}
+				end++
+			}
+
+			if end-start == 1 {
+				merged = append(merged, findings[start])
+				start = end
+				continue
+			}
+
+			prompt, err := render(findings[start:end])
+			if err != nil {
+				return "", err
+			}
+			text, err := s.complete(tmpl, data.Date, prompt, result)
+			if err != nil {
+				return "", err
+			}
+			merged = append(merged, text)
+			start = end
 		}
-		if err := database.DB.Create(&analysis).Error; err != nil {
-			return fmt.Errorf("failed to create analysis record: %w", err)
+		findings = merged
+	}
+
+	return findings[0], nil
+}
+
+// complete redacts a prompt, sends it to the provider or serves it from the
+// AI cache, and adds its token usage to result. The returned text has the
+// redacted values restored.
+func (s *AnalysisService) complete(tmpl *models.PromptTemplate, date, prompt string, result *aiResult) (string, error) {
+	prompt = result.Redactor.Redact(prompt)
+	req := CacheRequest{
+		Provider: s.ai.Name(),
+		Model:    s.ai.Model(),
+		Template: tmpl,
+		Date:     date,
+		Prompt:   prompt,
+	}
+	result.Calls++
+
+	completion, err := s.cache.Get(req)
+	if err != nil {
+		s.log.Warn("AI cache lookup failed", zap.Error(err))
+	}
+	if completion != nil {
+		result.CacheHits++
+	} else {
+		// Cached completions are free; only fresh calls count against the budget
+		exceeded, err := s.usage.BudgetExceeded()
+		if err != nil {
+			return "", err
+		}
+		if exceeded {
+			return "", fmt.Errorf("monthly AI budget of %.2f exceeded", s.cfg.AI.MonthlyBudget)
+		}
+
+		completion, err = s.callProvider(tmpl, date, prompt, result)
+		if err != nil {
+			return "", err
 		}
-	} else if err != nil {
+		if err := s.cache.Put(req, completion); err != nil {
+			s.log.Warn("Failed to cache AI completion", zap.Error(err))
+		}
+	}
+
+	result.InputTokens += completion.InputTokens
+	result.OutputTokens += completion.OutputTokens
+	return result.Redactor.Restore(completion.Text), nil
+}
+
+// callProvider sends a prompt to the AI provider and records the call in the
+// usage ledger
+func (s *AnalysisService) callProvider(tmpl *models.PromptTemplate, date, prompt string, result *aiResult) (*AICompletion, error) {
+	start := time.Now()
+	completion, callErr := s.ai.Complete(context.Background(), prompt)
+
+	usage := models.AIUsage{
+		Provider:         s.ai.Name(),
+		Model:            s.ai.Model(),
+		Dimension:        result.Template.Name,
+		PromptTemplateID: &tmpl.ID,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		Success:          callErr == nil,
+	}
+	if date != "" {
+		usage.Date = &date
+	}
+	if callErr != nil {
+		errorMsg := callErr.Error()
+		usage.ErrorMessage = &errorMsg
+	} else {
+		usage.InputTokens = completion.InputTokens
+		usage.OutputTokens = completion.OutputTokens
+	}
+
+	if err := s.usage.Record(&usage); err != nil {
+		s.log.Warn("Failed to record AI usage", zap.Error(err))
+	} else {
+		result.UsageIDs = append(result.UsageIDs, usage.ID)
+	}
+
+	return completion, callErr
+}
+
+// saveAnalysis creates or updates the analysis record for a date and type and
+// writes its markdown file
+func (s *AnalysisService) saveAnalysis(date, analysisType string, threadID *uint, analysisData map[string]interface{}, markdownContent string, result *aiResult) error {
+	analysisDataJSON, _ := json.Marshal(analysisData)
+
+	var analysis models.Analysis
+	existing, err := s.store.Analyses.ForDateAndType(date, analysisType)
+	if err != nil && err != repository.ErrNotFound {
 		return fmt.Errorf("failed to check existing analysis: %w", err)
+	}
+	exists := err == nil
+
+	if !exists {
+		analysis = models.Analysis{
+			Date:         &date,
+			ThreadID:     threadID,
+			AnalysisType: analysisType,
+			CreatedAt:    time.Now().UTC(),
+		}
 	} else {
-		// Update existing analysis
-		analysis.AnalysisData = string(analysisDataJSON)
-		analysis.MarkdownContent = markdownContent
+		analysis = *existing
 		updatedAt := time.Now().UTC()
 		analysis.UpdatedAt = &updatedAt
-		if err := database.DB.Save(&analysis).Error; err != nil {
+	}
+
+	analysis.AnalysisData = string(analysisDataJSON)
+	analysis.MarkdownContent = markdownContent
+	analysis.IsAIEnhanced = result != nil
+	analysis.AIProvider = nil
+	analysis.PromptTemplateID = nil
+	analysis.PromptVersion = nil
+	analysis.InputTokens = 0
+	analysis.OutputTokens = 0
+	analysis.ChunkCount = 0
+	analysis.CacheHit = false
+	analysis.RedactionStats = ""
+	if result != nil {
+		analysis.AIProvider = &result.Provider
+		analysis.PromptTemplateID = &result.Template.ID
+		analysis.PromptVersion = &result.Template.Version
+		analysis.InputTokens = result.InputTokens
+		analysis.OutputTokens = result.OutputTokens
+		analysis.ChunkCount = result.Chunks
+		analysis.CacheHit = result.Calls > 0 && result.CacheHits == result.Calls
+		if statsJSON, err := json.Marshal(result.Redactor.Stats()); err == nil {
+			analysis.RedactionStats = string(statsJSON)
+		}
+	}
+
+	if !exists {
+		if err := s.store.Analyses.Create(&analysis); err != nil {
+			return fmt.Errorf("failed to create analysis record: %w", err)
+		}
+	} else {
+		if err := s.store.Analyses.Save(&analysis); err != nil {
 			return fmt.Errorf("failed to update analysis record: %w", err)
 		}
 	}
 
+	if result != nil {
+		if err := s.usage.LinkAnalysis(result.UsageIDs, analysis.ID); err != nil {
+			s.log.Warn("Failed to link AI usage", zap.Uint("analysis_id", analysis.ID), zap.Error(err))
+		}
+	}
+
 	// Save markdown file
 	analysisDir := filepath.Join(s.cfg.Directories.AnalysisDir, date)
-	filePath := filepath.Join(analysisDir, fmt.Sprintf("%s.md", dimension))
+	filePath := filepath.Join(analysisDir, fmt.Sprintf("%s.md", analysisType))
 	if err := os.WriteFile(filePath, []byte(markdownContent), 0644); err != nil {
 		return fmt.Errorf("failed to write markdown file: %w", err)
 	}
@@ -175,6 +663,40 @@ func (s *AnalysisService) generateDimensionAnalysis(date, dimension string, mess
 	return nil
 }
 
+// generateDimensionAnalysis generates analysis for a specific dimension and
+// returns the content it produced
+func (s *AnalysisService) generateDimensionAnalysis(date, dimension string, messages []models.Message, threads []models.Thread, findings map[string]string) (string, error) {
+	content := s.generateTemplateContent(dimension, messages)
+	result := s.enhance(dimension, s.buildPromptData(date, dimension, messages, threads, priorFindings(dimension, findings)))
+	if result != nil {
+		content = result.Content
+	}
+
+	// Create analysis data
+	analysisData := map[string]interface{}{
+		"dimension": dimension,
+		"date":      date,
+		"message_count": len(messages),
+		"thread_count": len(threads),
+		"content":   content,
+	}
+
+	// Create markdown content
+	markdownContent := s.generateMarkdownContent(dimension, analysisData)
+
+	// Get first thread for thread_id reference
+	var threadID *uint
+	if len(threads) > 0 {
+		threadID = &threads[0].ID
+	}
+
+	if err := s.saveAnalysis(date, dimension, threadID, analysisData, markdownContent, result); err != nil {
+		return "", err
+	}
+
+	return content, nil
+}
+
 // generateTemplateContent generates template content for a dimension
 func (s *AnalysisService) generateTemplateContent(dimension string, messages []models.Message) string {
 	// Extract user messages for analysis
@@ -228,87 +750,46 @@ func (s *AnalysisService) generateMarkdownContent(dimension string, data map[str
 }
 
 // generateSynthesis generates synthesis analysis
-func (s *AnalysisService) generateSynthesis(date string, messages []models.Message) error {
+func (s *AnalysisService) generateSynthesis(date string, messages []models.Message, threads []models.Thread, findings map[string]string) error {
+	content := "Integrated view combining insights from all analysis dimensions."
+	markdownContent := fmt.Sprintf("# Synthesis\n\n**Date:** %s\n\n---\n\nIntegrated analysis combining all dimensions.", date)
+
+	result := s.enhance("synthesis", s.buildPromptData(date, "synthesis", messages, threads, priorFindings("synthesis", findings)))
+	if result != nil {
+		content = result.Content
+		markdownContent = fmt.Sprintf("# Synthesis\n\n**Date:** %s\n\n---\n\n%s", date, content)
+		findings["synthesis"] = content
+	}
+
 	analysisData := map[string]interface{}{
 		"type":         "synthesis",
 		"date":         date,
 		"message_count": len(messages),
-		"content":      "Integrated view combining insights from all analysis dimensions.",
-	}
-
-	analysisDataJSON, _ := json.Marshal(analysisData)
-	markdownContent := fmt.Sprintf("# Synthesis\n\n**Date:** %s\n\n---\n\nIntegrated analysis combining all dimensions.", date)
-
-	var analysis models.Analysis
-	err := database.DB.Where("date = ? AND analysis_type = ?", date, "synthesis").First(&analysis).Error
-	
-	if err == gorm.ErrRecordNotFound {
-		analysis = models.Analysis{
-			Date:            &date,
-			AnalysisType:    "synthesis",
-			AnalysisData:    string(analysisDataJSON),
-			MarkdownContent: markdownContent,
-			CreatedAt:       time.Now().UTC(),
-		}
-		if err := database.DB.Create(&analysis).Error; err != nil {
-			return err
-		}
-	} else {
-		analysis.AnalysisData = string(analysisDataJSON)
-		analysis.MarkdownContent = markdownContent
-		updatedAt := time.Now().UTC()
-		analysis.UpdatedAt = &updatedAt
-		if err := database.DB.Save(&analysis).Error; err != nil {
-			return err
-		}
+		"content":      content,
 	}
 
-	// Save file
-	analysisDir := filepath.Join(s.cfg.Directories.AnalysisDir, date)
-	filePath := filepath.Join(analysisDir, "synthesis.md")
-	return os.WriteFile(filePath, []byte(markdownContent), 0644)
+	return s.saveAnalysis(date, "synthesis", nil, analysisData, markdownContent, result)
 }
 
 // generateSummary generates summary analysis
-func (s *AnalysisService) generateSummary(date string, messages []models.Message) error {
+func (s *AnalysisService) generateSummary(date string, messages []models.Message, threads []models.Thread, findings map[string]string) error {
+	content := "Quick overview and key insights."
+	markdownContent := fmt.Sprintf("# Summary\n\n**Date:** %s\n\n---\n\nQuick overview of key insights from the day's conversations.", date)
+
+	result := s.enhance("summary", s.buildPromptData(date, "summary", messages, threads, priorFindings("summary", findings)))
+	if result != nil {
+		content = result.Content
+		markdownContent = fmt.Sprintf("# Summary\n\n**Date:** %s\n\n---\n\n%s", date, content)
+	}
+
 	analysisData := map[string]interface{}{
 		"type":         "summary",
 		"date":         date,
 		"message_count": len(messages),
-		"content":      "Quick overview and key insights.",
+		"content":      content,
 	}
 
-	analysisDataJSON, _ := json.Marshal(analysisData)
-	markdownContent := fmt.Sprintf("# Summary\n\n**Date:** %s\n\n---\n\nQuick overview of key insights from the day's conversations.", date)
-
-	var analysis models.Analysis
-	err := database.DB.Where("date = ? AND analysis_type = ?", date, "summary").First(&analysis).Error
-	
-	if err == gorm.ErrRecordNotFound {
-		analysis = models.Analysis{
-			Date:            &date,
-			AnalysisType:    "summary",
-			AnalysisData:    string(analysisDataJSON),
-			MarkdownContent: markdownContent,
-			CreatedAt:       time.Now().UTC(),
-		}
-		if err := database.DB.Create(&analysis).Error; err != nil {
-			return err
-		}
-	} else {
-		analysis.AnalysisData = string(analysisDataJSON)
-		analysis.MarkdownContent = markdownContent
-		updatedAt := time.Now().UTC()
-		analysis.UpdatedAt = &updatedAt
-		if err := database.DB.Save(&analysis).Error; err != nil {
-			return err
-		}
-	}
-
-	// Save file
-	analysisDir := filepath.Join(s.cfg.Directories.AnalysisDir, date)
-	filePath := filepath.Join(analysisDir, "summary.md")
-	return os.WriteFile(filePath, []byte(markdownContent), 0644)
+	return s.saveAnalysis(date, "summary", nil, analysisData, markdownContent, result)
 }
 
 // capitalizeFirst capitalizes the first letter of a string
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"chatgpt-autopsy-go/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// Errors raised while copying an entry out of an archive
+var (
+	errExtractionSizeExceeded = errors.New("exceeded max extraction size")
+	errEntryTooLarge          = errors.New("entry exceeds max entry size")
+	errCompressionRatio       = errors.New("entry exceeds max compression ratio")
+)
+
+// ratioCheckFloor is the size below which compression ratios are not
+// checked, since small, repetitive files legitimately compress very well
+const ratioCheckFloor = 1 << 20
+
+// exportEntry is one file of an uploaded export
+type exportEntry struct {
+	name           string
+	mode           os.FileMode  // type bits only; zero for regular files
+	size           int64        // declared uncompressed size, -1 if unknown
+	compressedSize int64        // declared compressed size, -1 if unknown
+	compressed     func() int64 // compressed bytes read so far, nil if stored uncompressed
+	src            io.Reader    // nil for entries that are not regular files or failed to open
+	openErr        error        // why a regular file could not be opened
+	fraction       float64      // fraction of the export read so far
+}
+
+// exportEntryFunc receives each entry of an export. Returning an error stops
+// the walk.
+type exportEntryFunc func(entry *exportEntry) error
+
+// walkExport calls fn for every non-directory entry in the stored upload
+func (s *ExtractionService) walkExport(upload *models.Upload, format string, fn exportEntryFunc) error {
+	switch format {
+	case FormatZip:
+		return s.walkZip(upload.StoredPath, fn)
+	case FormatTar, FormatTarGz:
+		return s.walkTar(upload.StoredPath, format == FormatTarGz, fn)
+	case FormatJSON, FormatJSONGz:
+		return s.walkJSON(upload, format == FormatJSONGz, fn)
+	}
+	return fmt.Errorf("unsupported file format: %s", format)
+}
+
+// walkZip walks the entries of a ZIP archive
+func (s *ExtractionService) walkZip(path string, fn exportEntryFunc) error {
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer zipReader.Close()
+
+	for i, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		compressedSize := clampSize(file.CompressedSize64)
+		entry := &exportEntry{
+			name:           file.Name,
+			mode:           file.Mode().Type(),
+			size:           clampSize(file.UncompressedSize64),
+			compressedSize: compressedSize,
+			compressed:     func() int64 { return compressedSize },
+			fraction:       float64(i+1) / float64(len(zipReader.File)),
+		}
+		if entry.mode != 0 {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Entries that cannot be opened are still passed on so they are
+		// recorded as rejected rather than vanishing from the catalogue
+		src, err := file.Open()
+		if err != nil {
+			s.log.Warn("Failed to open file in ZIP", zap.String("file", file.Name), zap.Error(err))
+			entry.openErr = err
+			if err := fn(entry); err != nil {
+				return err
+			}
+			continue
+		}
+		entry.src = src
+		err = fn(entry)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar walks the entries of a tar or tar.gz archive. Progress is measured
+// by how much of the stored file has been read.
+func (s *ExtractionService) walkTar(path string, gzipped bool, fn exportEntryFunc) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat tar file: %w", err)
+	}
+
+	counter := &countingReader{r: file}
+	var stream io.Reader = counter
+	var inflated *streamLimiter
+	if gzipped {
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+
+		// Skipping a rejected entry still inflates it, so the stream as a
+		// whole is capped as well
+		inflated = &streamLimiter{r: gz, limit: s.cfg.Upload.MaxExtractionSize}
+		stream = inflated
+	}
+
+	tarReader := tar.NewReader(stream)
+	for entries := 1; ; entries++ {
+		if inflated != nil {
+			// Allow for headers and padding per entry on top of file data
+			inflated.limit = s.cfg.Upload.MaxExtractionSize + int64(entries)*4096
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, errExtractionSizeExceeded) {
+				return fmt.Errorf("exceeded max extraction size: %d", s.cfg.Upload.MaxExtractionSize)
+			}
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		fraction := 1.0
+		if info.Size() > 0 {
+			fraction = float64(counter.n) / float64(info.Size())
+		}
+		entry := &exportEntry{
+			name:           header.Name,
+			size:           header.Size,
+			compressedSize: -1,
+			fraction:       fraction,
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			entry.src = tarReader
+			if gzipped {
+				start := counter.n
+				entry.compressed = func() int64 { return counter.n - start }
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			entry.mode = os.ModeSymlink
+		case tar.TypeChar:
+			entry.mode = os.ModeDevice | os.ModeCharDevice
+		case tar.TypeBlock:
+			entry.mode = os.ModeDevice
+		case tar.TypeFifo:
+			entry.mode = os.ModeNamedPipe
+		default:
+			entry.mode = os.ModeIrregular
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// walkJSON presents a bare JSON or JSON.gz upload as a single file, keeping
+// the original name when it looks like a conversations file
+func (s *ExtractionService) walkJSON(upload *models.Upload, gzipped bool, fn exportEntryFunc) error {
+	file, err := os.Open(upload.StoredPath)
+	if err != nil {
+		return fmt.Errorf("failed to open JSON file: %w", err)
+	}
+	defer file.Close()
+
+	name := filepath.Base(upload.OriginalFilename)
+	if strings.EqualFold(filepath.Ext(name), ".gz") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if s.determineFileType(name) != "conversation" {
+		name = "conversations.json"
+	}
+
+	entry := &exportEntry{
+		name:           name,
+		size:           upload.FileSize,
+		compressedSize: -1,
+		fraction:       1,
+	}
+
+	var src io.Reader = file
+	if gzipped {
+		counter := &countingReader{r: file}
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+		entry.size = -1
+		entry.compressed = func() int64 { return counter.n }
+	}
+
+	// Drop a UTF-8 byte order mark, which the JSON parser rejects
+	buffered := bufio.NewReader(src)
+	if bom, err := buffered.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		buffered.Discard(3)
+		if entry.size > 0 {
+			entry.size -= 3
+		}
+	}
+	entry.src = buffered
+	return fn(entry)
+}
+
+// uploadFormat returns the format of a stored upload. Uploads stored before
+// format detection have no usable MIME type and are sniffed.
+func uploadFormat(upload *models.Upload) (string, error) {
+	if format := FormatForMimeType(upload.MimeType); format != "" {
+		return format, nil
+	}
+	return DetectFormat(upload.StoredPath)
+}
+
+// errEntryFound stops a walk once the wanted entry has been read
+var errEntryFound = errors.New("entry found")
+
+// readEntry calls fn with the regular file named name in the stored upload
+func (s *ExtractionService) readEntry(upload *models.Upload, name string, fn exportEntryFunc) error {
+	format, err := uploadFormat(upload)
+	if err != nil {
+		return err
+	}
+
+	err = s.walkExport(upload, format, func(entry *exportEntry) error {
+		if entry.name != name {
+			return nil
+		}
+		if entry.openErr != nil {
+			return fmt.Errorf("failed to open %s: %w", name, entry.openErr)
+		}
+		if entry.src == nil {
+			return nil
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+		return errEntryFound
+	})
+	switch {
+	case errors.Is(err, errEntryFound):
+		return nil
+	case err != nil:
+		return err
+	}
+	return fmt.Errorf("file not found in upload: %s", name)
+}
+
+// checkEntry validates an entry before it is extracted and returns its
+// destination path, or the reason it is rejected
+func (s *ExtractionService) checkEntry(entry *exportEntry, extractDir string) (string, string) {
+	switch {
+	case entry.mode&os.ModeSymlink != 0:
+		return "", "links are not allowed"
+	case entry.mode&os.ModeDevice != 0:
+		return "", "device files are not allowed"
+	case entry.mode != 0:
+		return "", fmt.Sprintf("unsupported entry type: %s", entry.mode)
+	}
+
+	if reason := checkEntryName(entry.name); reason != "" {
+		return "", reason
+	}
+
+	path, err := s.sanitizePath(entry.name, extractDir)
+	if err != nil {
+		return "", err.Error()
+	}
+
+	if entry.size > s.cfg.Upload.MaxEntrySize {
+		return "", fmt.Sprintf("declared size %d exceeds max entry size %d", entry.size, s.cfg.Upload.MaxEntrySize)
+	}
+	if entry.size > ratioCheckFloor && entry.compressedSize >= 0 && entry.size > entry.compressedSize*s.cfg.Upload.MaxCompressionRatio {
+		return "", fmt.Sprintf("declared compression ratio exceeds %d:1", s.cfg.Upload.MaxCompressionRatio)
+	}
+
+	return path, ""
+}
+
+// checkEntryName rejects names that are not valid UTF-8 or contain control
+// characters
+func checkEntryName(name string) string {
+	if name == "" {
+		return "empty filename"
+	}
+	if !utf8.ValidString(name) {
+		return "filename is not valid UTF-8"
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "filename contains control characters"
+	}
+	return ""
+}
+
+// entryGuard caps the bytes copied out of one entry: against the space left
+// in the extraction budget, the per-entry limit and the compression ratio
+type entryGuard struct {
+	r          io.Reader
+	n          int64
+	remaining  int64
+	maxSize    int64
+	maxRatio   int64
+	compressed func() int64
+	err        error // set once a limit is hit
+}
+
+func (g *entryGuard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	g.n += int64(n)
+
+	switch {
+	case g.n > g.remaining:
+		g.err = errExtractionSizeExceeded
+	case g.n > g.maxSize:
+		g.err = fmt.Errorf("%w: more than %d bytes", errEntryTooLarge, g.maxSize)
+	case g.compressed != nil && g.n > ratioCheckFloor && g.n > g.compressed()*g.maxRatio:
+		g.err = fmt.Errorf("%w: inflated beyond %d:1", errCompressionRatio, g.maxRatio)
+	}
+	if g.err != nil {
+		return n, g.err
+	}
+	return n, err
+}
+
+// streamLimiter fails reads once more than limit bytes have passed through
+type streamLimiter struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *streamLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.limit {
+		return n, errExtractionSizeExceeded
+	}
+	return n, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// clampSize converts a declared ZIP size, treating values that overflow
+// int64 as unknown
+func clampSize(size uint64) int64 {
+	if size > 1<<63-1 {
+		return -1
+	}
+	return int64(size)
+}
@@ -0,0 +1,676 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/database"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// backupFormat is the version of the archive layout written by CreateBackup
+const backupFormat = 1
+
+// backupManifestName and backupDatabaseName are the archive entries holding
+// the manifest and the database snapshot
+const (
+	backupManifestName = "manifest.json"
+	backupDatabaseName = "database/chatgpt_autopsy.db"
+)
+
+// backupNamePattern matches the archives CreateBackup writes
+var backupNamePattern = regexp.MustCompile(`^autopsy-backup-\d{8}-\d{6}\.tar\.gz$`)
+
+// BackupService snapshots the database and data directories into a single
+// archive and restores them
+type BackupService struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	pipeline *PipelineService
+}
+
+// NewBackupService creates a new backup service. pipeline may be nil when
+// no uploads can be processed meanwhile, as in cmd/backup.
+func NewBackupService(cfg *config.Config, log *zap.Logger, store *repository.Store, pipeline *PipelineService) *BackupService {
+	return &BackupService{
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		pipeline: pipeline,
+	}
+}
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	Format        int               `json:"format"`
+	CreatedAt     time.Time         `json:"created_at"`
+	SchemaVersion int               `json:"schema_version"`
+	Directories   map[string]string `json:"directories"` // archive directory -> path it was backed up from
+	Files         []BackupFile      `json:"files"`
+}
+
+// BackupFile is one file in a backup archive
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupInfo describes a stored backup archive
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RestoreReport describes a completed restore
+type RestoreReport struct {
+	Manifest        *BackupManifest `json:"manifest"`
+	FilesRestored   int             `json:"files_restored"`
+	PathsRewritten  int64           `json:"paths_rewritten"`
+	SchemaMigrated  bool            `json:"schema_migrated"`
+	DurationSeconds float64         `json:"duration_seconds"`
+}
+
+// backupDirectories maps the archive directory of each data directory to
+// its configured path
+func (s *BackupService) backupDirectories() map[string]string {
+	return map[string]string{
+		"uploads":   s.cfg.Directories.UploadsDir,
+		"extracted": s.cfg.Directories.ExtractedDir,
+		"analysis":  s.cfg.Directories.AnalysisDir,
+		"messages":  s.cfg.Directories.MessagesDir,
+	}
+}
+
+// CreateBackup writes an archive of a consistent database snapshot, taken
+// online with VACUUM INTO, and the data directories, ending with a manifest
+// of checksums. The pipeline is paused throughout so no purge or reprocess
+// removes files the snapshot references; new uploads written while the
+// backup runs may be missed.
+func (s *BackupService) CreateBackup() (*BackupInfo, *BackupManifest, error) {
+	db := s.store.DB()
+	if db.Dialector.Name() != "sqlite" {
+		return nil, nil, fmt.Errorf("backups are only supported for SQLite; use pg_dump for PostgreSQL")
+	}
+
+	if s.pipeline != nil {
+		resume, err := s.pipeline.Pause("a backup is being created")
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot back up while processing uploads: %w", err)
+		}
+		defer resume()
+	}
+	if err := os.MkdirAll(s.cfg.Directories.BackupsDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create backups directory: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	name := fmt.Sprintf("autopsy-backup-%s.tar.gz", createdAt.Format("20060102-150405"))
+	archivePath := filepath.Join(s.cfg.Directories.BackupsDir, name)
+	if _, err := os.Stat(archivePath); err == nil {
+		return nil, nil, fmt.Errorf("backup %s already exists", name)
+	}
+
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := filepath.Join(s.cfg.Directories.BackupsDir, fmt.Sprintf(".snapshot-%d.db", createdAt.UnixNano()))
+	defer os.Remove(snapshot)
+	if err := db.Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	manifest := &BackupManifest{
+		Format:        backupFormat,
+		CreatedAt:     createdAt,
+		SchemaVersion: version,
+		Directories:   s.backupDirectories(),
+	}
+
+	tmp, err := os.CreateTemp(s.cfg.Directories.BackupsDir, "."+name+".*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.writeArchive(tmp, snapshot, manifest); err != nil {
+		tmp.Close()
+		return nil, nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, nil, fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return nil, nil, fmt.Errorf("failed to save backup file: %w", err)
+	}
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat backup file: %w", err)
+	}
+	s.log.Info("Backup created",
+		zap.String("name", name),
+		zap.Int("files", len(manifest.Files)),
+		zap.Int64("size", info.Size()),
+	)
+	return &BackupInfo{Name: name, Size: info.Size(), CreatedAt: createdAt}, manifest, nil
+}
+
+// writeArchive writes the snapshot, the data directories and the manifest
+// as a gzipped tar
+func (s *BackupService) writeArchive(w io.Writer, snapshot string, manifest *BackupManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := s.addFile(tw, snapshot, backupDatabaseName, manifest); err != nil {
+		return err
+	}
+
+	roles := make([]string, 0, len(manifest.Directories))
+	for role := range manifest.Directories {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		dir := manifest.Directories[role]
+		err := filepath.WalkDir(dir, func(filePath string, entry os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && filePath == dir {
+					return nil
+				}
+				return err
+			}
+			// Backups may live inside a data directory; never archive them
+			if entry.IsDir() && filePath == s.cfg.Directories.BackupsDir {
+				return filepath.SkipDir
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, filePath)
+			if err != nil {
+				return err
+			}
+			return s.addFile(tw, filePath, role+"/"+filepath.ToSlash(rel), manifest)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", role, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := &tar.Header{
+		Name:    backupManifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish backup archive: %w", err)
+	}
+	return gz.Close()
+}
+
+// addFile copies one file into the archive and records its checksum
+func (s *BackupService) addFile(tw *tar.Writer, filePath, name string, manifest *BackupManifest) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filePath, err)
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", filePath, err)
+	}
+
+	// A file that changes size while it is read would corrupt the archive
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tw, hash), io.LimitReader(f, info.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", filePath, err)
+	}
+	if written != info.Size() {
+		return fmt.Errorf("failed to archive %s: file shrank while being read", filePath)
+	}
+
+	manifest.Files = append(manifest.Files, BackupFile{
+		Path:   name,
+		Size:   written,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+	return nil
+}
+
+// ListBackups lists the stored backup archives, newest first
+func (s *BackupService) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.cfg.Directories.BackupsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read backups directory: %w", err)
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		createdAt, _ := time.Parse("20060102-150405", strings.TrimSuffix(strings.TrimPrefix(entry.Name(), "autopsy-backup-"), ".tar.gz"))
+		backups = append(backups, BackupInfo{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// BackupPath returns the path of a stored backup archive
+func (s *BackupService) BackupPath(name string) (string, error) {
+	if !backupNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid backup name: %s", name)
+	}
+	archivePath := filepath.Join(s.cfg.Directories.BackupsDir, name)
+	if _, err := os.Stat(archivePath); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("backup not found: %s", name)
+		}
+		return "", fmt.Errorf("failed to stat backup: %w", err)
+	}
+	return archivePath, nil
+}
+
+// VerifyBackup unpacks an archive to a scratch directory and checks it as a
+// restore would, without changing anything
+func (s *BackupService) VerifyBackup(archivePath string) (*BackupManifest, error) {
+	if err := os.MkdirAll(s.cfg.Directories.BackupsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backups directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(s.cfg.Directories.BackupsDir, ".verify-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	return s.unpack(archivePath, staging)
+}
+
+// RestoreBackup replaces the database and data directories with the
+// contents of an archive. The archive is unpacked and verified in full
+// first; nothing is replaced if any check fails. The pipeline is paused
+// while the restore runs.
+func (s *BackupService) RestoreBackup(archivePath string) (*RestoreReport, error) {
+	started := time.Now()
+	db := s.store.DB()
+	if db.Dialector.Name() != "sqlite" {
+		return nil, fmt.Errorf("restores are only supported for SQLite; use pg_restore for PostgreSQL")
+	}
+
+	if s.pipeline != nil {
+		resume, err := s.pipeline.Pause("a backup is being restored")
+		if err != nil {
+			return nil, fmt.Errorf("cannot restore while processing uploads: %w", err)
+		}
+		defer resume()
+	}
+
+	if err := os.MkdirAll(s.cfg.Directories.BackupsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backups directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(s.cfg.Directories.BackupsDir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := s.unpack(archivePath, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	// Swap the directories first: renames are cheap to undo if the
+	// database cannot be restored
+	undo, commit, err := s.swapDirectories(staging)
+	if err != nil {
+		return nil, err
+	}
+	if err := restoreSQLite(db, filepath.Join(staging, filepath.FromSlash(backupDatabaseName))); err != nil {
+		if undoErr := undo(); undoErr != nil {
+			s.log.Error("Failed to put data directories back after a failed restore", zap.Error(undoErr))
+		}
+		return nil, fmt.Errorf("failed to restore database: %w", err)
+	}
+	commit()
+
+	report := &RestoreReport{
+		Manifest:      manifest,
+		FilesRestored: len(manifest.Files) - 1,
+	}
+
+	// Bring an older snapshot up to this build's schema
+	if manifest.SchemaVersion < database.LatestVersion() {
+		if err := database.Migrate(db, s.log); err != nil {
+			return report, fmt.Errorf("database restored but migrations failed: %w", err)
+		}
+		report.SchemaMigrated = true
+	}
+
+	// Stored paths are absolute; point them at this machine's directories
+	rewritten, err := s.rewritePaths(manifest.Directories)
+	if err != nil {
+		return report, fmt.Errorf("database restored but file paths could not be updated: %w", err)
+	}
+	report.PathsRewritten = rewritten
+	report.DurationSeconds = time.Since(started).Seconds()
+
+	s.log.Info("Backup restored",
+		zap.String("archive", archivePath),
+		zap.Int("files", report.FilesRestored),
+		zap.Int64("paths_rewritten", rewritten),
+	)
+	return report, nil
+}
+
+// unpack extracts an archive into staging and verifies it: every entry must
+// be a safe relative path, every file must match the manifest's size and
+// checksum with none missing or extra, and the database snapshot must pass
+// an integrity check at a schema version this build can run
+func (s *BackupService) unpack(archivePath, staging string) (*BackupManifest, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	directories := s.backupDirectories()
+	checksums := make(map[string]BackupFile)
+	var manifestData []byte
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			// Reach the gzip trailer so its checksum is checked too
+			if _, err := io.Copy(io.Discard, gz); err != nil {
+				return nil, fmt.Errorf("invalid backup archive: %w", err)
+			}
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("invalid backup archive: %s is not a regular file", header.Name)
+		}
+
+		name := header.Name
+		if name == backupManifestName {
+			if manifestData, err = io.ReadAll(io.LimitReader(tr, 64<<20)); err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			continue
+		}
+		if path.Clean(name) != name || path.IsAbs(name) || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid backup archive: unsafe path %s", name)
+		}
+		if _, ok := directories[strings.SplitN(name, "/", 2)[0]]; !ok && name != backupDatabaseName {
+			return nil, fmt.Errorf("invalid backup archive: unexpected file %s", name)
+		}
+		if _, ok := checksums[name]; ok {
+			return nil, fmt.Errorf("invalid backup archive: duplicate file %s", name)
+		}
+
+		dest := filepath.Join(staging, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack %s: %w", name, err)
+		}
+		hash := sha256.New()
+		written, err := io.Copy(io.MultiWriter(out, hash), tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack %s: %w", name, err)
+		}
+		checksums[name] = BackupFile{Path: name, Size: written, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	}
+
+	if manifestData == nil {
+		return nil, fmt.Errorf("invalid backup archive: %s is missing", backupManifestName)
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if manifest.Format != backupFormat {
+		return nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	if manifest.SchemaVersion > database.LatestVersion() {
+		return nil, fmt.Errorf("%w: backup is at version %d, this build supports up to %d",
+			database.ErrSchemaTooNew, manifest.SchemaVersion, database.LatestVersion())
+	}
+
+	for _, file := range manifest.Files {
+		actual, ok := checksums[file.Path]
+		if !ok {
+			return nil, fmt.Errorf("backup is incomplete: %s is missing", file.Path)
+		}
+		if actual.Size != file.Size || actual.SHA256 != file.SHA256 {
+			return nil, fmt.Errorf("backup is corrupt: checksum mismatch for %s", file.Path)
+		}
+		delete(checksums, file.Path)
+	}
+	for name := range checksums {
+		return nil, fmt.Errorf("invalid backup archive: %s is not in the manifest", name)
+	}
+
+	if err := checkSQLite(filepath.Join(staging, filepath.FromSlash(backupDatabaseName))); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// swapDirectories moves the staged data directories into place. The current
+// ones are kept aside until commit removes them or undo puts them back.
+func (s *BackupService) swapDirectories(staging string) (undo func() error, commit func(), err error) {
+	suffix := fmt.Sprintf(".replaced-%d", time.Now().UnixNano())
+	var moved []string
+	undo = func() error {
+		var firstErr error
+		for _, dir := range moved {
+			if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if err := os.Rename(dir+suffix, dir); err != nil && !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+	commit = func() {
+		for _, dir := range moved {
+			os.RemoveAll(dir + suffix)
+		}
+	}
+
+	for role, dir := range s.backupDirectories() {
+		staged := filepath.Join(staging, role)
+		if err := os.MkdirAll(staged, 0755); err != nil {
+			undo()
+			return nil, nil, fmt.Errorf("failed to stage %s: %w", role, err)
+		}
+		if err := os.Rename(dir, dir+suffix); err != nil && !os.IsNotExist(err) {
+			undo()
+			return nil, nil, fmt.Errorf("failed to move %s aside: %w", dir, err)
+		}
+		moved = append(moved, dir)
+		if err := os.Rename(staged, dir); err != nil {
+			undo()
+			return nil, nil, fmt.Errorf("failed to restore %s: %w", dir, err)
+		}
+	}
+	return undo, commit, nil
+}
+
+// rewritePaths points stored file paths recorded under the backed-up data
+// directories at the configured ones. SQLite's SUBSTR and LENGTH count
+// characters, so the prefix is measured in SQL rather than in Go bytes.
+func (s *BackupService) rewritePaths(from map[string]string) (int64, error) {
+	columns := []struct{ table, column string }{
+		{"uploads", "stored_path"},
+		{"upload_sessions", "temp_path"},
+		{"extractions", "file_path"},
+		{"conversations", "source_file_path"},
+	}
+
+	var rewritten int64
+	to := s.backupDirectories()
+	err := s.store.Transaction(func(tx *repository.Store) error {
+		for role, oldDir := range from {
+			newDir, ok := to[role]
+			if !ok || oldDir == newDir || oldDir == "" {
+				continue
+			}
+			prefix := oldDir + string(filepath.Separator)
+			for _, c := range columns {
+				result := tx.DB().Exec(
+					fmt.Sprintf("UPDATE %s SET %s = ? || SUBSTR(%s, LENGTH(?) + 1) WHERE INSTR(%s, ?) = 1", c.table, c.column, c.column, c.column),
+					newDir+string(filepath.Separator), prefix, prefix,
+				)
+				if result.Error != nil {
+					return fmt.Errorf("failed to update %s.%s: %w", c.table, c.column, result.Error)
+				}
+				rewritten += result.RowsAffected
+			}
+		}
+		return nil
+	})
+	return rewritten, err
+}
+
+// checkSQLite runs an integrity check on a database file
+func checkSQLite(dbPath string) error {
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open database snapshot: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup database is unreadable: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup database failed its integrity check: %s", result)
+	}
+	return nil
+}
+
+// restoreSQLite copies a database file over the live database with the
+// SQLite backup API, holding a connection so nothing else runs meanwhile
+func restoreSQLite(db *gorm.DB, srcPath string) error {
+	liveDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	srcDB, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+
+	ctx := context.Background()
+	dstConn, err := liveDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			dstSQLite, ok := dst.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("live database is not SQLite")
+			}
+			srcSQLite, ok := src.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup database is not SQLite")
+			}
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository/repotest"
+
+	"go.uber.org/zap"
+)
+
+func TestRewritePathsNonASCII(t *testing.T) {
+	cfg := repotest.Config(t)
+	store := repotest.NewStore(t)
+	fixtures := repotest.NewFixtures(t, store)
+
+	// SUBSTR counts characters, so a multi-byte directory name must not
+	// shift the rewritten suffix
+	oldDir := filepath.Join(string(filepath.Separator), "home", "josé", "données", "uploads")
+	upload := fixtures.Upload(func(u *models.Upload) {
+		u.StoredPath = filepath.Join(oldDir, "export.zip")
+	})
+	other := fixtures.Upload(func(u *models.Upload) {
+		u.StoredPath = filepath.Join(string(filepath.Separator), "elsewhere", "export.zip")
+	})
+
+	backups := NewBackupService(cfg, zap.NewNop(), store, nil)
+	rewritten, err := backups.rewritePaths(map[string]string{"uploads": oldDir})
+	if err != nil {
+		t.Fatalf("rewritePaths failed: %v", err)
+	}
+	if rewritten != 1 {
+		t.Errorf("rewritten = %d, want 1", rewritten)
+	}
+
+	got, err := store.Uploads.Get(upload.ID)
+	if err != nil {
+		t.Fatalf("failed to reload upload: %v", err)
+	}
+	if want := filepath.Join(cfg.Directories.UploadsDir, "export.zip"); got.StoredPath != want {
+		t.Errorf("stored path = %q, want %q", got.StoredPath, want)
+	}
+
+	untouched, err := store.Uploads.Get(other.ID)
+	if err != nil {
+		t.Fatalf("failed to reload upload: %v", err)
+	}
+	if untouched.StoredPath != other.StoredPath {
+		t.Errorf("path outside the old directory was rewritten to %q", untouched.StoredPath)
+	}
+}
+
+func TestCreateBackupPausesPipeline(t *testing.T) {
+	cfg := repotest.Config(t)
+	store := repotest.NewStore(t)
+	pipeline := NewPipelineService(cfg, zap.NewNop(), store, nil, nil, nil, nil, nil, nil, nil)
+
+	// A running upload keeps the backup from starting
+	if err := pipeline.acquire(1); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	backups := NewBackupService(cfg, zap.NewNop(), store, pipeline)
+	if _, _, err := backups.CreateBackup(); err == nil {
+		t.Fatal("backup ran while an upload was being processed")
+	}
+	pipeline.release(1)
+
+	if _, _, err := backups.CreateBackup(); err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if reason := pipeline.Paused(); reason != "" {
+		t.Errorf("pipeline still paused after backup: %s", reason)
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Sensitive value categories recognised by the detectors
+const (
+	CategoryEmail      = "email"
+	CategoryPhone      = "phone"
+	CategoryAddress    = "address"
+	CategoryIPAddress  = "ip_address"
+	CategoryCreditCard = "credit_card"
+	CategoryPrivateKey = "private_key"
+	CategoryAPIKey     = "api_key"
+	CategoryJWT        = "jwt"
+	CategoryConnString = "connection_string"
+	CategoryPassword   = "password"
+	CategorySecret     = "secret" // high-entropy token without a known prefix
+)
+
+// SensitiveCategories lists every detector category in match priority order
+var SensitiveCategories = []string{
+	CategoryPrivateKey,
+	CategoryConnString,
+	CategoryAPIKey,
+	CategoryJWT,
+	CategoryPassword,
+	CategoryEmail,
+	CategoryCreditCard,
+	CategoryIPAddress,
+	CategoryPhone,
+	CategoryAddress,
+	CategorySecret,
+}
+
+// SecretCategories lists the categories that are credentials rather than
+// personal data
+var SecretCategories = []string{
+	CategoryPrivateKey,
+	CategoryConnString,
+	CategoryAPIKey,
+	CategoryJWT,
+	CategoryPassword,
+	CategorySecret,
+}
+
+// detector finds values of one category. validate, when set, filters out
+// pattern matches that are not real values (bad checksums, too few digits).
+type detector struct {
+	category string
+	pattern  *regexp.Regexp
+	validate func(string) bool
+}
+
+// secretCandidatePattern matches long runs of token characters that may be
+// credentials when their entropy is high enough
+var secretCandidatePattern = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{20,}`)
+
+// newDetectors builds the detectors for the enabled categories
+func newDetectors(categories []string, entropyThreshold float64, minSecretLength int) []detector {
+	enabled := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		enabled[strings.TrimSpace(category)] = true
+	}
+
+	all := map[string]detector{
+		CategoryPrivateKey: {
+			category: CategoryPrivateKey,
+			pattern:  regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+		},
+		CategoryAPIKey: {
+			category: CategoryAPIKey,
+			pattern: regexp.MustCompile(`\b(?:` +
+				`sk-ant-[A-Za-z0-9_\-]{20,}` + // Anthropic
+				`|sk-(?:proj-)?[A-Za-z0-9_\-]{20,}` + // OpenAI
+				`|(?:AKIA|ASIA)[0-9A-Z]{16}` + // AWS access key ID
+				`|gh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+				`|github_pat_[A-Za-z0-9_]{22,}` +
+				`|xox[abprs]-[A-Za-z0-9\-]{10,}` + // Slack
+				`|AIza[0-9A-Za-z_\-]{35}` + // Google
+				`|(?:sk|rk|pk)_(?:live|test)_[A-Za-z0-9]{16,}` + // Stripe
+				`|SG\.[A-Za-z0-9_\-]{22}\.[A-Za-z0-9_\-]{43}` + // SendGrid
+				`|glpat-[A-Za-z0-9_\-]{20}` + // GitLab
+				`|hf_[A-Za-z0-9]{30,}` + // Hugging Face
+				`)`),
+		},
+		CategoryJWT: {
+			category: CategoryJWT,
+			pattern:  regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}`),
+		},
+		CategoryConnString: {
+			category: CategoryConnString,
+			pattern: regexp.MustCompile(`(?i)\b(?:postgres(?:ql)?|mysql|mariadb|mongodb(?:\+srv)?|redis|rediss|amqps?|mssql|sqlserver)://[^\s:@/]+:[^\s@/]+@[^\s"'<>]+` +
+				`|(?i)\b(?:Server|Data Source|Host)=[^;\n]+;[^\n]*?(?:Password|Pwd)=[^;\s]+`),
+		},
+		CategoryPassword: {
+			category: CategoryPassword,
+			pattern:  regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|passphrase)\s*[:=]\s*["']?[^\s"']{6,}["']?`),
+			validate: func(value string) bool {
+				// Skip placeholders like password=<your-password> or ********
+				secret := secretValue(CategoryPassword, value)
+				return !strings.ContainsAny(secret, "<>{}$") && strings.Trim(secret, "*x.") != ""
+			},
+		},
+		CategoryEmail: {
+			category: CategoryEmail,
+			pattern:  regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		CategoryCreditCard: {
+			category: CategoryCreditCard,
+			pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+			validate: luhnValid,
+		},
+		CategoryIPAddress: {
+			category: CategoryIPAddress,
+			pattern:  regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+			validate: validIPv4,
+		},
+		CategoryPhone: {
+			category: CategoryPhone,
+			pattern:  regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{2,4}\)|\d{2,4})[\s.\-]?\d{3,4}[\s.\-]?\d{3,4}\b`),
+			validate: func(value string) bool {
+				digits := countDigits(value)
+				return digits >= 10 && digits <= 15
+			},
+		},
+		CategoryAddress: {
+			category: CategoryAddress,
+			pattern: regexp.MustCompile(`\b\d{1,5}\s+(?:[A-Z][A-Za-z]+\s+){1,4}` +
+				`(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way|Place|Pl|Terrace|Parkway|Pkwy)\b\.?` +
+				`(?:,?\s+(?:Apt|Suite|Unit|#)\s*[A-Za-z0-9\-]+)?`),
+		},
+		CategorySecret: {
+			category: CategorySecret,
+			pattern:  secretCandidatePattern,
+			validate: func(value string) bool {
+				return looksLikeSecret(value, entropyThreshold, minSecretLength)
+			},
+		},
+	}
+
+	var detectors []detector
+	for _, category := range SensitiveCategories {
+		if enabled[category] {
+			detectors = append(detectors, all[category])
+		}
+	}
+	return detectors
+}
+
+// sensitiveMatch is a detector match at [start, end) of the scanned text
+type sensitiveMatch struct {
+	category   string
+	start, end int
+}
+
+// findSensitive runs every detector over the original text and returns the
+// valid matches in text order. Where matches overlap, the one from the
+// detector earlier in SensitiveCategories wins, so no value is matched twice.
+func findSensitive(detectors []detector, text string) []sensitiveMatch {
+	var matches []sensitiveMatch
+	for _, d := range detectors {
+		for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+			if d.validate != nil && !d.validate(text[loc[0]:loc[1]]) {
+				continue
+			}
+			overlaps := false
+			for _, m := range matches {
+				if loc[0] < m.end && m.start < loc[1] {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				matches = append(matches, sensitiveMatch{category: d.category, start: loc[0], end: loc[1]})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// secretValue extracts the credential itself from a detector match, dropping
+// labels such as "password=" and the user part of connection strings
+func secretValue(category, match string) string {
+	switch category {
+	case CategoryPassword:
+		if i := strings.IndexAny(match, ":="); i >= 0 {
+			return strings.Trim(strings.TrimSpace(match[i+1:]), `"'`)
+		}
+	case CategoryConnString:
+		if scheme := strings.Index(match, "://"); scheme >= 0 {
+			rest := match[scheme+3:]
+			if at := strings.LastIndex(rest, "@"); at >= 0 {
+				if colon := strings.Index(rest[:at], ":"); colon >= 0 {
+					return rest[colon+1 : at]
+				}
+			}
+		}
+		lower := strings.ToLower(match)
+		for _, key := range []string{"password=", "pwd="} {
+			if i := strings.Index(lower, key); i >= 0 {
+				value := match[i+len(key):]
+				if end := strings.IndexAny(value, "; "); end >= 0 {
+					value = value[:end]
+				}
+				return value
+			}
+		}
+	}
+	return match
+}
+
+// maskSecret returns a preview of a detector match with the credential
+// masked, keeping enough of it to recognise which one leaked
+func maskSecret(category, match string) string {
+	if category == CategoryPrivateKey {
+		header := match
+		if i := strings.Index(match, "-----\n"); i >= 0 {
+			header = match[:i+5]
+		} else if len(header) > 40 {
+			header = header[:40]
+		}
+		return fmt.Sprintf("%s [%d chars]", header, len(match))
+	}
+
+	secret := secretValue(category, match)
+	masked := maskValue(secret)
+	if secret == match {
+		return masked
+	}
+	return strings.Replace(match, secret, masked, 1)
+}
+
+// maskValue keeps the first and last four characters of long values
+func maskValue(value string) string {
+	if len(value) <= 12 {
+		return strings.Repeat("*", len(value))
+	}
+	hidden := len(value) - 8
+	if hidden > 12 {
+		hidden = 12
+	}
+	return value[:4] + strings.Repeat("*", hidden) + value[len(value)-4:]
+}
+
+// looksLikeSecret reports whether a token has the length, character mix and
+// entropy of a generated credential
+func looksLikeSecret(value string, entropyThreshold float64, minLength int) bool {
+	if len(value) < minLength {
+		return false
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range value {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		} else if unicode.IsDigit(r) {
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return false
+	}
+
+	return shannonEntropy(value) >= entropyThreshold
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// luhnValid reports whether the digits in value pass the Luhn checksum
+func luhnValid(value string) bool {
+	var digits []int
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIPv4 reports whether value is a dotted quad with octets in range
+func validIPv4(value string) bool {
+	parts := strings.Split(value, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, part := range parts {
+		if len(part) > 1 && part[0] == '0' {
+			return false
+		}
+		n := 0
+		for _, r := range part {
+			n = n*10 + int(r-'0')
+		}
+		if n > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+// countDigits returns the number of ASCII digits in value
+func countDigits(value string) int {
+	count := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ExportDataService serves the account, feedback, comparison and share
+// records imported alongside conversations
+type ExportDataService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+}
+
+// NewExportDataService creates a new export data service
+func NewExportDataService(cfg *config.Config, log *zap.Logger, store *repository.Store) *ExportDataService {
+	return &ExportDataService{
+		cfg:   cfg,
+		log:   log,
+		store: store,
+	}
+}
+
+// ExportDataFilter narrows feedback, comparison and share listings
+type ExportDataFilter struct {
+	UploadID       uint
+	ConversationID uint
+	Rating         string // feedback only
+}
+
+// GetAccountProfile returns the account profile imported with an upload
+func (s *ExportDataService) GetAccountProfile(uploadID uint) (*models.AccountProfile, error) {
+	profile, err := s.store.AccountProfiles.First("upload_id = ?", uploadID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("account profile not found for upload: %d", uploadID)
+		}
+		return nil, fmt.Errorf("failed to get account profile: %w", err)
+	}
+	return profile, nil
+}
+
+// ListFeedback lists message ratings, newest first
+func (s *ExportDataService) ListFeedback(filter ExportDataFilter, page, limit int) ([]models.MessageFeedback, int64, error) {
+	var feedback []models.MessageFeedback
+	query := s.filter(s.store.MessageFeedback.Query(), filter)
+	if filter.Rating != "" {
+		query = query.Where("rating = ?", normalizeRating(filter.Rating))
+	}
+	total, err := paginate(query, "created_at DESC, id DESC", page, limit, &feedback)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list feedback: %w", err)
+	}
+	return feedback, total, nil
+}
+
+// FeedbackSummary returns the number of ratings of each kind
+func (s *ExportDataService) FeedbackSummary(filter ExportDataFilter) (map[string]int, error) {
+	var counts []struct {
+		Rating string
+		Count  int
+	}
+	if err := s.filter(s.store.MessageFeedback.Query(), filter).
+		Select("rating, COUNT(*) AS count").
+		Group("rating").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count feedback: %w", err)
+	}
+
+	summary := make(map[string]int, len(counts))
+	for _, count := range counts {
+		summary[count.Rating] = count.Count
+	}
+	return summary, nil
+}
+
+// ListComparisons lists model comparison choices, newest first
+func (s *ExportDataService) ListComparisons(filter ExportDataFilter, page, limit int) ([]models.ModelComparison, int64, error) {
+	var comparisons []models.ModelComparison
+	total, err := paginate(s.filter(s.store.ModelComparisons.Query(), filter), "created_at DESC, id DESC", page, limit, &comparisons)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list comparisons: %w", err)
+	}
+	return comparisons, total, nil
+}
+
+// ListSharedConversations lists share links
+func (s *ExportDataService) ListSharedConversations(filter ExportDataFilter, page, limit int) ([]models.SharedConversation, int64, error) {
+	var shares []models.SharedConversation
+	total, err := paginate(s.filter(s.store.SharedConversations.Query(), filter), "id ASC", page, limit, &shares)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list shared conversations: %w", err)
+	}
+	return shares, total, nil
+}
+
+// filter applies the upload and conversation filters shared by all listings
+func (s *ExportDataService) filter(query *gorm.DB, filter ExportDataFilter) *gorm.DB {
+	if filter.UploadID != 0 {
+		query = query.Where("upload_id = ?", filter.UploadID)
+	}
+	if filter.ConversationID != 0 {
+		query = query.Where("conversation_id = ?", filter.ConversationID)
+	}
+	return query
+}
+
+// paginate counts the rows matched by query and loads one page of them into dest
+func paginate(query *gorm.DB, order string, page, limit int, dest interface{}) (int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order(order).Offset(offset).Limit(limit).Find(dest).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// exportDataModels are the per-upload tables filled alongside conversations
+// and messages, cleared whenever an upload is re-parsed or purged
+var exportDataModels = []interface{}{
+	&models.AccountProfile{},
+	&models.MessageFeedback{},
+	&models.ModelComparison{},
+	&models.SharedConversation{},
+	&models.MediaAsset{},
+	&models.MessageAsset{},
+}
+
+// exportFileCounts maps export file types to the import count they report
+var exportFileCounts = map[string]string{
+	"account":              "account_profiles",
+	"feedback":             "feedback_count",
+	"comparisons":          "comparisons_count",
+	"shared_conversations": "shared_conversations_count",
+}
+
+// exportTime accepts both the Unix seconds and the ISO 8601 strings that
+// different export files use for timestamps
+type exportTime struct {
+	time.Time
+}
+
+func (t *exportTime) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		t.Time = time.Unix(int64(seconds), 0).UTC()
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil || text == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999", "2006-01-02 15:04:05.999999"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return nil
+}
+
+// exportUser is the layout of user.json
+type exportUser struct {
+	ID          string  `json:"id"`
+	Email       *string `json:"email"`
+	PhoneNumber *string `json:"phone_number"`
+	PlusUser    bool    `json:"chatgpt_plus_user"`
+	BirthYear   *int    `json:"birth_year"`
+}
+
+// exportFeedback is one entry of message_feedback.json
+type exportFeedback struct {
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversation_id"`
+	MessageID      string          `json:"message_id"`
+	Rating         string          `json:"rating"`
+	Content        json.RawMessage `json:"content"`
+	CreateTime     exportTime      `json:"create_time"`
+}
+
+// exportComparison is one entry of model_comparisons.json
+type exportComparison struct {
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversation_id"`
+	Input          json.RawMessage `json:"input"`
+	Output         json.RawMessage `json:"output"`
+	Metadata       json.RawMessage `json:"metadata"`
+	CreateTime     exportTime      `json:"create_time"`
+}
+
+// exportShare is one entry of shared_conversations.json
+type exportShare struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	Title          string `json:"title"`
+	IsAnonymous    bool   `json:"is_anonymous"`
+}
+
+// parseExportFiles imports the account, feedback, comparison and share
+// files of an upload. Failures are logged; they never fail the import.
+func (s *ParserService) parseExportFiles(upload *models.Upload, importRecord *models.Import) {
+	files, err := s.store.Extractions.ForUpload(upload.ID,
+		[]string{"account", "feedback", "comparisons", "shared_conversations"}, []string{"extracted", "parsed"})
+	if err != nil {
+		s.log.Warn("Failed to find export files", zap.Uint("upload_id", upload.ID), zap.Error(err))
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	// Export conversation UUID -> conversation row, for linking
+	var conversations []models.Conversation
+	s.store.Conversations.Query().Select("id", "export_id").Where("upload_id = ? AND export_id <> ''", upload.ID).Find(&conversations)
+	conversationIDs := make(map[string]uint, len(conversations))
+	for _, conv := range conversations {
+		conversationIDs[conv.ExportID] = conv.ID
+	}
+
+	for i := range files {
+		file := &files[i]
+		var count int
+		err := s.extraction.ReadFile(file, func(r io.Reader) error {
+			var err error
+			switch file.FileType {
+			case "account":
+				count, err = s.importAccount(upload.ID, r)
+			case "feedback":
+				count, err = s.importFeedback(upload.ID, r, conversationIDs)
+			case "comparisons":
+				count, err = s.importComparisons(upload.ID, r, conversationIDs)
+			case "shared_conversations":
+				count, err = s.importSharedConversations(upload.ID, r, conversationIDs)
+			}
+			return err
+		})
+		if err != nil {
+			s.log.Warn("Failed to parse export file",
+				zap.String("file", file.FilePath),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		file.Status = "parsed"
+		s.store.Extractions.Save(file)
+		s.progress.SetCount(importRecord, exportFileCounts[file.FileType], count)
+	}
+}
+
+// importAccount stores the account profile from user.json
+func (s *ParserService) importAccount(uploadID uint, r io.Reader) (int, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read account file: %w", err)
+	}
+	var user exportUser
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return 0, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	profile := models.AccountProfile{
+		UploadID:    uploadID,
+		AccountID:   user.ID,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		PlusUser:    user.PlusUser,
+		BirthYear:   user.BirthYear,
+		Raw:         string(raw),
+		ImportedAt:  time.Now().UTC(),
+	}
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		if _, err := tx.AccountProfiles.DeleteWhere("upload_id = ?", uploadID); err != nil {
+			return err
+		}
+		return tx.AccountProfiles.Create(&profile)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save account profile: %w", err)
+	}
+	return 1, nil
+}
+
+// importFeedback stores message ratings from message_feedback.json, linked
+// to the rated messages where they were imported
+func (s *ParserService) importFeedback(uploadID uint, r io.Reader, conversationIDs map[string]uint) (int, error) {
+	var rows []models.MessageFeedback
+	err := decodeExportArray(r, func(decoder *json.Decoder) error {
+		var entry exportFeedback
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+
+		// Older exports key feedback by the rated message's ID
+		messageID := entry.MessageID
+		if messageID == "" {
+			messageID = entry.ID
+		}
+		tags, text := parseFeedbackContent(entry.Content)
+
+		row := models.MessageFeedback{
+			UploadID:             uploadID,
+			FeedbackID:           entry.ID,
+			ExportConversationID: entry.ConversationID,
+			ExportMessageID:      messageID,
+			Rating:               normalizeRating(entry.Rating),
+			Tags:                 tags,
+			Text:                 text,
+			Content:              rawString(entry.Content),
+			CreatedAt:            entry.CreateTime.Time,
+		}
+		if id, ok := conversationIDs[entry.ConversationID]; ok {
+			row.ConversationID = &id
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Link ratings to the imported messages
+	messageIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		messageIDs = append(messageIDs, row.ExportMessageID)
+	}
+	messages := make(map[string]models.Message)
+	for start := 0; start < len(messageIDs); start += 500 {
+		end := start + 500
+		if end > len(messageIDs) {
+			end = len(messageIDs)
+		}
+		var batch []models.Message
+		if err := s.store.Messages.Query().Select("messages.id", "messages.conversation_id", "messages.message_id").
+			Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+			Where("conversations.upload_id = ? AND messages.message_id IN ?", uploadID, messageIDs[start:end]).
+			Find(&batch).Error; err != nil {
+			return 0, fmt.Errorf("failed to find rated messages: %w", err)
+		}
+		for _, message := range batch {
+			messages[*message.MessageID] = message
+		}
+	}
+	for i := range rows {
+		if message, ok := messages[rows[i].ExportMessageID]; ok {
+			rows[i].MessageID = &message.ID
+			rows[i].ConversationID = &message.ConversationID
+		}
+	}
+
+	return len(rows), replaceExportRows(s.store, uploadID, &models.MessageFeedback{}, rows)
+}
+
+// importComparisons stores side-by-side choices from model_comparisons.json
+func (s *ParserService) importComparisons(uploadID uint, r io.Reader, conversationIDs map[string]uint) (int, error) {
+	var rows []models.ModelComparison
+	err := decodeExportArray(r, func(decoder *json.Decoder) error {
+		var entry exportComparison
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+
+		row := models.ModelComparison{
+			UploadID:             uploadID,
+			ComparisonID:         entry.ID,
+			ExportConversationID: entry.ConversationID,
+			Input:                rawString(entry.Input),
+			Output:               rawString(entry.Output),
+			Metadata:             rawString(entry.Metadata),
+			CreatedAt:            entry.CreateTime.Time,
+		}
+		if id, ok := conversationIDs[entry.ConversationID]; ok {
+			row.ConversationID = &id
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), replaceExportRows(s.store, uploadID, &models.ModelComparison{}, rows)
+}
+
+// importSharedConversations stores share links from shared_conversations.json
+func (s *ParserService) importSharedConversations(uploadID uint, r io.Reader, conversationIDs map[string]uint) (int, error) {
+	var rows []models.SharedConversation
+	err := decodeExportArray(r, func(decoder *json.Decoder) error {
+		var entry exportShare
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+
+		row := models.SharedConversation{
+			UploadID:             uploadID,
+			ShareID:              entry.ID,
+			ExportConversationID: entry.ConversationID,
+			Title:                entry.Title,
+			IsAnonymous:          entry.IsAnonymous,
+		}
+		if id, ok := conversationIDs[entry.ConversationID]; ok {
+			row.ConversationID = &id
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), replaceExportRows(s.store, uploadID, &models.SharedConversation{}, rows)
+}
+
+// decodeExportArray calls fn for each element of a JSON array
+func decodeExportArray(r io.Reader, fn func(decoder *json.Decoder) error) error {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("failed to parse JSON: expected an array")
+	}
+
+	for decoder.More() {
+		if err := fn(decoder); err != nil {
+			return fmt.Errorf("failed to parse JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceExportRows swaps an upload's rows in one export data table for rows
+func replaceExportRows[T any](store *repository.Store, uploadID uint, model interface{}, rows []T) error {
+	return store.Transaction(func(tx *repository.Store) error {
+		if err := tx.DB().Where("upload_id = ?", uploadID).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to clear %T rows: %w", model, err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.DB().CreateInBatches(rows, 500).Error; err != nil {
+			return fmt.Errorf("failed to create %T rows: %w", model, err)
+		}
+		return nil
+	})
+}
+
+// parseFeedbackContent pulls the tags and free text out of a feedback
+// entry's content, which exports store as a JSON-encoded string
+func parseFeedbackContent(content json.RawMessage) (string, *string) {
+	var body struct {
+		Tags []string `json:"tags"`
+		Text string   `json:"text"`
+	}
+
+	var encoded string
+	if err := json.Unmarshal(content, &encoded); err == nil {
+		json.Unmarshal([]byte(encoded), &body)
+	} else {
+		json.Unmarshal(content, &body)
+	}
+
+	if body.Tags == nil {
+		body.Tags = []string{}
+	}
+	tags, _ := json.Marshal(body.Tags)
+	if body.Text == "" {
+		return string(tags), nil
+	}
+	return string(tags), &body.Text
+}
+
+// normalizeRating maps the export's thumbsUp/thumbsDown to snake case
+func normalizeRating(rating string) string {
+	switch strings.ToLower(rating) {
+	case "thumbsup", "thumbs_up", "up":
+		return "thumbs_up"
+	case "thumbsdown", "thumbs_down", "down":
+		return "thumbs_down"
+	}
+	return strings.ToLower(rating)
+}
+
+// rawString returns raw JSON as text, or "" for null and missing values
+func rawString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
@@ -1,161 +1,404 @@
 package services
 
 import (
-	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
-// ExtractionService handles ZIP file extraction
+// ExtractionService handles export archive extraction
 type ExtractionService struct {
-	cfg *config.Config
-	log *zap.Logger
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	progress *ImportProgressService
+
+	extractMu sync.Mutex // serialises on-demand extraction
 }
 
 // NewExtractionService creates a new extraction service
-func NewExtractionService(cfg *config.Config, log *zap.Logger) *ExtractionService {
+func NewExtractionService(cfg *config.Config, log *zap.Logger, store *repository.Store, progress *ImportProgressService) *ExtractionService {
 	return &ExtractionService{
-		cfg: cfg,
-		log: log,
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		progress: progress,
 	}
 }
 
-// ExtractUpload extracts a ZIP file for an upload
+// ExtractUpload validates and catalogues the files of an uploaded export.
+// In disk mode they are written to the extraction directory: ZIP and tar
+// archives are unpacked and bare JSON files are copied in as a single
+// conversation file. In archive mode they are read from the upload later.
 func (s *ExtractionService) ExtractUpload(uploadID uint) error {
-	var upload models.Upload
-	if err := database.DB.First(&upload, uploadID).Error; err != nil {
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil {
 		return fmt.Errorf("upload not found: %w", err)
 	}
 
 	// Update import status to extracting
-	var importRecord models.Import
-	if err := database.DB.Where("upload_id = ?", uploadID).First(&importRecord).Error; err != nil {
+	importRecord, err := s.store.Imports.ForUpload(uploadID)
+	if err != nil {
 		return fmt.Errorf("import record not found: %w", err)
 	}
 
 	importRecord.Status = "extracting"
+	importRecord.Stage = StageExtract
 	importRecord.ProgressPercent = 10
-	if err := database.DB.Save(&importRecord).Error; err != nil {
+	if err := s.progress.Save(importRecord); err != nil {
 		return fmt.Errorf("failed to update import status: %w", err)
 	}
 
-	// Open ZIP file
-	zipReader, err := zip.OpenReader(upload.StoredPath)
+	format, err := uploadFormat(upload)
 	if err != nil {
-		importRecord.Status = "failed"
-		errorMsg := fmt.Sprintf("failed to open ZIP file: %v", err)
-		importRecord.ErrorMessage = &errorMsg
-		database.DB.Save(&importRecord)
-		return fmt.Errorf("failed to open ZIP file: %w", err)
+		return s.failImport(importRecord, fmt.Errorf("failed to detect file format: %w", err))
 	}
-	defer zipReader.Close()
 
-	// Create extraction directory using upload UUID
+	// Create extraction directory using upload UUID; in archive mode it is
+	// only created once a file is extracted on demand
 	extractDir := filepath.Join(s.cfg.Directories.ExtractedDir, upload.UUID)
-	if err := os.MkdirAll(extractDir, 0755); err != nil {
-		return fmt.Errorf("failed to create extraction directory: %w", err)
+	if s.cfg.Upload.ExtractMode == "disk" {
+		if err := os.MkdirAll(extractDir, 0755); err != nil {
+			return fmt.Errorf("failed to create extraction directory: %w", err)
+		}
 	}
 
 	var totalSize int64
-	var fileCount int
+	var fileCount, rejectedCount, entryCount int
 	var extractedFiles []models.Extraction
-
-	// Extract files with path traversal protection
-	for _, file := range zipReader.File {
-		// Validate file count limit
-		if fileCount >= s.cfg.Upload.MaxExtractedFiles {
-			errorMsg := fmt.Sprintf("exceeded max extracted files: %d", s.cfg.Upload.MaxExtractedFiles)
-			importRecord.Status = "failed"
-			importRecord.ErrorMessage = &errorMsg
-			database.DB.Save(&importRecord)
-			return fmt.Errorf("exceeded max extracted files limit")
+	seen := make(map[string]bool)
+
+	// reject records an entry that was not extracted and why
+	reject := func(entry *exportEntry, status, reason string) {
+		s.log.Warn("Rejected archive entry",
+			zap.String("file", entry.name),
+			zap.String("reason", reason),
+		)
+		size := entry.size
+		if size < 0 {
+			size = 0
 		}
+		extractedFiles = append(extractedFiles, models.Extraction{
+			UploadID:     uploadID,
+			FilePath:     strings.ToValidUTF8(entry.name, "\uFFFD"),
+			FileType:     s.determineFileType(entry.name),
+			FileSize:     size,
+			ExtractedAt:  time.Now().UTC(),
+			Status:       status,
+			ErrorMessage: &reason,
+		})
+		rejectedCount++
+	}
 
-		// Sanitize file path
-		sanitizedPath, err := s.sanitizePath(file.Name, extractDir)
-		if err != nil {
-			s.log.Warn("Skipping file with invalid path",
-				zap.String("file", file.Name),
-				zap.Error(err),
-			)
-			continue
+	// Extract files with path traversal and decompression bomb protection
+	err = s.walkExport(upload, format, func(entry *exportEntry) error {
+		// Validate entry count limit; rejected entries count too
+		if entryCount >= s.cfg.Upload.MaxExtractedFiles {
+			return fmt.Errorf("exceeded max extracted files: %d", s.cfg.Upload.MaxExtractedFiles)
 		}
+		entryCount++
 
-		// Check total extraction size
-		if totalSize+file.UncompressedSize64 > uint64(s.cfg.Upload.MaxExtractionSize) {
-			errorMsg := fmt.Sprintf("exceeded max extraction size: %d", s.cfg.Upload.MaxExtractionSize)
-			importRecord.Status = "failed"
-			importRecord.ErrorMessage = &errorMsg
-			database.DB.Save(&importRecord)
-			return fmt.Errorf("exceeded max extraction size limit")
+		sanitizedPath, reason := s.checkEntry(entry, extractDir)
+		if reason == "" && entry.openErr != nil {
+			reason = fmt.Sprintf("failed to open entry: %v", entry.openErr)
 		}
-
-		// Extract file
-		if err := s.extractFile(file, sanitizedPath); err != nil {
-			s.log.Warn("Failed to extract file",
-				zap.String("file", file.Name),
-				zap.Error(err),
-			)
-			continue
+		if reason == "" && seen[sanitizedPath] {
+			reason = "duplicate entry"
 		}
+		if reason != "" {
+			reject(entry, "rejected", reason)
+			return nil
+		}
+		seen[sanitizedPath] = true
 
-		// Determine file type
-		fileType := s.determineFileType(file.Name)
+		// Check total extraction size against the declared size, then
+		// enforce the limits while copying since declared sizes can lie
+		remaining := s.cfg.Upload.MaxExtractionSize - totalSize
+		if entry.size > remaining {
+			return fmt.Errorf("exceeded max extraction size: %d", s.cfg.Upload.MaxExtractionSize)
+		}
 
-		// Create extraction record
 		extraction := models.Extraction{
 			UploadID:    uploadID,
-			FilePath:    sanitizedPath,
-			FileType:    fileType,
-			FileSize:    int64(file.UncompressedSize64),
+			FileType:    s.determineFileType(entry.name),
 			ExtractedAt: time.Now().UTC(),
 			Status:      "extracted",
 		}
+
+		if s.cfg.Upload.ExtractMode == "archive" {
+			// Only catalogue the entry; it is read from the upload when needed
+			extraction.FilePath = entry.name
+			extraction.InArchive = true
+			if entry.size > 0 {
+				extraction.FileSize = entry.size
+			}
+		} else {
+			// Extract file
+			written, guard, err := s.copyEntry(entry, sanitizedPath, remaining)
+			if err != nil {
+				switch {
+				case errors.Is(guard.err, errExtractionSizeExceeded):
+					return fmt.Errorf("exceeded max extraction size: %d", s.cfg.Upload.MaxExtractionSize)
+				case guard.err != nil:
+					reject(entry, "rejected", guard.err.Error())
+				default:
+					reject(entry, "failed", err.Error())
+				}
+				return nil
+			}
+			extraction.FilePath = sanitizedPath
+			extraction.FileSize = written
+		}
+
+		// Create extraction record
 		extractedFiles = append(extractedFiles, extraction)
 
-		totalSize += int64(file.UncompressedSize64)
+		totalSize += extraction.FileSize
 		fileCount++
 
 		// Update progress
-		progress := 10 + int(float64(fileCount)/float64(len(zipReader.File))*30) // 10-40%
-		importRecord.ProgressPercent = progress
-		database.DB.Save(&importRecord)
+		importRecord.ProgressPercent = 10 + int(entry.fraction*30) // 10-40%
+		s.progress.SetCount(importRecord, "files_extracted", fileCount)
+		s.progress.Save(importRecord)
+		return nil
+	})
+
+	// Save extraction records in batch, keeping the rejected entries of a
+	// failed extraction too
+	if err := s.store.Extractions.CreateInBatches(extractedFiles, 100); err != nil {
+		return fmt.Errorf("failed to create extraction records: %w", err)
 	}
-
-	// Save extraction records in batch
-	if len(extractedFiles) > 0 {
-		if err := database.DB.CreateInBatches(extractedFiles, 100).Error; err != nil {
-			return fmt.Errorf("failed to create extraction records: %w", err)
-		}
+	if err != nil {
+		s.progress.SetCount(importRecord, "files_rejected", rejectedCount)
+		return s.failImport(importRecord, err)
 	}
 
 	// Update import status to parsing
+	s.progress.SetCount(importRecord, "files_rejected", rejectedCount)
 	importRecord.Status = "parsing"
+	importRecord.Stage = StageParse
 	importRecord.ProgressPercent = 40
-	if err := database.DB.Save(&importRecord).Error; err != nil {
+	if err := s.progress.Save(importRecord); err != nil {
 		return fmt.Errorf("failed to update import status: %w", err)
 	}
 
 	s.log.Info("Extraction completed",
 		zap.Uint("upload_id", uploadID),
+		zap.String("format", format),
 		zap.Int("files_extracted", fileCount),
+		zap.Int("files_rejected", rejectedCount),
 		zap.Int64("total_size", totalSize),
 	)
 
 	return nil
 }
 
+// ListFiles lists the files of an upload, optionally filtered by file type
+// and status
+func (s *ExtractionService) ListFiles(uploadID uint, fileType, status string, page, limit int) ([]models.Extraction, int64, error) {
+	var files []models.Extraction
+	var total int64
+
+	query := s.store.Extractions.Query().Where("upload_id = ?", uploadID)
+	if fileType != "" {
+		query = query.Where("file_type = ?", fileType)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count files: %w", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&files).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return files, total, nil
+}
+
+// ReadFile calls fn with the content of an extracted file, reading it out of
+// the stored upload when it was only catalogued
+func (s *ExtractionService) ReadFile(extraction *models.Extraction, fn func(r io.Reader) error) error {
+	if !extraction.InArchive {
+		file, err := os.Open(extraction.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		return fn(file)
+	}
+
+	upload, err := s.store.Uploads.Get(extraction.UploadID)
+	if err != nil {
+		return fmt.Errorf("upload not found: %w", err)
+	}
+	return s.readEntry(upload, extraction.FilePath, func(entry *exportEntry) error {
+		guard := s.newGuard(entry, s.cfg.Upload.MaxExtractionSize)
+		if err := fn(guard); err != nil {
+			if guard.err != nil {
+				return guard.err
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// ReadFiles calls fn with the content of each of an upload's files, walking
+// the stored upload once for those only catalogued rather than once per file.
+// Files that cannot be opened are logged and skipped; an error from fn stops
+// the walk.
+func (s *ExtractionService) ReadFiles(upload *models.Upload, files []models.Extraction, fn func(file *models.Extraction, r io.Reader) error) error {
+	archived := make(map[string]*models.Extraction)
+	for i := range files {
+		file := &files[i]
+		if file.InArchive {
+			archived[file.FilePath] = file
+			continue
+		}
+
+		src, err := os.Open(file.FilePath)
+		if err != nil {
+			s.log.Warn("Failed to open file", zap.String("file", file.FilePath), zap.Error(err))
+			continue
+		}
+		err = fn(file, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if len(archived) == 0 {
+		return nil
+	}
+
+	format, err := uploadFormat(upload)
+	if err != nil {
+		return err
+	}
+	return s.walkExport(upload, format, func(entry *exportEntry) error {
+		file, ok := archived[entry.name]
+		if !ok || entry.src == nil {
+			return nil
+		}
+		return fn(file, s.newGuard(entry, s.cfg.Upload.MaxExtractionSize))
+	})
+}
+
+// ExtractFile writes a catalogued file of an upload out of the stored
+// archive into the extraction directory, so it can be served from disk.
+// Files already on disk are returned unchanged.
+func (s *ExtractionService) ExtractFile(uploadID, extractionID uint) (*models.Extraction, error) {
+	s.extractMu.Lock()
+	defer s.extractMu.Unlock()
+
+	extraction, err := s.store.Extractions.First("upload_id = ? AND id = ?", uploadID, extractionID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("file not found: %d", extractionID)
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	// Rejected and failed rows record the raw entry name, which may point
+	// anywhere on the host, so only files that made it through are served
+	if extraction.Status != "extracted" && extraction.Status != "parsed" {
+		return nil, fmt.Errorf("file was not extracted: %s", extraction.Status)
+	}
+
+	upload, err := s.store.Uploads.Get(extraction.UploadID)
+	if err != nil {
+		return nil, fmt.Errorf("upload not found: %w", err)
+	}
+
+	extractDir := filepath.Join(s.cfg.Directories.ExtractedDir, upload.UUID)
+	if !extraction.InArchive {
+		if !isWithin(extractDir, filepath.Clean(extraction.FilePath)) {
+			return nil, fmt.Errorf("file not found: %d is outside the extraction directory", extractionID)
+		}
+		return extraction, nil
+	}
+	destPath, err := s.sanitizePath(extraction.FilePath, extractDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var written int64
+	err = s.readEntry(upload, extraction.FilePath, func(entry *exportEntry) error {
+		var guard *entryGuard
+		var err error
+		written, guard, err = s.copyEntry(entry, destPath, s.cfg.Upload.MaxExtractionSize)
+		if guard != nil && guard.err != nil {
+			return guard.err
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract file: %w", err)
+	}
+
+	extraction.FilePath = destPath
+	extraction.FileSize = written
+	extraction.InArchive = false
+	if err := s.store.Extractions.Save(extraction); err != nil {
+		os.Remove(destPath)
+		return nil, fmt.Errorf("failed to update file record: %w", err)
+	}
+
+	s.log.Info("Extracted file on demand",
+		zap.Uint("upload_id", upload.ID),
+		zap.String("file", destPath),
+		zap.Int64("size", written),
+	)
+	return extraction, nil
+}
+
+// copyEntry extracts an entry to destPath under the entry limits, removing
+// the partial file on failure. The guard's err tells which limit was hit.
+func (s *ExtractionService) copyEntry(entry *exportEntry, destPath string, remaining int64) (int64, *entryGuard, error) {
+	guard := s.newGuard(entry, remaining)
+	written, err := s.extractFile(guard, destPath)
+	if err != nil {
+		os.Remove(destPath)
+		return written, guard, err
+	}
+	return written, guard, nil
+}
+
+// newGuard wraps an entry's content in the configured size and compression
+// ratio limits
+func (s *ExtractionService) newGuard(entry *exportEntry, remaining int64) *entryGuard {
+	return &entryGuard{
+		r:          entry.src,
+		remaining:  remaining,
+		maxSize:    s.cfg.Upload.MaxEntrySize,
+		maxRatio:   s.cfg.Upload.MaxCompressionRatio,
+		compressed: entry.compressed,
+	}
+}
+
+// failImport marks the import failed with err and returns it
+func (s *ExtractionService) failImport(importRecord *models.Import, err error) error {
+	errorMsg := err.Error()
+	importRecord.Status = "failed"
+	importRecord.ErrorMessage = &errorMsg
+	s.progress.Save(importRecord)
+	return err
+}
+
 // sanitizePath sanitizes file paths to prevent directory traversal attacks
 func (s *ExtractionService) sanitizePath(filePath, baseDir string) (string, error) {
 	// Clean the path
@@ -166,9 +409,14 @@ func (s *ExtractionService) sanitizePath(filePath, baseDir string) (string, erro
 	cleaned = strings.TrimPrefix(cleaned, "/")
 	cleaned = strings.TrimPrefix(cleaned, "\\")
 
-	// Reject paths with ..
-	if strings.Contains(cleaned, "..") {
-		return "", fmt.Errorf("path contains '..': %s", filePath)
+	// Reject paths with .. components
+	for _, part := range strings.Split(filepath.ToSlash(cleaned), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("path contains '..': %s", filePath)
+		}
+	}
+	if cleaned == "." || cleaned == "" {
+		return "", fmt.Errorf("empty path: %s", filePath)
 	}
 
 	// Reject absolute paths
@@ -190,46 +438,56 @@ func (s *ExtractionService) sanitizePath(filePath, baseDir string) (string, erro
 		return "", fmt.Errorf("failed to get absolute file path: %w", err)
 	}
 
-	if !strings.HasPrefix(fullAbs, baseAbs) {
+	if rel, err := filepath.Rel(baseAbs, fullAbs); err != nil || rel == "." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || rel == ".." {
 		return "", fmt.Errorf("path outside base directory: %s", filePath)
 	}
 
 	return fullPath, nil
 }
 
-// extractFile extracts a single file from the ZIP archive
-func (s *ExtractionService) extractFile(file *zip.File, destPath string) error {
+// extractFile writes one file from an export and returns the number of
+// bytes written
+func (s *ExtractionService) extractFile(src io.Reader, destPath string) (int64, error) {
 	// Create directory if needed
 	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return 0, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Open file from ZIP
-	srcFile, err := file.Open()
-	if err != nil {
-		return fmt.Errorf("failed to open file in ZIP: %w", err)
-	}
-	defer srcFile.Close()
-
 	// Create destination file
 	destFile, err := os.Create(destPath)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		return 0, fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer destFile.Close()
 
 	// Copy file content
-	_, err = io.Copy(destFile, srcFile)
+	written, err := io.Copy(destFile, src)
 	if err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
+		return written, fmt.Errorf("failed to copy file content: %w", err)
 	}
 
-	return nil
+	return written, nil
+}
+
+// exportFileTypes maps the non-conversation files of a ChatGPT export to
+// their file types
+var exportFileTypes = map[string]string{
+	"user.json":                 "account",
+	"message_feedback.json":     "feedback",
+	"model_comparisons.json":    "comparisons",
+	"shared_conversations.json": "shared_conversations",
+	"chat.html":                 "chat_html",
 }
 
 // determineFileType determines the type of extracted file
 func (s *ExtractionService) determineFileType(filePath string) string {
 	ext := strings.ToLower(filepath.Ext(filePath))
+
+	// Check for the other files of a ChatGPT export, some of which would
+	// otherwise match the conversation check below
+	if fileType, ok := exportFileTypes[strings.ToLower(filepath.Base(filePath))]; ok {
+		return fileType
+	}
 	
 	// Check for conversation JSON files
 	if ext == ".json" && (strings.Contains(filePath, "conversation") || 
@@ -238,8 +496,19 @@ func (s *ExtractionService) determineFileType(filePath string) string {
 		return "conversation"
 	}
 
+	// Generic JSONL exports, and Gemini activity from Google Takeout
+	if ext == ".jsonl" || ext == ".ndjson" {
+		return "conversation"
+	}
+	if strings.EqualFold(filepath.Base(filePath), "MyActivity.json") {
+		lower := strings.ToLower(filePath)
+		if strings.Contains(lower, "gemini") || strings.Contains(lower, "bard") {
+			return "conversation"
+		}
+	}
+
 	// Check for media files
-	mediaExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".mp3", ".mp4", ".wav", ".pdf"}
+	mediaExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".mp3", ".mp4", ".m4a", ".wav", ".ogg", ".webm", ".pdf"}
 	for _, mediaExt := range mediaExts {
 		if ext == mediaExt {
 			return "media"
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"chatgpt-autopsy-go/internal/models"
+)
+
+func TestExtractUploadRejectsUnsafeEntries(t *testing.T) {
+	ts := newTestServices(t)
+	bomb := bytes.Repeat([]byte{0}, 2<<20) // inflates far beyond the ratio limit
+	upload := ts.upload(t, "export.zip", zipArchive(t,
+		zipEntry{"conversations.json", []byte("[]")},
+		zipEntry{"../../etc/passwd", []byte("root:x:0:0")},
+		zipEntry{"bomb.bin", bomb},
+	))
+
+	if err := ts.extraction.ExtractUpload(upload.ID); err != nil {
+		t.Fatalf("ExtractUpload failed: %v", err)
+	}
+
+	files, err := ts.store.Extractions.Find("upload_id = ?", upload.ID)
+	if err != nil {
+		t.Fatalf("failed to list extractions: %v", err)
+	}
+	status := make(map[string]models.Extraction)
+	for _, file := range files {
+		status[file.FilePath] = file
+	}
+	if got := status["conversations.json"].Status; got != "extracted" {
+		t.Errorf("conversations.json status = %q, want extracted", got)
+	}
+	traversal, ok := status["../../etc/passwd"]
+	if !ok || traversal.Status != "rejected" {
+		t.Fatalf("path traversal entry = %+v, want rejected", traversal)
+	}
+	if got := status["bomb.bin"]; got.Status != "rejected" || got.ErrorMessage == nil || !strings.Contains(*got.ErrorMessage, "compression ratio") {
+		t.Errorf("bomb entry = %+v, want rejected for its compression ratio", got)
+	}
+
+	// Rejected rows keep the raw entry name, which must never be served
+	if _, err := ts.extraction.ExtractFile(upload.ID, traversal.ID); err == nil || !strings.Contains(err.Error(), "not extracted") {
+		t.Errorf("ExtractFile of a rejected entry error = %v, want not extracted", err)
+	}
+}
+
+func TestExtractUploadFileLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   func(ts *testServices)
+		entries []zipEntry
+		want    string
+	}{
+		{
+			name:  "too many files",
+			limit: func(ts *testServices) { ts.cfg.Upload.MaxExtractedFiles = 2 },
+			entries: []zipEntry{
+				{"conversations.json", []byte("[]")},
+				{"a.txt", []byte("a")},
+				{"b.txt", []byte("b")},
+			},
+			want: "exceeded max extracted files",
+		},
+		{
+			name:  "total size",
+			limit: func(ts *testServices) { ts.cfg.Upload.MaxExtractionSize = 16 },
+			entries: []zipEntry{
+				{"conversations.json", []byte("[]")},
+				{"notes.txt", []byte(strings.Repeat("x", 64))},
+			},
+			want: "exceeded max extraction size",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []string{"archive", "disk"} {
+			t.Run(tt.name+"/"+mode, func(t *testing.T) {
+				ts := newTestServices(t)
+				ts.cfg.Upload.ExtractMode = mode
+				tt.limit(ts)
+				upload := ts.upload(t, "export.zip", zipArchive(t, tt.entries...))
+
+				err := ts.extraction.ExtractUpload(upload.ID)
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("ExtractUpload error = %v, want %q", err, tt.want)
+				}
+				record, err := ts.store.Imports.ForUpload(upload.ID)
+				if err != nil {
+					t.Fatalf("failed to get import: %v", err)
+				}
+				if record.Status != "failed" {
+					t.Errorf("import status = %q, want failed", record.Status)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractUploadRecordsUnreadableEntries(t *testing.T) {
+	ts := newTestServices(t)
+
+	// An entry stored with a compression method the reader doesn't know
+	// cannot be opened
+	const unknownMethod = 99
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	w.RegisterCompressor(unknownMethod, func(out io.Writer) (io.WriteCloser, error) {
+		return nopWriteCloser{out}, nil
+	})
+	for _, header := range []*zip.FileHeader{
+		{Name: "conversations.json", Method: zip.Deflate},
+		{Name: "odd.bin", Method: unknownMethod},
+	} {
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", header.Name, err)
+		}
+		f.Write([]byte("[]"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	upload := ts.upload(t, "export.zip", buf.Bytes())
+
+	if err := ts.extraction.ExtractUpload(upload.ID); err != nil {
+		t.Fatalf("ExtractUpload failed: %v", err)
+	}
+	file, err := ts.store.Extractions.First("upload_id = ? AND file_path = ?", upload.ID, "odd.bin")
+	if err != nil {
+		t.Fatalf("unreadable entry was not recorded: %v", err)
+	}
+	if file.Status != "rejected" || file.ErrorMessage == nil || !strings.Contains(*file.ErrorMessage, "failed to open") {
+		t.Errorf("unreadable entry = %s, %v; want rejected with the open error", file.Status, file.ErrorMessage)
+	}
+}
+
+// nopWriteCloser stores data as is for a test compression method
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Export file formats recognised by content sniffing
+const (
+	FormatZip    = "zip"
+	FormatJSON   = "json"
+	FormatJSONGz = "json.gz"
+	FormatTar    = "tar"
+	FormatTarGz  = "tar.gz"
+)
+
+// formatMimeTypes maps formats to the MIME type stored on the upload
+var formatMimeTypes = map[string]string{
+	FormatZip:    "application/zip",
+	FormatJSON:   "application/json",
+	FormatJSONGz: "application/gzip",
+	FormatTar:    "application/x-tar",
+	FormatTarGz:  "application/x-gtar",
+}
+
+// sniffLength is how many bytes are inspected to detect a format; tar
+// headers carry their magic at offset 257
+const sniffLength = 512
+
+// DetectFormat identifies an export file from its content, ignoring the
+// file name
+func DetectFormat(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header, _ := reader.Peek(sniffLength)
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return FormatZip, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return "", fmt.Errorf("invalid file type: corrupt gzip stream: %w", err)
+		}
+		defer gz.Close()
+
+		inner := make([]byte, sniffLength)
+		n, _ := io.ReadFull(gz, inner)
+		switch {
+		case isTarHeader(inner[:n]):
+			return FormatTarGz, nil
+		case isJSON(inner[:n]):
+			return FormatJSONGz, nil
+		}
+		return "", fmt.Errorf("invalid file type: gzip file does not contain JSON or a tar archive")
+	case isTarHeader(header):
+		return FormatTar, nil
+	case isJSON(header):
+		return FormatJSON, nil
+	}
+
+	return "", fmt.Errorf("invalid file type: expected a ZIP, JSON, JSON.gz or tar.gz export")
+}
+
+// FormatForMimeType returns the format stored as a MIME type, or "" if unknown
+func FormatForMimeType(mimeType string) string {
+	for format, mime := range formatMimeTypes {
+		if mime == mimeType {
+			return format
+		}
+	}
+	return ""
+}
+
+// isTarHeader reports whether header starts with a POSIX tar header
+func isTarHeader(header []byte) bool {
+	return len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar"))
+}
+
+// isJSON reports whether header looks like the start of a JSON document
+func isJSON(header []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{')
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Inconsistency categories reported by FsckService
+const (
+	FsckMissingUploadFile     = "missing_upload_file"     // an upload's stored file is gone
+	FsckMissingExtractionFile = "missing_extraction_file" // an extracted file is gone
+	FsckConversationCount     = "conversation_message_count"
+	FsckThreadMissingMessage  = "thread_missing_message" // a thread's first or last message is gone
+	FsckThreadCount           = "thread_message_count"
+	FsckMissingAnalysisFile   = "missing_analysis_file"  // an analysis has no markdown file
+	FsckOrphanedAnalysisFile  = "orphaned_analysis_file" // a markdown file has no analysis
+	FsckMissingMessageFile    = "missing_message_file"   // a date with user messages has no message file
+	FsckStaleMessageFile      = "stale_message_file"     // a message file's date has no user messages
+)
+
+// fsckBatchSize is how many messages are loaded at a time
+const fsckBatchSize = 5000
+
+// FsckService finds rows and files that disagree with each other and, in
+// repair mode, fixes what can be rebuilt from the other side
+type FsckService struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	parser   *ParserService
+	pipeline *PipelineService
+
+	mu      sync.Mutex // serialises runs
+	lastMu  sync.RWMutex
+	lastRun *FsckReport
+}
+
+// NewFsckService creates a new consistency checker. pipeline may be nil when
+// no uploads can be processed meanwhile, as in cmd/fsck.
+func NewFsckService(cfg *config.Config, log *zap.Logger, store *repository.Store, parser *ParserService, pipeline *PipelineService) *FsckService {
+	return &FsckService{
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		parser:   parser,
+		pipeline: pipeline,
+	}
+}
+
+// FsckIssue is one inconsistency
+type FsckIssue struct {
+	ID          uint   `json:"id,omitempty"` // row the issue was found on
+	Path        string `json:"path,omitempty"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// FsckReport lists the inconsistencies found by a run, by category
+type FsckReport struct {
+	StartedAt time.Time              `json:"started_at"`
+	Duration  string                 `json:"duration"`
+	Repair    bool                   `json:"repair"`
+	Issues    map[string][]FsckIssue `json:"issues"`
+	Found     int                    `json:"found"`
+	Repaired  int                    `json:"repaired"`
+	Errors    []string               `json:"errors,omitempty"`
+}
+
+// add records an issue, repairing it first in repair mode. repair may be
+// nil for issues that cannot be fixed from the other side.
+func (r *FsckReport) add(category string, issue FsckIssue, repair func() error) {
+	if r.Repair && repair != nil {
+		if err := repair(); err != nil {
+			issue.RepairError = err.Error()
+		} else {
+			issue.Repaired = true
+			r.Repaired++
+		}
+	}
+	r.Issues[category] = append(r.Issues[category], issue)
+	r.Found++
+}
+
+// threadSpan summarises a conversation's messages on one date
+type threadSpan struct {
+	count      int
+	start, end models.Message
+}
+
+// Run checks every upload, extraction, conversation, thread, analysis and
+// message file. With repair, the pipeline is paused while it runs and each
+// issue is fixed where possible: counts are recomputed, threads are rebuilt
+// from their messages, and files are rewritten from the database or removed.
+func (s *FsckService) Run(repair bool) (*FsckReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if repair && s.pipeline != nil {
+		resume, err := s.pipeline.Pause("the consistency checker is repairing")
+		if err != nil {
+			return nil, fmt.Errorf("cannot repair while processing uploads: %w", err)
+		}
+		defer resume()
+	}
+
+	report := &FsckReport{
+		StartedAt: time.Now().UTC(),
+		Repair:    repair,
+		Issues:    make(map[string][]FsckIssue),
+	}
+
+	s.checkUploads(report)
+	s.checkExtractions(report)
+
+	// One pass over the messages serves the conversation, thread and
+	// message file checks
+	counts := make(map[uint]int)
+	spans := make(map[uint]map[string]*threadSpan)
+	userDates := make(map[string]bool)
+	var batch []models.Message
+	err := s.store.Messages.Query().Select("id", "conversation_id", "role", "timestamp").Order("id ASC").
+		FindInBatches(&batch, fsckBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, msg := range batch {
+				date := msg.Timestamp.UTC().Format("2006-01-02")
+				counts[msg.ConversationID]++
+				if msg.Role == "user" {
+					userDates[date] = true
+				}
+				if spans[msg.ConversationID] == nil {
+					spans[msg.ConversationID] = make(map[string]*threadSpan)
+				}
+				span := spans[msg.ConversationID][date]
+				if span == nil {
+					spans[msg.ConversationID][date] = &threadSpan{count: 1, start: msg, end: msg}
+					continue
+				}
+				span.count++
+				if msg.Timestamp.Before(span.start.Timestamp) {
+					span.start = msg
+				}
+				if !msg.Timestamp.Before(span.end.Timestamp) {
+					span.end = msg
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+
+	s.checkConversations(report, counts)
+	s.checkThreads(report, spans)
+	s.checkAnalyses(report)
+	s.checkMessageFiles(report, userDates)
+
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+
+	s.lastMu.Lock()
+	s.lastRun = report
+	s.lastMu.Unlock()
+
+	s.log.Info("Consistency check completed",
+		zap.Bool("repair", repair),
+		zap.Int("found", report.Found),
+		zap.Int("repaired", report.Repaired),
+		zap.Int("errors", len(report.Errors)),
+	)
+	return report, nil
+}
+
+// LastRun returns the report of the most recent run, or nil
+func (s *FsckService) LastRun() *FsckReport {
+	s.lastMu.RLock()
+	defer s.lastMu.RUnlock()
+	return s.lastRun
+}
+
+// checkUploads reports uploads whose stored file is gone. The file is the
+// only copy of the export, so there is nothing to repair from.
+func (s *FsckService) checkUploads(report *FsckReport) {
+	var uploads []models.Upload
+	if err := s.store.Uploads.Query().Select("id", "stored_path").Find(&uploads).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list uploads: "+err.Error())
+		return
+	}
+	for _, upload := range uploads {
+		if fileExists(upload.StoredPath) {
+			continue
+		}
+		report.add(FsckMissingUploadFile, FsckIssue{
+			ID:     upload.ID,
+			Path:   upload.StoredPath,
+			Detail: "stored file is missing; delete the upload and import the export again",
+		}, nil)
+	}
+}
+
+// checkExtractions reports extracted files that are gone. Repair reads them
+// from the stored upload again, or marks them failed if that is gone too.
+func (s *FsckService) checkExtractions(report *FsckReport) {
+	var extractions []models.Extraction
+	if err := s.store.Extractions.Query().
+		Where("in_archive = ? AND status IN ?", false, []string{"extracted", "parsed"}).
+		Where("upload_id IN (?)", s.store.Uploads.Query().Select("id")).
+		Find(&extractions).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list extractions: "+err.Error())
+		return
+	}
+
+	uploads := make(map[uint]*models.Upload)
+	for i := range extractions {
+		extraction := &extractions[i]
+		if fileExists(extraction.FilePath) {
+			continue
+		}
+		if _, ok := uploads[extraction.UploadID]; !ok {
+			upload, err := s.store.Uploads.Get(extraction.UploadID)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to get upload %d: %s", extraction.UploadID, err))
+				continue
+			}
+			uploads[extraction.UploadID] = upload
+		}
+		upload := uploads[extraction.UploadID]
+
+		report.add(FsckMissingExtractionFile, FsckIssue{
+			ID:     extraction.ID,
+			Path:   extraction.FilePath,
+			Detail: fmt.Sprintf("file extracted from upload %d is missing", extraction.UploadID),
+		}, func() error {
+			return s.recatalogue(extraction, upload)
+		})
+	}
+}
+
+// recatalogue points an extraction whose file is gone back at its entry in
+// the stored upload
+func (s *FsckService) recatalogue(extraction *models.Extraction, upload *models.Upload) error {
+	entry, err := filepath.Rel(filepath.Join(s.cfg.Directories.ExtractedDir, upload.UUID), extraction.FilePath)
+	if err != nil || strings.HasPrefix(entry, "..") || !fileExists(upload.StoredPath) {
+		message := "file missing and the stored upload cannot provide it"
+		return s.store.Extractions.Query().Where("id = ?", extraction.ID).
+			Updates(map[string]interface{}{"status": "failed", "error_message": message}).Error
+	}
+	return s.store.Extractions.Query().Where("id = ?", extraction.ID).
+		Updates(map[string]interface{}{"file_path": filepath.ToSlash(entry), "in_archive": true}).Error
+}
+
+// checkConversations compares each conversation's message count with its
+// messages
+func (s *FsckService) checkConversations(report *FsckReport, counts map[uint]int) {
+	var conversations []models.Conversation
+	if err := s.store.Conversations.Query().Select("id", "message_count").Find(&conversations).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list conversations: "+err.Error())
+		return
+	}
+	for _, conversation := range conversations {
+		actual := counts[conversation.ID]
+		if conversation.MessageCount == actual {
+			continue
+		}
+		id := conversation.ID
+		report.add(FsckConversationCount, FsckIssue{
+			ID:     id,
+			Detail: fmt.Sprintf("message_count is %d but the conversation has %d messages", conversation.MessageCount, actual),
+		}, func() error {
+			return s.store.Conversations.Query().Where("id = ?", id).Update("message_count", actual).Error
+		})
+	}
+}
+
+// checkThreads reports threads whose first or last message is gone or
+// belongs to another conversation, and threads whose count disagrees with
+// the conversation's messages on that date. Repair rebuilds the thread from
+// those messages, or deletes it if there are none.
+func (s *FsckService) checkThreads(report *FsckReport, spans map[uint]map[string]*threadSpan) {
+	var threads []models.Thread
+	if err := s.store.Threads.Query().Find(&threads).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list threads: "+err.Error())
+		return
+	}
+
+	// Collect the referenced messages that still exist
+	var ids []uint
+	for _, thread := range threads {
+		if thread.StartMessageID != nil {
+			ids = append(ids, *thread.StartMessageID)
+		}
+		if thread.EndMessageID != nil {
+			ids = append(ids, *thread.EndMessageID)
+		}
+	}
+	owners := make(map[uint]uint, len(ids))
+	for start := 0; start < len(ids); start += fsckBatchSize {
+		end := start + fsckBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var messages []models.Message
+		if err := s.store.Messages.Query().Select("id", "conversation_id").Where("id IN ?", ids[start:end]).Find(&messages).Error; err != nil {
+			report.Errors = append(report.Errors, "failed to load thread messages: "+err.Error())
+			return
+		}
+		for _, msg := range messages {
+			owners[msg.ID] = msg.ConversationID
+		}
+	}
+	references := func(id *uint, conversationID uint) bool {
+		return id != nil && owners[*id] == conversationID
+	}
+
+	for i := range threads {
+		thread := &threads[i]
+		date := thread.Date
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		span := spans[thread.ConversationID][date]
+		actual := 0
+		if span != nil {
+			actual = span.count
+		}
+
+		category, detail := "", ""
+		switch {
+		case !references(thread.StartMessageID, thread.ConversationID) || !references(thread.EndMessageID, thread.ConversationID):
+			category = FsckThreadMissingMessage
+			detail = fmt.Sprintf("thread for %s references messages missing from conversation %d", date, thread.ConversationID)
+		case thread.MessageCount != actual:
+			category = FsckThreadCount
+			detail = fmt.Sprintf("message_count is %d but conversation %d has %d messages on %s", thread.MessageCount, thread.ConversationID, actual, date)
+		default:
+			continue
+		}
+
+		report.add(category, FsckIssue{ID: thread.ID, Detail: detail}, func() error {
+			if span == nil {
+				// Analyses are per date, so they outlive the thread
+				return s.store.Transaction(func(tx *repository.Store) error {
+					if err := tx.Analyses.Query().Where("thread_id = ?", thread.ID).Update("thread_id", nil).Error; err != nil {
+						return err
+					}
+					_, err := tx.Threads.DeleteWhere("id = ?", thread.ID)
+					return err
+				})
+			}
+			return s.store.Threads.Query().Where("id = ?", thread.ID).Updates(map[string]interface{}{
+				"message_count":    span.count,
+				"start_message_id": span.start.ID,
+				"end_message_id":   span.end.ID,
+				"start_timestamp":  span.start.Timestamp.UTC(),
+				"end_timestamp":    span.end.Timestamp.UTC(),
+			}).Error
+		})
+	}
+}
+
+// checkAnalyses reports dated analyses without a markdown file, rewritten
+// from the stored content on repair, and markdown files without an
+// analysis, removed on repair
+func (s *FsckService) checkAnalyses(report *FsckReport) {
+	var analyses []models.Analysis
+	if err := s.store.Analyses.Query().Select("id", "date", "analysis_type", "markdown_content").
+		Where("date IS NOT NULL").Find(&analyses).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list analyses: "+err.Error())
+		return
+	}
+
+	expected := make(map[string]bool, len(analyses))
+	for i := range analyses {
+		analysis := &analyses[i]
+		date := *analysis.Date
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		filePath := filepath.Join(s.cfg.Directories.AnalysisDir, date, analysis.AnalysisType+".md")
+		expected[filePath] = true
+		if fileExists(filePath) {
+			continue
+		}
+		report.add(FsckMissingAnalysisFile, FsckIssue{
+			ID:     analysis.ID,
+			Path:   filePath,
+			Detail: fmt.Sprintf("%s analysis for %s has no markdown file", analysis.AnalysisType, date),
+		}, func() error {
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				return err
+			}
+			return writeFileAtomic(filePath, []byte(analysis.MarkdownContent))
+		})
+	}
+
+	dates, err := os.ReadDir(s.cfg.Directories.AnalysisDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, "failed to read analysis directory: "+err.Error())
+		}
+		return
+	}
+	for _, dateEntry := range dates {
+		if !dateEntry.IsDir() || !messageFilePattern.MatchString(dateEntry.Name()+".md") {
+			continue
+		}
+		dir := filepath.Join(s.cfg.Directories.AnalysisDir, dateEntry.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			report.Errors = append(report.Errors, "failed to read "+dir+": "+err.Error())
+			continue
+		}
+		for _, file := range files {
+			filePath := filepath.Join(dir, file.Name())
+			if file.IsDir() || filepath.Ext(file.Name()) != ".md" || expected[filePath] {
+				continue
+			}
+			report.add(FsckOrphanedAnalysisFile, FsckIssue{
+				Path:   filePath,
+				Detail: fmt.Sprintf("no %s analysis exists for %s", strings.TrimSuffix(file.Name(), ".md"), dateEntry.Name()),
+			}, func() error {
+				if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				// Leave no empty date directory behind
+				os.Remove(dir)
+				return nil
+			})
+		}
+	}
+}
+
+// checkMessageFiles reports dates with user messages but no message file,
+// and message files for dates without user messages. Repair regenerates or
+// removes them from the database.
+func (s *FsckService) checkMessageFiles(report *FsckReport, userDates map[string]bool) {
+	existing := make(map[string]bool)
+	entries, err := os.ReadDir(s.cfg.Directories.MessagesDir)
+	if err != nil && !os.IsNotExist(err) {
+		report.Errors = append(report.Errors, "failed to read messages directory: "+err.Error())
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && messageFilePattern.MatchString(entry.Name()) {
+			existing[strings.TrimSuffix(entry.Name(), ".md")] = true
+		}
+	}
+
+	dates := make([]string, 0, len(userDates))
+	for date := range userDates {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		if existing[date] {
+			continue
+		}
+		date := date
+		report.add(FsckMissingMessageFile, FsckIssue{
+			Path:   filepath.Join(s.cfg.Directories.MessagesDir, date+".md"),
+			Detail: fmt.Sprintf("%s has user messages but no message file", date),
+		}, func() error {
+			return s.parser.RewriteMessageFiles([]string{date})
+		})
+	}
+
+	stale := make([]string, 0)
+	for date := range existing {
+		if !userDates[date] {
+			stale = append(stale, date)
+		}
+	}
+	sort.Strings(stale)
+	for _, date := range stale {
+		date := date
+		report.add(FsckStaleMessageFile, FsckIssue{
+			Path:   filepath.Join(s.cfg.Directories.MessagesDir, date+".md"),
+			Detail: fmt.Sprintf("%s has no user messages", date),
+		}, func() error {
+			return s.parser.RewriteMessageFiles([]string{date})
+		})
+	}
+}
+
+// fileExists reports whether path names an existing file
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// GCService reconciles soft-deleted uploads and files on disk that no
+// upload references
+type GCService struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	pipeline *PipelineService
+	sessions *UploadSessionService
+
+	mu      sync.Mutex // serialises runs
+	lastMu  sync.RWMutex
+	lastRun *GCReport
+}
+
+// NewGCService creates a new garbage collector
+func NewGCService(cfg *config.Config, log *zap.Logger, store *repository.Store, pipeline *PipelineService, sessions *UploadSessionService) *GCService {
+	return &GCService{
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		pipeline: pipeline,
+		sessions: sessions,
+	}
+}
+
+// GCReport describes what a garbage collection run reclaimed
+type GCReport struct {
+	StartedAt       time.Time `json:"started_at"`
+	Duration        string    `json:"duration"`
+	UploadsPurged   []uint    `json:"uploads_purged"`
+	SessionsExpired int       `json:"sessions_expired"`
+	OrphanedFiles   []string  `json:"orphaned_files"`
+	FilesRemoved    int       `json:"files_removed"`
+	BytesReclaimed  int64     `json:"bytes_reclaimed"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+// Start runs the collector on the configured interval until ctx is done
+func (s *GCService) Start(ctx context.Context) {
+	if s.cfg.GC.Interval <= 0 {
+		s.log.Info("Scheduled garbage collection disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.GC.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Run()
+			}
+		}
+	}()
+}
+
+// Run purges soft-deleted uploads and removes orphaned files
+func (s *GCService) Run() *GCReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &GCReport{
+		StartedAt:     time.Now().UTC(),
+		UploadsPurged: []uint{},
+		OrphanedFiles: []string{},
+	}
+
+	// A backup or restore is reading or replacing the data directories
+	if reason := s.pipeline.Paused(); reason != "" {
+		report.Errors = append(report.Errors, "skipped while "+reason)
+		return s.finish(report)
+	}
+
+	// Purge uploads that were soft-deleted
+	var deleted []models.Upload
+	if err := s.store.Uploads.Unscoped().Query().Where("deleted_at IS NOT NULL").Find(&deleted).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list deleted uploads: "+err.Error())
+	}
+	for _, upload := range deleted {
+		purged, err := s.pipeline.Purge(upload.ID)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		report.UploadsPurged = append(report.UploadsPurged, upload.ID)
+		report.FilesRemoved += purged.FilesRemoved
+		report.BytesReclaimed += purged.BytesReclaimed
+	}
+
+	// Expire idle resumable upload sessions
+	expired, reclaimed, err := s.sessions.ExpireSessions()
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.SessionsExpired = expired
+	report.FilesRemoved += expired
+	report.BytesReclaimed += reclaimed
+
+	// Collect the files still referenced by uploads
+	var uploads []models.Upload
+	if err := s.store.Uploads.Unscoped().Query().Select("uuid", "stored_path").Find(&uploads).Error; err != nil {
+		report.Errors = append(report.Errors, "failed to list uploads: "+err.Error())
+		return s.finish(report)
+	}
+	storedPaths := make(map[string]bool, len(uploads))
+	uuids := make(map[string]bool, len(uploads))
+	for _, upload := range uploads {
+		storedPaths[filepath.Clean(upload.StoredPath)] = true
+		uuids[upload.UUID] = true
+	}
+
+	s.removeOrphans(report, s.cfg.Directories.UploadsDir, func(path string) bool {
+		return storedPaths[filepath.Clean(path)]
+	})
+	s.removeOrphans(report, s.cfg.Directories.ExtractedDir, func(path string) bool {
+		return uuids[filepath.Base(path)]
+	})
+
+	return s.finish(report)
+}
+
+// LastRun returns the report of the most recent run, or nil
+func (s *GCService) LastRun() *GCReport {
+	s.lastMu.RLock()
+	defer s.lastMu.RUnlock()
+	return s.lastRun
+}
+
+// removeOrphans removes the entries of dir that are not referenced and are
+// older than the grace period, so in-flight uploads are left alone
+func (s *GCService) removeOrphans(report *GCReport, dir string, referenced func(string) bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, "failed to read "+dir+": "+err.Error())
+		}
+		return
+	}
+
+	cutoff := time.Now().Add(-s.cfg.GC.GracePeriod)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || referenced(path) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		files, bytes, err := removePath(path)
+		if err != nil {
+			report.Errors = append(report.Errors, "failed to remove "+path+": "+err.Error())
+			continue
+		}
+		report.OrphanedFiles = append(report.OrphanedFiles, path)
+		report.FilesRemoved += files
+		report.BytesReclaimed += bytes
+	}
+}
+
+// finish records and logs a completed run
+func (s *GCService) finish(report *GCReport) *GCReport {
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+
+	s.lastMu.Lock()
+	s.lastRun = report
+	s.lastMu.Unlock()
+
+	s.log.Info("Garbage collection completed",
+		zap.Int("uploads_purged", len(report.UploadsPurged)),
+		zap.Int("sessions_expired", report.SessionsExpired),
+		zap.Int("orphaned_files", len(report.OrphanedFiles)),
+		zap.Int("files_removed", report.FilesRemoved),
+		zap.Int64("bytes_reclaimed", report.BytesReclaimed),
+		zap.Int("errors", len(report.Errors)),
+	)
+	return report
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// Inbox subdirectories that processed files are moved into
+const (
+	inboxProcessedDir = "processed"
+	inboxFailedDir    = "failed"
+)
+
+// InboxService watches the inbox directory and imports export files dropped
+// into it through the normal upload path and pipeline
+type InboxService struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	uploads  *UploadService
+	pipeline *PipelineService
+
+	pending map[string]inboxFile // files seen on the last poll
+}
+
+// inboxFile is the size and modification time of a file when it was seen
+type inboxFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewInboxService creates a new inbox watcher
+func NewInboxService(cfg *config.Config, log *zap.Logger, uploads *UploadService, pipeline *PipelineService) *InboxService {
+	return &InboxService{
+		cfg:      cfg,
+		log:      log,
+		uploads:  uploads,
+		pipeline: pipeline,
+		pending:  make(map[string]inboxFile),
+	}
+}
+
+// Start polls the inbox until ctx is done. It does nothing when no inbox
+// directory is configured.
+func (s *InboxService) Start(ctx context.Context) error {
+	if s.cfg.Upload.InboxDir == "" {
+		return nil
+	}
+
+	for _, dir := range []string{s.cfg.Upload.InboxDir, s.processedDir(), s.failedDir()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create inbox directory: %w", err)
+		}
+	}
+
+	s.log.Info("Watching inbox for exports",
+		zap.String("dir", s.cfg.Upload.InboxDir),
+		zap.Duration("interval", s.cfg.Upload.InboxPollInterval),
+	)
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.Upload.InboxPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.poll()
+			}
+		}
+	}()
+	return nil
+}
+
+// poll imports every file whose size and modification time have not changed
+// since the previous poll, so files still being copied in are left alone.
+// Nothing is imported while a backup or restore pauses the pipeline.
+func (s *InboxService) poll() {
+	if reason := s.pipeline.Paused(); reason != "" {
+		s.log.Debug("Inbox paused", zap.String("reason", reason))
+		return
+	}
+
+	entries, err := os.ReadDir(s.cfg.Upload.InboxDir)
+	if err != nil {
+		s.log.Warn("Failed to read inbox", zap.Error(err))
+		return
+	}
+
+	seen := make(map[string]inboxFile)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(s.cfg.Upload.InboxDir, name)
+		current := inboxFile{size: info.Size(), modTime: info.ModTime()}
+
+		if previous, ok := s.pending[path]; !ok || previous != current {
+			seen[path] = current
+			continue
+		}
+		s.importFile(path)
+	}
+	s.pending = seen
+}
+
+// importFile uploads one inbox file and moves it out of the inbox
+func (s *InboxService) importFile(path string) {
+	upload, err := s.uploads.UploadLocalFile(path)
+	if err != nil {
+		if upload != nil && strings.Contains(err.Error(), "already uploaded") {
+			// Already imported; nothing more to do with this copy
+			s.log.Info("Inbox file already imported", zap.String("file", path), zap.Uint("upload_id", upload.ID))
+			s.move(path, s.processedDir())
+			return
+		}
+
+		s.log.Error("Failed to import inbox file", zap.String("file", path), zap.Error(err))
+		if target := s.move(path, s.failedDir()); target != "" {
+			os.WriteFile(target+".error.txt", []byte(err.Error()+"\n"), 0644)
+		}
+		return
+	}
+
+	if err := s.pipeline.Start(upload.ID); err != nil {
+		s.log.Error("Failed to start import pipeline", zap.Uint("upload_id", upload.ID), zap.Error(err))
+	}
+	s.log.Info("Imported inbox file", zap.String("file", path), zap.Uint("upload_id", upload.ID))
+	s.move(path, s.processedDir())
+}
+
+// move moves a file into dir, adding a timestamp to avoid overwriting an
+// earlier file with the same name, and returns the new path
+func (s *InboxService) move(path, dir string) string {
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(target)
+		target = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(target, ext), time.Now().UTC().Format("20060102T150405"), ext)
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		s.log.Error("Failed to move inbox file", zap.String("file", path), zap.Error(err))
+		return ""
+	}
+	return target
+}
+
+// processedDir holds inbox files that were imported or already known
+func (s *InboxService) processedDir() string {
+	return filepath.Join(s.cfg.Upload.InboxDir, inboxProcessedDir)
+}
+
+// failedDir holds inbox files that could not be imported
+func (s *InboxService) failedDir() string {
+	return filepath.Join(s.cfg.Upload.InboxDir, inboxFailedDir)
+}
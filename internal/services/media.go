@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// MediaService catalogues the images and audio in uploads and serves them
+type MediaService struct {
+	cfg        *config.Config
+	log        *zap.Logger
+	store      *repository.Store
+	extraction *ExtractionService
+}
+
+// NewMediaService creates a new media service
+func NewMediaService(cfg *config.Config, log *zap.Logger, store *repository.Store, extraction *ExtractionService) *MediaService {
+	return &MediaService{
+		cfg:        cfg,
+		log:        log,
+		store:      store,
+		extraction: extraction,
+	}
+}
+
+// MediaFilter narrows media listings
+type MediaFilter struct {
+	UploadID       uint
+	ConversationID uint
+	Date           string // YYYY-MM-DD of a message referencing the file
+	Kind           string // MIME type prefix: image, audio, video, ...
+}
+
+// mediaAssetIDPattern matches the file ID that export file names start
+// with, e.g. file-abc123-photo.png or file_00000000abc-<uuid>.wav
+var mediaAssetIDPattern = regexp.MustCompile(`^file[-_][A-Za-z0-9]+`)
+
+// CatalogueUpload records the content hash, MIME type, dimensions and
+// duration of every media file of an upload, replacing any earlier
+// catalogue. Files that cannot be read are logged and skipped.
+func (s *MediaService) CatalogueUpload(uploadID uint) (int, error) {
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil {
+		return 0, fmt.Errorf("upload not found: %w", err)
+	}
+
+	files, err := s.store.Extractions.ForUpload(uploadID, []string{"media"}, []string{"extracted", "parsed"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find media files: %w", err)
+	}
+
+	var assets []models.MediaAsset
+	err = s.extraction.ReadFiles(upload, files, func(file *models.Extraction, r io.Reader) error {
+		// File paths inside uploads always use forward slashes
+		name := path.Base(strings.ReplaceAll(file.FilePath, "\\", "/"))
+		info, err := probeMedia(r, name)
+		if err != nil {
+			s.log.Warn("Failed to read media file", zap.String("file", file.FilePath), zap.Error(err))
+			return nil
+		}
+
+		assets = append(assets, models.MediaAsset{
+			UploadID:     uploadID,
+			ExtractionID: file.ID,
+			AssetID:      mediaAssetIDPattern.FindString(name),
+			FileName:     name,
+			ContentHash:  info.hash,
+			MimeType:     info.mimeType,
+			FileSize:     info.size,
+			Width:        info.width,
+			Height:       info.height,
+			Duration:     info.duration,
+			CataloguedAt: time.Now().UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read media files: %w", err)
+	}
+
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		if _, err := tx.MediaAssets.DeleteWhere("upload_id = ?", uploadID); err != nil {
+			return err
+		}
+		return tx.MediaAssets.CreateInBatches(assets, 100)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save media catalogue: %w", err)
+	}
+
+	s.log.Info("Media catalogued",
+		zap.Uint("upload_id", uploadID),
+		zap.Int("files", len(assets)),
+	)
+	return len(assets), nil
+}
+
+// ListMedia lists catalogued media. Conversation and date filters match
+// files referenced by messages in that conversation or on that date.
+func (s *MediaService) ListMedia(filter MediaFilter, page, limit int) ([]models.MediaAsset, int64, error) {
+	var assets []models.MediaAsset
+	var total int64
+
+	query := s.store.MediaAssets.Query()
+	if filter.UploadID != 0 {
+		query = query.Where("upload_id = ?", filter.UploadID)
+	}
+	if filter.Kind != "" {
+		query = query.Where("mime_type LIKE ?", strings.TrimSuffix(filter.Kind, "/")+"/%")
+	}
+
+	if filter.ConversationID != 0 || filter.Date != "" {
+		links := s.store.MessageAssets.Query().
+			Select("message_assets.upload_id, message_assets.asset_id").
+			Joins("JOIN messages ON messages.id = message_assets.message_id")
+		if filter.ConversationID != 0 {
+			links = links.Where("message_assets.conversation_id = ?", filter.ConversationID)
+		}
+		if filter.Date != "" {
+			day, err := time.Parse("2006-01-02", filter.Date)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid date: %w", err)
+			}
+			links = links.Where("messages.timestamp >= ? AND messages.timestamp < ?", day, day.AddDate(0, 0, 1))
+		}
+		query = query.Where("asset_id <> '' AND (upload_id, asset_id) IN (?)", links)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count media: %w", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&assets).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list media: %w", err)
+	}
+
+	return assets, total, nil
+}
+
+// GetMedia returns a catalogued media file with the messages that reference it
+func (s *MediaService) GetMedia(id uint) (*models.MediaAsset, []models.MessageAsset, error) {
+	asset, err := s.store.MediaAssets.Get(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, fmt.Errorf("media not found: %d", id)
+		}
+		return nil, nil, fmt.Errorf("failed to get media: %w", err)
+	}
+
+	links := []models.MessageAsset{}
+	if asset.AssetID != "" {
+		if err := s.store.MessageAssets.Query().Where("upload_id = ? AND asset_id = ?", asset.UploadID, asset.AssetID).
+			Order("message_id ASC").Find(&links).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to get media messages: %w", err)
+		}
+	}
+	return asset, links, nil
+}
+
+// OpenMedia opens a media file for streaming, extracting it from the stored
+// upload first if it was only catalogued
+func (s *MediaService) OpenMedia(id uint) (*models.MediaAsset, *os.File, error) {
+	asset, _, err := s.GetMedia(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extraction, err := s.extraction.ExtractFile(asset.UploadID, asset.ExtractionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(extraction.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open media file: %w", err)
+	}
+	return asset, file, nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// mediaInfo is what probing a media file found out about it
+type mediaInfo struct {
+	hash     string
+	mimeType string
+	size     int64
+	width    *int
+	height   *int
+	duration *float64 // seconds
+}
+
+// probeMedia reads a media file to the end, hashing it, sniffing its MIME
+// type and reading image dimensions or audio duration where the format allows
+func probeMedia(r io.Reader, name string) (*mediaInfo, error) {
+	counter := &countingReader{r: r}
+	hash := sha256.New()
+	tee := io.TeeReader(counter, hash)
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(tee, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	info := &mediaInfo{mimeType: sniffMediaType(head, name)}
+	body := io.MultiReader(bytes.NewReader(head), tee)
+
+	var mp3 *mp3Header
+	switch info.mimeType {
+	case "image/png", "image/jpeg", "image/gif":
+		decode := map[string]func(io.Reader) (image.Config, error){
+			"image/png":  png.DecodeConfig,
+			"image/jpeg": jpeg.DecodeConfig,
+			"image/gif":  gif.DecodeConfig,
+		}[info.mimeType]
+		if config, err := decode(body); err == nil {
+			info.width, info.height = &config.Width, &config.Height
+		}
+	case "image/webp":
+		if width, height, ok := webpSize(head); ok {
+			info.width, info.height = &width, &height
+		}
+	case "audio/mpeg":
+		mp3 = readMP3Header(body)
+	}
+
+	// Read the rest for the hash and size
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, err
+	}
+	info.hash = hex.EncodeToString(hash.Sum(nil))
+	info.size = counter.n
+
+	switch info.mimeType {
+	case "audio/wav":
+		if seconds, ok := wavDuration(head, info.size); ok {
+			info.duration = &seconds
+		}
+	case "audio/mpeg":
+		if seconds, ok := mp3.duration(info.size); ok {
+			info.duration = &seconds
+		}
+	}
+	return info, nil
+}
+
+// sniffMediaType detects a file's MIME type from its first bytes, falling
+// back to the extension when the content is not recognised
+func sniffMediaType(head []byte, name string) string {
+	mimeType := http.DetectContentType(head)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+
+	switch {
+	case mimeType == "audio/wave":
+		return "audio/wav"
+	case mimeType == "video/mp4" && len(head) >= 12 && string(head[8:11]) == "M4A":
+		return "audio/mp4"
+	case mimeType == "application/octet-stream":
+		if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); byExt != "" {
+			if i := strings.IndexByte(byExt, ';'); i >= 0 {
+				byExt = byExt[:i]
+			}
+			return byExt
+		}
+	}
+	return mimeType
+}
+
+// webpSize reads the canvas size from a WebP header
+func webpSize(head []byte) (int, int, bool) {
+	if len(head) < 30 || string(head[0:4]) != "RIFF" || string(head[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	data := head[20:]
+	switch string(head[12:16]) {
+	case "VP8X":
+		width := int(data[4]) | int(data[5])<<8 | int(data[6])<<16
+		height := int(data[7]) | int(data[8])<<8 | int(data[9])<<16
+		return width + 1, height + 1, true
+	case "VP8 ":
+		if data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return 0, 0, false
+		}
+		width := int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+		return width, height, true
+	case "VP8L":
+		if data[0] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	}
+	return 0, 0, false
+}
+
+// wavDuration computes a WAV file's duration from its fmt and data chunks
+func wavDuration(head []byte, size int64) (float64, bool) {
+	if len(head) < 12 || string(head[0:4]) != "RIFF" || string(head[8:12]) != "WAVE" {
+		return 0, false
+	}
+
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(head); {
+		chunkSize := binary.LittleEndian.Uint32(head[offset+4 : offset+8])
+		switch string(head[offset : offset+4]) {
+		case "fmt ":
+			if offset+20 > len(head) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(head[offset+16 : offset+20])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			// Streamed recordings leave the data size unset
+			dataSize := int64(chunkSize)
+			if remaining := size - int64(offset+8); dataSize == 0 || dataSize > remaining {
+				dataSize = remaining
+			}
+			return float64(dataSize) / float64(byteRate), true
+		}
+		offset += 8 + int(chunkSize) + int(chunkSize&1)
+	}
+	return 0, false
+}
+
+// mp3Header holds what is needed from an MP3's first frame to estimate its
+// duration
+type mp3Header struct {
+	start      int64 // offset of the first frame, after any ID3 tag
+	bitrate    int   // bits per second
+	sampleRate int
+	samples    int   // samples per frame
+	frames     int64 // total frames from a Xing/Info header, 0 if absent
+}
+
+var (
+	mp3Bitrates = map[bool][]int{ // MPEG-1 or not -> Layer III bitrates in kbps
+		true:  {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		false: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = map[byte][]int{ // version bits -> sample rates
+		3: {44100, 48000, 32000},
+		2: {22050, 24000, 16000},
+		0: {11025, 12000, 8000},
+	}
+)
+
+// readMP3Header skips an ID3v2 tag and parses the first Layer III frame
+// header, or returns nil
+func readMP3Header(r io.Reader) *mp3Header {
+	header := &mp3Header{}
+	frame := make([]byte, 4)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil
+	}
+
+	if string(frame[:3]) == "ID3" {
+		rest := make([]byte, 6)
+		if _, err := io.ReadFull(r, rest); err != nil {
+			return nil
+		}
+		tagSize := int64(rest[2])<<21 | int64(rest[3])<<14 | int64(rest[4])<<7 | int64(rest[5])
+		if rest[1]&0x10 != 0 {
+			tagSize += 10 // footer
+		}
+		if _, err := io.CopyN(io.Discard, r, tagSize); err != nil {
+			return nil
+		}
+		header.start = 10 + tagSize
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil
+		}
+	}
+
+	version := frame[1] >> 3 & 0x3
+	if frame[0] != 0xff || frame[1]&0xe0 != 0xe0 || version == 1 || frame[1]>>1&0x3 != 1 {
+		return nil
+	}
+	mpeg1 := version == 3
+	bitrateIndex, rateIndex := frame[2]>>4, frame[2]>>2&0x3
+	if bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return nil
+	}
+	header.bitrate = mp3Bitrates[mpeg1][bitrateIndex] * 1000
+	header.sampleRate = mp3SampleRates[version][rateIndex]
+	header.samples = 576
+	if mpeg1 {
+		header.samples = 1152
+	}
+
+	// A Xing or Info tag after the side information gives the frame count
+	// of variable bitrate files
+	sideInfo := 17
+	switch mono := frame[3]>>6 == 3; {
+	case mpeg1 && !mono:
+		sideInfo = 32
+	case !mpeg1 && mono:
+		sideInfo = 9
+	}
+	xing := make([]byte, sideInfo+12)
+	if _, err := io.ReadFull(r, xing); err == nil {
+		tag := xing[sideInfo:]
+		if (string(tag[:4]) == "Xing" || string(tag[:4]) == "Info") && tag[7]&0x1 != 0 {
+			header.frames = int64(binary.BigEndian.Uint32(tag[8:12]))
+		}
+	}
+	return header
+}
+
+// duration estimates the playing time of an MP3 of the given size
+func (h *mp3Header) duration(size int64) (float64, bool) {
+	switch {
+	case h == nil:
+		return 0, false
+	case h.frames > 0:
+		return float64(h.frames*int64(h.samples)) / float64(h.sampleRate), true
+	case h.bitrate > 0 && size > h.start:
+		return float64(size-h.start) * 8 / float64(h.bitrate), true
+	}
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository/repotest"
+
+	"go.uber.org/zap"
+)
+
+// The conversation and date filters match (upload_id, asset_id) pairs
+// against a subquery, which both databases must accept
+func TestListMediaByMessage(t *testing.T) {
+	for _, backend := range repotest.Backends {
+		t.Run(backend.Name, func(t *testing.T) {
+			store := backend.New(t)
+			fixtures := repotest.NewFixtures(t, store)
+			media := NewMediaService(repotest.Config(t), zap.NewNop(), store, nil)
+
+			upload := fixtures.Upload()
+			other := fixtures.Upload()
+			conversation := fixtures.Conversation(upload)
+			message := fixtures.Message(conversation, "user", "see attached", testDay)
+			later := fixtures.Message(fixtures.Conversation(upload), "user", "another", testDay.AddDate(0, 0, 1))
+
+			asset := func(upload *models.Upload, assetID string) *models.MediaAsset {
+				t.Helper()
+				extraction := &models.Extraction{UploadID: upload.ID, FilePath: assetID + ".png", FileType: "image", Status: "extracted", ExtractedAt: testDay}
+				if err := store.Extractions.Create(extraction); err != nil {
+					t.Fatalf("failed to create extraction: %v", err)
+				}
+				record := &models.MediaAsset{UploadID: upload.ID, ExtractionID: extraction.ID, AssetID: assetID, FileName: assetID + ".png", MimeType: "image/png", CataloguedAt: testDay}
+				if err := store.MediaAssets.Create(record); err != nil {
+					t.Fatalf("failed to create media asset: %v", err)
+				}
+				return record
+			}
+			link := func(msg *models.Message, conversationID uint, assetID string) {
+				t.Helper()
+				record := &models.MessageAsset{UploadID: upload.ID, ConversationID: conversationID, MessageID: msg.ID, AssetID: assetID, PointerType: "attachment"}
+				if err := store.MessageAssets.Create(record); err != nil {
+					t.Fatalf("failed to create message asset: %v", err)
+				}
+			}
+
+			linked := asset(upload, "file-linked")
+			asset(upload, "file-later")
+			asset(other, "file-linked") // same ID in another upload is not the referenced file
+			link(message, conversation.ID, "file-linked")
+			link(later, later.ConversationID, "file-later")
+
+			for _, filter := range []MediaFilter{
+				{ConversationID: conversation.ID},
+				{Date: testDate},
+			} {
+				assets, total, err := media.ListMedia(filter, 1, 10)
+				if err != nil {
+					t.Fatalf("ListMedia(%+v) failed: %v", filter, err)
+				}
+				if total != 1 || len(assets) != 1 || assets[0].ID != linked.ID {
+					t.Errorf("ListMedia(%+v) = %d assets of %d, want only asset %d", filter, len(assets), total, linked.ID)
+				}
+			}
+		})
+	}
+}
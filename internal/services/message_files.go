@@ -0,0 +1,232 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+)
+
+// MessageFileReport summarises a rebuild of the message files
+type MessageFileReport struct {
+	Dates        int `json:"dates"`
+	FilesWritten int `json:"files_written"`
+	FilesRemoved int `json:"files_removed"`
+}
+
+// messageFilePattern matches the names of the per-date message files
+var messageFilePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\.md$`)
+
+// conversationLinks maps a source to the URL of a conversation in the
+// original app, keyed by its export ID
+var conversationLinks = map[string]string{
+	"chatgpt": "https://chatgpt.com/c/%s",
+	"claude":  "https://claude.ai/chat/%s",
+}
+
+// RebuildMessageFiles regenerates the message file of every date with user
+// messages and removes files for dates that no longer have any
+func (s *ParserService) RebuildMessageFiles() (*MessageFileReport, error) {
+	var messages []models.Message
+	if err := s.store.Messages.Query().Select("timestamp").Where("role = ?", "user").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get message dates: %w", err)
+	}
+	dates := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		dates = append(dates, msg.Timestamp.UTC().Format("2006-01-02"))
+	}
+	dates = mergeDates(nil, dates)
+
+	if err := s.RewriteMessageFiles(dates); err != nil {
+		return nil, err
+	}
+	report := &MessageFileReport{Dates: len(dates), FilesWritten: len(dates)}
+
+	// Remove files left behind for dates without messages
+	current := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		current[date] = true
+	}
+	entries, err := os.ReadDir(s.cfg.Directories.MessagesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return report, fmt.Errorf("failed to read messages directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !messageFilePattern.MatchString(name) || current[strings.TrimSuffix(name, ".md")] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.cfg.Directories.MessagesDir, name)); err != nil && !os.IsNotExist(err) {
+			return report, fmt.Errorf("failed to remove message file: %w", err)
+		}
+		report.FilesRemoved++
+	}
+	return report, nil
+}
+
+// RewriteMessageFiles regenerates the message files for the given dates from
+// the database, removing files for dates with no user messages. Each file
+// lists the day's user messages grouped by conversation, with assistant
+// replies when configured, and is replaced atomically.
+func (s *ParserService) RewriteMessageFiles(dates []string) error {
+	for _, date := range dates {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return fmt.Errorf("invalid date: %s", date)
+		}
+
+		roles := []string{"user"}
+		if s.cfg.Messages.IncludeAssistant {
+			roles = append(roles, "assistant")
+		}
+
+		// Only conversations with user messages that day are listed
+		var messages []models.Message
+		if err := s.store.Messages.Query().Where("role IN ? AND timestamp >= ? AND timestamp < ?", roles, day, day.AddDate(0, 0, 1)).
+			Where("conversation_id IN (?)", s.store.Messages.Query().Select("conversation_id").
+				Where("role = ? AND timestamp >= ? AND timestamp < ?", "user", day, day.AddDate(0, 0, 1))).
+			Order("timestamp ASC, message_index ASC, id ASC").
+			Find(&messages).Error; err != nil {
+			return fmt.Errorf("failed to load messages for %s: %w", date, err)
+		}
+
+		filePath := filepath.Join(s.cfg.Directories.MessagesDir, fmt.Sprintf("%s.md", date))
+		if len(messages) == 0 {
+			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove message file: %w", err)
+			}
+			continue
+		}
+
+		content, err := s.renderMessageFile(date, messages)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filePath, []byte(content)); err != nil {
+			return fmt.Errorf("failed to write message file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// renderMessageFile formats one date's messages, ordering conversations by
+// their first message of the day and listing each export conversation once
+func (s *ParserService) renderMessageFile(date string, messages []models.Message) (string, error) {
+	var order []uint
+	byConversation := make(map[uint][]models.Message)
+	for _, msg := range messages {
+		if _, ok := byConversation[msg.ConversationID]; !ok {
+			order = append(order, msg.ConversationID)
+		}
+		byConversation[msg.ConversationID] = append(byConversation[msg.ConversationID], msg)
+	}
+
+	conversations, err := s.store.Conversations.Find("id IN ?", order)
+	if err != nil {
+		return "", fmt.Errorf("failed to load conversations for %s: %w", date, err)
+	}
+	byID := make(map[uint]models.Conversation, len(conversations))
+	for _, conv := range conversations {
+		byID[conv.ID] = conv
+	}
+
+	latest, err := s.latestCopies(order)
+	if err != nil {
+		return "", fmt.Errorf("failed to load conversation copies for %s: %w", date, err)
+	}
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("# Messages for %s\n\n", date))
+	for _, id := range order {
+		conv := byID[id]
+		if keep, ok := latest[copyKey{conv.Source, conv.ExportID}]; ok && keep != id {
+			continue
+		}
+		title := "Untitled conversation"
+		if conv.Title != nil && strings.TrimSpace(*conv.Title) != "" {
+			title = strings.TrimSpace(*conv.Title)
+		}
+
+		content.WriteString(fmt.Sprintf("## %s\n\n", title))
+		links := []string{fmt.Sprintf("[conversation %d](/api/v1/conversations/%d)", id, id)}
+		if format, ok := conversationLinks[conv.Source]; ok && conv.ExportID != "" {
+			links = append(links, fmt.Sprintf("[original](%s)", fmt.Sprintf(format, conv.ExportID)))
+		}
+		content.WriteString(strings.Join(links, " · "))
+		content.WriteString("\n\n")
+
+		for _, msg := range byConversation[id] {
+			content.WriteString(fmt.Sprintf("### %s %s\n\n", msg.Timestamp.UTC().Format("15:04:05"), msg.Role))
+			content.WriteString(strings.TrimRight(msg.Content, "\n"))
+			content.WriteString("\n\n")
+		}
+		content.WriteString("---\n\n")
+	}
+	return content.String(), nil
+}
+
+// copyKey identifies a conversation across uploads of the same account
+type copyKey struct {
+	source   string
+	exportID string
+}
+
+// latestCopies returns, for each export conversation among the given rows,
+// the row ID of its copy in the newest live upload. Cumulative exports import
+// the same conversation again, and only the newest copy is listed. Only the
+// rows with messages on the date compete, so an older copy still shows
+// messages a newer export no longer has.
+func (s *ParserService) latestCopies(conversationIDs []uint) (map[copyKey]uint, error) {
+	latest := make(map[copyKey]uint)
+	if len(conversationIDs) == 0 {
+		return latest, nil
+	}
+
+	var copies []struct {
+		ID       uint
+		Source   string
+		ExportID string
+	}
+	if err := s.store.Conversations.Query().
+		Select("conversations.id, conversations.source, conversations.export_id").
+		Joins("JOIN uploads ON uploads.id = conversations.upload_id AND uploads.deleted_at IS NULL").
+		Where("conversations.id IN ? AND conversations.export_id <> ''", conversationIDs).
+		Order("uploads.uploaded_at ASC, conversations.upload_id ASC, conversations.id ASC").
+		Scan(&copies).Error; err != nil {
+		return nil, err
+	}
+
+	// Ordered oldest first, so the last copy seen wins
+	for _, c := range copies {
+		latest[copyKey{c.Source, c.ExportID}] = c.ID
+	}
+	return latest, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partly written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chatgpt-autopsy-go/internal/models"
+)
+
+func TestRewriteMessageFilesListsNewestCopyPerDate(t *testing.T) {
+	ts := newTestServices(t)
+	nextDay := testDay.AddDate(0, 0, 1)
+
+	// The older export holds a message the newer one no longer has, such as
+	// a regenerated branch
+	older := ts.fixtures.Upload(func(u *models.Upload) { u.UploadedAt = testDay })
+	newer := ts.fixtures.Upload(func(u *models.Upload) { u.UploadedAt = nextDay.AddDate(0, 0, 1) })
+	sameExport := func(c *models.Conversation) { c.ExportID = "export-1" }
+	oldCopy := ts.fixtures.Conversation(older, sameExport)
+	newCopy := ts.fixtures.Conversation(newer, sameExport)
+	ts.fixtures.Message(oldCopy, "user", "first question, old copy", testDay)
+	ts.fixtures.Message(oldCopy, "user", "dropped follow-up", nextDay)
+	ts.fixtures.Message(newCopy, "user", "first question, new copy", testDay)
+
+	nextDate := nextDay.Format("2006-01-02")
+	if err := ts.parser.RewriteMessageFiles([]string{testDate, nextDate}); err != nil {
+		t.Fatalf("RewriteMessageFiles failed: %v", err)
+	}
+
+	read := func(date string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(ts.cfg.Directories.MessagesDir, date+".md"))
+		if err != nil {
+			t.Fatalf("failed to read message file for %s: %v", date, err)
+		}
+		return string(data)
+	}
+
+	first := read(testDate)
+	if !strings.Contains(first, "first question, new copy") || strings.Contains(first, "old copy") {
+		t.Errorf("%s lists the wrong copy:\n%s", testDate, first)
+	}
+	if got := strings.Count(first, "\n## "); got != 1 {
+		t.Errorf("%s lists the conversation %d times, want once", testDate, got)
+	}
+
+	// Only the older copy has messages on the next day, so it is listed
+	if second := read(nextDate); !strings.Contains(second, "dropped follow-up") {
+		t.Errorf("%s lost the older copy's message:\n%s", nextDate, second)
+	}
+}
@@ -1,109 +1,89 @@
 package services
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
+	"io"
 	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
-// ParserService handles parsing of ChatGPT export JSON files
+// ParserService parses the conversation files of chat exports, handing each
+// file to the importer for its source
 type ParserService struct {
-	cfg *config.Config
-	log *zap.Logger
+	cfg        *config.Config
+	log        *zap.Logger
+	store      *repository.Store
+	progress   *ImportProgressService
+	extraction *ExtractionService
+	importers  []SourceImporter
 }
 
 // NewParserService creates a new parser service
-func NewParserService(cfg *config.Config, log *zap.Logger) *ParserService {
+func NewParserService(cfg *config.Config, log *zap.Logger, store *repository.Store, progress *ImportProgressService, extraction *ExtractionService) *ParserService {
 	return &ParserService{
-		cfg: cfg,
-		log: log,
+		cfg:        cfg,
+		log:        log,
+		store:      store,
+		progress:   progress,
+		extraction: extraction,
+		importers:  defaultSourceImporters(),
 	}
 }
 
-// ChatGPTExport represents the structure of ChatGPT export JSON
-type ChatGPTExport []ChatGPTConversation
-
-// ChatGPTConversation represents a single conversation in the export
-type ChatGPTConversation struct {
-	Title      string                 `json:"title"`
-	CreateTime float64                `json:"create_time"`
-	UpdateTime float64                `json:"update_time"`
-	Mapping    map[string]MessageNode `json:"mapping"`
-}
-
-// MessageNode represents a message node in the conversation tree
-type MessageNode struct {
-	ID       string      `json:"id"`
-	Message  *MessageData `json:"message"`
-	Parent   *string     `json:"parent"`
-	Children []string    `json:"children"`
-}
-
-// MessageData represents the actual message content
-type MessageData struct {
-	ID       string                 `json:"id"`
-	Author   AuthorData             `json:"author"`
-	Content  ContentData             `json:"content"`
-	Status   string                 `json:"status"`
-	Metadata map[string]interface{} `json:"metadata"`
-}
-
-// AuthorData represents message author information
-type AuthorData struct {
-	Role     string                 `json:"role"`
-	Name     *string                `json:"name"`
-	Metadata map[string]interface{} `json:"metadata"`
-}
-
-// ContentData represents message content
-type ContentData struct {
-	ContentType string   `json:"content_type"`
-	Parts       []string `json:"parts"`
-}
-
 // ParseUpload parses extracted files for an upload
 func (s *ParserService) ParseUpload(uploadID uint) error {
-	var upload models.Upload
-	if err := database.DB.First(&upload, uploadID).Error; err != nil {
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil {
 		return fmt.Errorf("upload not found: %w", err)
 	}
 
-	// Find conversation JSON files
+	// Find conversation JSON files, falling back to the copy embedded in
+	// chat.html for exports without them
 	var extractions []models.Extraction
-	if err := database.DB.Where("upload_id = ? AND file_type = ?", uploadID, "conversation").Find(&extractions).Error; err != nil {
-		return fmt.Errorf("failed to find extraction files: %w", err)
+	for _, fileType := range []string{"conversation", "chat_html"} {
+		extractions, err = s.store.Extractions.ForUpload(uploadID, []string{fileType}, []string{"extracted", "parsed"})
+		if err != nil {
+			return fmt.Errorf("failed to find extraction files: %w", err)
+		}
+		if len(extractions) > 0 {
+			break
+		}
 	}
 
 	if len(extractions) == 0 {
 		return fmt.Errorf("no conversation files found for upload %d", uploadID)
 	}
 
-	var importRecord models.Import
-	if err := database.DB.Where("upload_id = ?", uploadID).First(&importRecord).Error; err != nil {
+	importRecord, err := s.store.Imports.ForUpload(uploadID)
+	if err != nil {
 		return fmt.Errorf("import record not found: %w", err)
 	}
 
 	importRecord.Status = "parsing"
+	importRecord.Stage = StageParse
 	importRecord.ProgressPercent = 40
-	database.DB.Save(&importRecord)
+	s.progress.Save(importRecord)
 
 	var totalConversations int
 	var totalMessages int
 
 	// Process each conversation file
 	for i, extraction := range extractions {
-		conversations, messages, err := s.parseConversationFile(extraction.FilePath, uploadID)
+		conversations, messages, err := s.parseConversationFile(&extraction, upload)
+
+		// Conversations decoded before an error are kept
+		totalConversations += conversations
+		totalMessages += messages
+
 		if err != nil {
 			s.log.Warn("Failed to parse conversation file",
 				zap.String("file", extraction.FilePath),
@@ -112,30 +92,30 @@ func (s *ParserService) ParseUpload(uploadID uint) error {
 			continue
 		}
 
-		totalConversations += conversations
-		totalMessages += messages
-
 		// Update extraction status
 		extraction.Status = "parsed"
-		database.DB.Save(&extraction)
+		s.store.Extractions.Save(&extraction)
 
 		// Update progress
 		progress := 40 + int(float64(i+1)/float64(len(extractions))*30) // 40-70%
 		importRecord.ProgressPercent = progress
-		database.DB.Save(&importRecord)
+		s.progress.SetCount(importRecord, "conversations_count", totalConversations)
+		s.progress.SetCount(importRecord, "messages_count", totalMessages)
+		s.progress.Save(importRecord)
 	}
 
+	// Import the account, feedback, comparison and share files once the
+	// conversations they refer to exist
+	s.parseExportFiles(upload, importRecord)
+
 	// Update import stats
-	stats := map[string]interface{}{
-		"conversations_count": totalConversations,
-		"messages_count":      totalMessages,
-		"files_extracted":     len(extractions),
-	}
-	statsJSON, _ := json.Marshal(stats)
-	importRecord.Stats = string(statsJSON)
+	s.progress.SetCount(importRecord, "conversations_count", totalConversations)
+	s.progress.SetCount(importRecord, "messages_count", totalMessages)
+	s.progress.SetCount(importRecord, "conversation_files", len(extractions))
 	importRecord.Status = "importing"
+	importRecord.Stage = StageThread
 	importRecord.ProgressPercent = 70
-	database.DB.Save(&importRecord)
+	s.progress.Save(importRecord)
 
 	s.log.Info("Parsing completed",
 		zap.Uint("upload_id", uploadID),
@@ -146,241 +126,195 @@ func (s *ParserService) ParseUpload(uploadID uint) error {
 	return nil
 }
 
-// parseConversationFile parses a single conversation JSON file
-func (s *ParserService) parseConversationFile(filePath string, uploadID uint) (int, int, error) {
-	// Read file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse JSON
-	var export ChatGPTExport
-	if err := json.Unmarshal(data, &export); err != nil {
-		return 0, 0, fmt.Errorf("failed to parse JSON: %w", err)
+// parseConversationFile parses a single conversation file, streaming it
+// from disk or straight out of the stored upload. The source format is
+// detected from the file's first record.
+func (s *ParserService) parseConversationFile(extraction *models.Extraction, upload *models.Upload) (int, int, error) {
+	sourcePath := extraction.FilePath
+	if extraction.InArchive {
+		sourcePath = upload.StoredPath + "!/" + extraction.FilePath
 	}
 
 	var conversationsCreated int
 	var messagesCreated int
 
-	// Process each conversation
-	for _, conv := range export {
-		convRecord, msgCount, err := s.processConversation(conv, uploadID, filePath)
-		if err != nil {
-			s.log.Warn("Failed to process conversation",
-				zap.String("title", conv.Title),
-				zap.Error(err),
-			)
-			continue
-		}
-
-		conversationsCreated++
-		messagesCreated += msgCount
-
-		// Update conversation message count
-		convRecord.MessageCount = msgCount
-		database.DB.Save(&convRecord)
-	}
-
-	return conversationsCreated, messagesCreated, nil
-}
-
-// processConversation processes a single conversation and creates database records
-func (s *ParserService) processConversation(conv ChatGPTConversation, uploadID uint, sourcePath string) (*models.Conversation, int, error) {
-	// Create conversation record
-	conversation := models.Conversation{
-		UploadID:       uploadID,
-		ConversationID: s.findRootMessageID(conv.Mapping),
-		Title:          &conv.Title,
-		CreatedAt:      time.Unix(int64(conv.CreateTime), 0).UTC(),
-		UpdatedAt:      time.Unix(int64(conv.UpdateTime), 0).UTC(),
-		SourceFilePath: sourcePath,
-		MessageCount:   0,
-	}
+	err := s.extraction.ReadFile(extraction, func(r io.Reader) error {
+		buffered := bufio.NewReaderSize(r, sourceSampleSize)
 
-	// Check if conversation already exists
-	var existing models.Conversation
-	if err := database.DB.Where("upload_id = ? AND conversation_id = ?", uploadID, conversation.ConversationID).
-		First(&existing).Error; err == nil {
-		// Conversation already exists, return it
-		return &existing, existing.MessageCount, nil
-	}
-
-	// Create conversation
-	if err := database.DB.Create(&conversation).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to create conversation: %w", err)
-	}
-
-	// Process messages
-	messages, err := s.processMessages(conv.Mapping, conversation.ID)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to process messages: %w", err)
-	}
-
-	// Save messages in batches
-	if len(messages) > 0 {
-		batchSize := 1000
-		for i := 0; i < len(messages); i += batchSize {
-			end := i + batchSize
-			if end > len(messages) {
-				end = len(messages)
-			}
-			if err := database.DB.CreateInBatches(messages[i:end], batchSize).Error; err != nil {
-				return nil, 0, fmt.Errorf("failed to create messages batch: %w", err)
+		// chat.html embeds the export as a script variable
+		if extraction.FileType == "chat_html" {
+			if err := skipPast(buffered, chatHTMLDataMarker); err != nil {
+				return fmt.Errorf("no conversation data found in chat.html: %w", err)
 			}
 		}
-	}
-
-	// Extract and save user messages by date
-	if err := s.extractUserMessagesByDate(messages, conversation.ID); err != nil {
-		s.log.Warn("Failed to extract user messages by date", zap.Error(err))
-	}
-
-	return &conversation, len(messages), nil
-}
 
-// processMessages processes message nodes and creates message records
-func (s *ParserService) processMessages(mapping map[string]MessageNode, conversationID uint) ([]models.Message, error) {
-	var messages []models.Message
-	messageIndex := 0
-
-	// Find root message (no parent)
-	var rootID string
-	for id, node := range mapping {
-		if node.Parent == nil {
-			rootID = id
-			break
+		// Drop a UTF-8 byte order mark, which the JSON parser rejects
+		if bom, err := buffered.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+			buffered.Discard(3)
 		}
-	}
 
-	if rootID == "" {
-		return messages, nil
-	}
+		importer, err := s.detectSource(sampleSource(buffered, extraction.FilePath))
+		if err != nil {
+			return err
+		}
 
-	// Traverse message tree
-	s.traverseMessages(mapping, rootID, conversationID, &messages, &messageIndex)
+		// Decode one conversation at a time rather than the whole export
+		return importer.Decode(buffered, func(conv *ImportedConversation) error {
+			_, msgCount, err := s.processConversation(conv, importer.Source(), upload.ID, sourcePath)
+			if err != nil {
+				s.log.Warn("Failed to process conversation",
+					zap.String("source", importer.Source()),
+					zap.String("title", conv.Title),
+					zap.Error(err),
+				)
+				return nil
+			}
 
-	// Sort by message_index
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].MessageIndex < messages[j].MessageIndex
+			conversationsCreated++
+			messagesCreated += msgCount
+			return nil
+		})
 	})
 
-	return messages, nil
+	return conversationsCreated, messagesCreated, err
 }
 
-// traverseMessages recursively traverses the message tree
-func (s *ParserService) traverseMessages(mapping map[string]MessageNode, nodeID string, conversationID uint, messages *[]models.Message, index *int) {
-	node, exists := mapping[nodeID]
-	if !exists || node.Message == nil {
-		return
-	}
+// chatHTMLDataMarker precedes the conversations array in chat.html
+const chatHTMLDataMarker = "var jsonData = "
 
-	msg := node.Message
-	if msg.Status != "finished_successfully" {
-		// Skip incomplete messages
-		for _, childID := range node.Children {
-			s.traverseMessages(mapping, childID, conversationID, messages, index)
+// skipPast reads r up to and including the first occurrence of marker
+func skipPast(r *bufio.Reader, marker string) error {
+	matched := 0
+	for matched < len(marker) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
 		}
-		return
-	}
-
-	// Extract content parts
-	var content strings.Builder
-	for i, part := range msg.Content.Parts {
-		if i > 0 {
-			content.WriteString("\n\n")
+		switch {
+		case b == marker[matched]:
+			matched++
+		case b == marker[0]:
+			matched = 1
+		default:
+			matched = 0
 		}
-		content.WriteString(part)
-	}
-
-	// Parse timestamp (usually in metadata or use current time as fallback)
-	timestamp := time.Now().UTC()
-	if createTime, ok := msg.Metadata["create_time"].(float64); ok {
-		timestamp = time.Unix(int64(createTime), 0).UTC()
-	}
-
-	// Create message record
-	message := models.Message{
-		ConversationID: conversationID,
-		MessageID:      &msg.ID,
-		Role:           msg.Author.Role,
-		Content:        content.String(),
-		Timestamp:      timestamp,
-		MessageIndex:   *index,
 	}
+	return nil
+}
 
-	// Serialize metadata
-	if metadataJSON, err := json.Marshal(msg.Metadata); err == nil {
-		message.Metadata = string(metadataJSON)
+// processConversation stores an imported conversation and its messages
+func (s *ParserService) processConversation(conv *ImportedConversation, source string, uploadID uint, sourcePath string) (*models.Conversation, int, error) {
+	// Messages without a timestamp fall back to the conversation's creation
+	// time, or failing that the import time
+	createdAt := conv.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
 	}
 
-	*messages = append(*messages, message)
-	*index++
-
-	// Process children
-	for _, childID := range node.Children {
-		s.traverseMessages(mapping, childID, conversationID, messages, index)
+	// Create conversation record
+	conversation := models.Conversation{
+		UploadID:       uploadID,
+		ConversationID: conv.ConversationID,
+		ExportID:       conv.ExportID,
+		Source:         source,
+		Title:          &conv.Title,
+		CreatedAt:      conv.CreatedAt,
+		UpdatedAt:      conv.UpdatedAt,
+		SourceFilePath: sourcePath,
 	}
-}
 
-// findRootMessageID finds the root message ID (conversation identifier)
-func (s *ParserService) findRootMessageID(mapping map[string]MessageNode) string {
-	for id, node := range mapping {
-		if node.Parent == nil {
-			return id
+	// Build message records
+	messages := make([]models.Message, 0, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		message := models.Message{
+			Role:         msg.Role,
+			Content:      msg.Content,
+			Timestamp:    msg.Timestamp,
+			MessageIndex: i,
+		}
+		if msg.ID != "" {
+			id := msg.ID
+			message.MessageID = &id
+		}
+		if message.Timestamp.IsZero() {
+			message.Timestamp = createdAt
 		}
-	}
-	// Fallback: return first key
-	for id := range mapping {
-		return id
-	}
-	return ""
-}
 
-// extractUserMessagesByDate extracts user messages and organizes them by date
-func (s *ParserService) extractUserMessagesByDate(messages []models.Message, conversationID uint) error {
-	// Group messages by date
-	messagesByDate := make(map[string][]models.Message)
-	
-	for _, msg := range messages {
-		if msg.Role == "user" {
-			date := msg.Timestamp.Format("2006-01-02")
-			messagesByDate[date] = append(messagesByDate[date], msg)
+		// Serialize metadata
+		if metadataJSON, err := json.Marshal(msg.Metadata); err == nil {
+			message.Metadata = string(metadataJSON)
 		}
+		messages = append(messages, message)
 	}
+	conversation.MessageCount = len(messages)
+
+	// The conversation, its messages and their asset links are stored
+	// together, so a failure part way leaves nothing behind to skip on retry.
+	// Message files are rebuilt from the database once parsing is done.
+	err := s.store.Transaction(func(tx *repository.Store) error {
+		existing, err := tx.Conversations.First("upload_id = ? AND conversation_id = ?", uploadID, conversation.ConversationID)
+		if err == nil {
+			stored, err := tx.Messages.Count("conversation_id = ?", existing.ID)
+			if err != nil {
+				return fmt.Errorf("failed to count messages: %w", err)
+			}
+			if int(stored) == existing.MessageCount {
+				// Already imported in full
+				conversation = *existing
+				messages = nil
+				return nil
+			}
 
-	// Write to date files
-	for date, msgs := range messagesByDate {
-		// Sort messages by timestamp
-		sort.Slice(msgs, func(i, j int) bool {
-			return msgs[i].Timestamp.Before(msgs[j].Timestamp)
-		})
+			// Left incomplete by an earlier import; replace it
+			if _, err := tx.MessageAssets.DeleteWhere("conversation_id = ?", existing.ID); err != nil {
+				return fmt.Errorf("failed to delete message assets: %w", err)
+			}
+			if _, err := tx.Messages.DeleteWhere("conversation_id = ?", existing.ID); err != nil {
+				return fmt.Errorf("failed to delete messages: %w", err)
+			}
+			conversation.ID = existing.ID
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to check conversation: %w", err)
+		}
 
-		// Create file path
-		filePath := filepath.Join(s.cfg.Directories.MessagesDir, fmt.Sprintf("%s.md", date))
-
-		// Append to file (multiple conversations may contribute to same date)
-		var content strings.Builder
-		content.WriteString(fmt.Sprintf("# Messages for %s\n\n", date))
-		
-		for _, msg := range msgs {
-			content.WriteString(fmt.Sprintf("## %s\n\n", msg.Timestamp.Format("15:04:05")))
-			content.WriteString(msg.Content)
-			content.WriteString("\n\n---\n\n")
+		if err := tx.Conversations.Save(&conversation); err != nil {
+			return fmt.Errorf("failed to create conversation: %w", err)
 		}
 
-		// Append to existing file or create new
-		existingContent, _ := os.ReadFile(filePath)
-		if len(existingContent) > 0 {
-			content.WriteString("\n\n")
-			content.Write(existingContent)
+		for i := range messages {
+			messages[i].ConversationID = conversation.ID
+		}
+		if err := tx.Messages.CreateInBatches(messages, 1000); err != nil {
+			return fmt.Errorf("failed to create messages: %w", err)
 		}
 
-		if err := os.WriteFile(filePath, []byte(content.String()), 0644); err != nil {
-			return fmt.Errorf("failed to write message file: %w", err)
+		// Link messages to the media files they reference
+		if err := s.linkMessageAssets(tx, conv, messages, uploadID, conversation.ID); err != nil {
+			return fmt.Errorf("failed to link message assets: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return nil
+	return &conversation, conversation.MessageCount, nil
 }
 
+// linkMessageAssets records the files each saved message refers to
+func (s *ParserService) linkMessageAssets(tx *repository.Store, conv *ImportedConversation, messages []models.Message, uploadID, conversationID uint) error {
+	var links []models.MessageAsset
+	for i, message := range messages {
+		for _, asset := range conv.Messages[i].Assets {
+			links = append(links, models.MessageAsset{
+				UploadID:       uploadID,
+				ConversationID: conversationID,
+				MessageID:      message.ID,
+				AssetID:        asset.AssetID,
+				PointerType:    asset.PointerType,
+			})
+		}
+	}
+
+	return tx.MessageAssets.CreateInBatches(links, 500)
+}
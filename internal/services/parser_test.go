@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"chatgpt-autopsy-go/internal/models"
+)
+
+func TestParseUploadTwice(t *testing.T) {
+	ts := newTestServices(t)
+	upload := ts.importExport(t, chatGPTExport(t, "conv-1", "hello", testDay))
+
+	conversations := countRows(t, ts.store, &models.Conversation{}, "upload_id = ?", upload.ID)
+	messages := countRows(t, ts.store, &models.Message{}, "")
+	if conversations != 1 || messages != 2 {
+		t.Fatalf("imported %d conversations and %d messages, want 1 and 2", conversations, messages)
+	}
+
+	// Parsing again, as a resumed import does, skips what is already stored
+	if err := ts.parser.ParseUpload(upload.ID); err != nil {
+		t.Fatalf("second ParseUpload failed: %v", err)
+	}
+	if got := countRows(t, ts.store, &models.Conversation{}, "upload_id = ?", upload.ID); got != conversations {
+		t.Errorf("conversations after second parse = %d, want %d", got, conversations)
+	}
+	if got := countRows(t, ts.store, &models.Message{}, ""); got != messages {
+		t.Errorf("messages after second parse = %d, want %d", got, messages)
+	}
+}
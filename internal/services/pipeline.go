@@ -0,0 +1,761 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// PipelineStages lists the stages that can be reprocessed, in run order
+var PipelineStages = []string{StageExtract, StageParse, StageThread, StageAnalyze}
+
+// PipelineService runs the import pipeline for an upload in the background,
+// reporting progress through the upload's import record
+type PipelineService struct {
+	cfg        *config.Config
+	log        *zap.Logger
+	store      *repository.Store
+	extraction *ExtractionService
+	parser     *ParserService
+	thread     *ThreadService
+	analysis   *AnalysisService
+	secrets    *SecretScanService
+	media      *MediaService
+	progress   *ImportProgressService
+
+	mu      sync.Mutex
+	running map[uint]bool // upload IDs with a pipeline in flight
+	paused  string        // what the pipeline is paused for, empty when running
+	queued  []uint        // uploads started while paused, run on resume
+}
+
+// NewPipelineService creates a new pipeline service
+func NewPipelineService(
+	cfg *config.Config,
+	log *zap.Logger,
+	store *repository.Store,
+	extraction *ExtractionService,
+	parser *ParserService,
+	thread *ThreadService,
+	analysis *AnalysisService,
+	secrets *SecretScanService,
+	media *MediaService,
+	progress *ImportProgressService,
+) *PipelineService {
+	return &PipelineService{
+		cfg:        cfg,
+		log:        log,
+		store:      store,
+		extraction: extraction,
+		parser:     parser,
+		thread:     thread,
+		analysis:   analysis,
+		secrets:    secrets,
+		media:      media,
+		progress:   progress,
+		running:    make(map[uint]bool),
+	}
+}
+
+// Start runs the full import pipeline for a new upload in the background.
+// While the pipeline is paused the upload is queued and started on resume.
+func (s *PipelineService) Start(uploadID uint) error {
+	queued, err := s.acquireOrQueue(uploadID)
+	if err != nil {
+		return err
+	}
+	if queued {
+		s.log.Info("Pipeline paused, upload queued", zap.Uint("upload_id", uploadID))
+		return nil
+	}
+
+	go s.run(uploadID, []string{StageExtract, StageParse, StageThread})
+	return nil
+}
+
+// Import runs the full import pipeline for a new upload and waits for it to
+// finish, for callers without a server to report progress to
+func (s *PipelineService) Import(uploadID uint) error {
+	if err := s.acquire(uploadID); err != nil {
+		return err
+	}
+
+	return s.run(uploadID, []string{StageExtract, StageParse, StageThread})
+}
+
+// Reprocess removes the rows and files derived by the selected stages and
+// rebuilds them in the background. Import stages after the earliest selected
+// one always run again because they consume its output; analyze only runs
+// when selected. It returns the stages that will run.
+func (s *PipelineService) Reprocess(uploadID uint, stages []string) ([]string, error) {
+	plan, err := planStages(stages)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("upload not found: %d", uploadID)
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	if containsStage(plan, StageExtract) {
+		if _, err := os.Stat(upload.StoredPath); err != nil {
+			return nil, fmt.Errorf("stored upload file is missing, cannot re-extract: %w", err)
+		}
+	}
+
+	if err := s.acquire(uploadID); err != nil {
+		return nil, err
+	}
+
+	if err := s.progress.Restart(uploadID); err != nil {
+		s.release(uploadID)
+		return nil, err
+	}
+
+	go s.run(uploadID, plan)
+	return plan, nil
+}
+
+// run executes the planned stages for an upload and records the outcome
+func (s *PipelineService) run(uploadID uint, plan []string) error {
+	defer s.release(uploadID)
+
+	s.setUploadStatus(uploadID, "processing", nil)
+	if err := s.runStages(uploadID, plan); err != nil {
+		s.log.Error("Pipeline failed",
+			zap.Uint("upload_id", uploadID),
+			zap.Strings("stages", plan),
+			zap.Error(err),
+		)
+		s.progress.Fail(uploadID, err)
+		errorMsg := err.Error()
+		s.setUploadStatus(uploadID, "failed", &errorMsg)
+		return err
+	}
+
+	if err := s.progress.Complete(uploadID); err != nil {
+		s.log.Error("Failed to complete import", zap.Uint("upload_id", uploadID), zap.Error(err))
+	}
+	s.setUploadStatus(uploadID, "completed", nil)
+	return nil
+}
+
+// runStages cleans up and runs each planned stage in order
+func (s *PipelineService) runStages(uploadID uint, plan []string) error {
+	var staleDates, invalidated []string
+
+	if containsStage(plan, StageExtract) {
+		if err := s.clearExtraction(uploadID); err != nil {
+			return err
+		}
+		if err := s.extraction.ExtractUpload(uploadID); err != nil {
+			return fmt.Errorf("extraction failed: %w", err)
+		}
+	}
+
+	if containsStage(plan, StageParse) {
+		dates, analysisDates, err := s.clearConversations(uploadID)
+		if err != nil {
+			return err
+		}
+		staleDates = dates
+		invalidated = mergeDates(invalidated, analysisDates)
+
+		parseErr := s.parser.ParseUpload(uploadID)
+
+		// Message files are shared between uploads, so rebuild every date this
+		// upload touched before or after parsing. Conversations committed
+		// before a parse failure are written too.
+		dates, err = s.uploadMessageDates(uploadID)
+		if err != nil {
+			return err
+		}
+		if err := s.parser.RewriteMessageFiles(mergeDates(staleDates, dates)); err != nil {
+			return fmt.Errorf("failed to rewrite message files: %w", err)
+		}
+		if parseErr != nil {
+			return fmt.Errorf("parsing failed: %w", parseErr)
+		}
+	}
+
+	if containsStage(plan, StageThread) {
+		analysisDates, err := s.clearThreads(uploadID)
+		if err != nil {
+			return err
+		}
+		invalidated = mergeDates(invalidated, analysisDates)
+		if err := s.thread.CreateThreadsForUpload(uploadID); err != nil {
+			return fmt.Errorf("thread creation failed: %w", err)
+		}
+	}
+
+	// Scan the rebuilt messages for leaked credentials and catalogue the
+	// media they reference
+	if containsStage(plan, StageParse) {
+		if _, err := s.secrets.ScanUpload(uploadID); err != nil {
+			s.log.Error("Secret scan failed", zap.Uint("upload_id", uploadID), zap.Error(err))
+		}
+		if _, err := s.media.CatalogueUpload(uploadID); err != nil {
+			s.log.Error("Media catalogue failed", zap.Uint("upload_id", uploadID), zap.Error(err))
+		}
+	}
+
+	if containsStage(plan, StageAnalyze) {
+		if err := s.analyze(uploadID, invalidated); err != nil {
+			return err
+		}
+	} else if len(invalidated) > 0 {
+		s.log.Info("Analyses invalidated, run the analyze stage to rebuild them",
+			zap.Uint("upload_id", uploadID),
+			zap.Strings("dates", invalidated),
+		)
+	}
+
+	return nil
+}
+
+// PurgeReport describes what purging an upload removed
+type PurgeReport struct {
+	UploadID         uint     `json:"upload_id"`
+	Conversations    int64    `json:"conversations"`
+	Messages         int64    `json:"messages"`
+	Threads          int64    `json:"threads"`
+	Analyses         int64    `json:"analyses"`
+	FilesRemoved     int      `json:"files_removed"`
+	BytesReclaimed   int64    `json:"bytes_reclaimed"`
+	InvalidatedDates []string `json:"invalidated_dates,omitempty"` // dates other uploads still cover, to be analyzed again
+}
+
+// Purge permanently removes an upload, including soft-deleted ones, with
+// everything derived from it: database rows, the stored file, extracted files,
+// its messages in the shared message files, and every analysis of the dates
+// its threads fall on. Dates other uploads still cover are reported as
+// invalidated so they can be analyzed again. The file hash can be uploaded
+// again afterwards.
+func (s *PipelineService) Purge(uploadID uint) (*PurgeReport, error) {
+	upload, err := s.store.Uploads.Unscoped().Get(uploadID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("upload not found: %d", uploadID)
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	if err := s.acquire(uploadID); err != nil {
+		return nil, err
+	}
+	defer s.release(uploadID)
+
+	report := &PurgeReport{UploadID: uploadID}
+	conversationIDs := s.store.Conversations.IDsForUpload(uploadID)
+	if report.Conversations, err = s.store.Conversations.Count("upload_id = ?", uploadID); err != nil {
+		return nil, fmt.Errorf("failed to count conversations: %w", err)
+	}
+	if report.Messages, err = s.store.Messages.Count("conversation_id IN (?)", conversationIDs); err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+	if report.Threads, err = s.store.Threads.Count("conversation_id IN (?)", conversationIDs); err != nil {
+		return nil, fmt.Errorf("failed to count threads: %w", err)
+	}
+
+	messageDates, err := s.uploadMessageDates(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	analysisDates, err := s.uploadAnalysisDates(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	// One transaction, so a failure never leaves an upload that still
+	// blocks its hash without the data it was imported with
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		removed, err := deleteDateAnalyses(tx, analysisDates)
+		if err != nil {
+			return err
+		}
+		report.Analyses += removed
+
+		removed, err = tx.Analyses.DeleteWhere("upload_id = ?", uploadID)
+		if err != nil {
+			return fmt.Errorf("failed to delete analyses: %w", err)
+		}
+		report.Analyses += removed
+
+		if err := deleteConversations(tx, uploadID); err != nil {
+			return err
+		}
+		if _, err := tx.Extractions.DeleteWhere("upload_id = ?", uploadID); err != nil {
+			return fmt.Errorf("failed to delete extraction records: %w", err)
+		}
+		if _, err := tx.Imports.DeleteWhere("upload_id = ?", uploadID); err != nil {
+			return fmt.Errorf("failed to delete import record: %w", err)
+		}
+		if err := tx.Uploads.Unscoped().Delete(upload); err != nil {
+			return fmt.Errorf("failed to delete upload: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove files once the rows are gone so a failure leaves nothing dangling
+	for _, path := range []string{upload.StoredPath, filepath.Join(s.cfg.Directories.ExtractedDir, upload.UUID)} {
+		files, bytes, err := removePath(path)
+		if err != nil {
+			s.log.Warn("Failed to remove upload files", zap.String("path", path), zap.Error(err))
+		}
+		report.FilesRemoved += files
+		report.BytesReclaimed += bytes
+	}
+	files, bytes := s.removeAnalysisFiles(analysisDates)
+	report.FilesRemoved += files
+	report.BytesReclaimed += bytes
+
+	for _, date := range analysisDates {
+		remaining, err := s.store.Threads.Count("date = ?", date)
+		if err != nil {
+			s.log.Warn("Failed to count threads", zap.String("date", date), zap.Error(err))
+			continue
+		}
+		if remaining > 0 {
+			report.InvalidatedDates = append(report.InvalidatedDates, date)
+		}
+	}
+
+	if err := s.parser.RewriteMessageFiles(messageDates); err != nil {
+		s.log.Warn("Failed to rewrite message files", zap.Uint("upload_id", uploadID), zap.Error(err))
+	}
+
+	s.log.Info("Upload purged",
+		zap.Uint("upload_id", uploadID),
+		zap.Int64("conversations", report.Conversations),
+		zap.Int64("messages", report.Messages),
+		zap.Int("files_removed", report.FilesRemoved),
+		zap.Int64("bytes_reclaimed", report.BytesReclaimed),
+		zap.Strings("invalidated_dates", report.InvalidatedDates),
+	)
+	return report, nil
+}
+
+// analyze regenerates the analyses for every date with threads from the
+// upload, and for the invalidated dates other uploads still have threads on
+func (s *PipelineService) analyze(uploadID uint, invalidated []string) error {
+	var dates []string
+	if err := s.store.Threads.Query().
+		Distinct("threads.date").
+		Joins("JOIN conversations ON conversations.id = threads.conversation_id").
+		Where("conversations.upload_id = ?", uploadID).
+		Pluck("threads.date", &dates).Error; err != nil {
+		return fmt.Errorf("failed to get upload dates: %w", err)
+	}
+	for _, date := range mergeDates(nil, invalidated) {
+		remaining, err := s.store.Threads.Count("date = ?", date)
+		if err != nil {
+			return fmt.Errorf("failed to count threads for %s: %w", date, err)
+		}
+		if remaining > 0 {
+			dates = append(dates, date)
+		}
+	}
+	dates = mergeDates(nil, dates)
+
+	importRecord, err := s.progress.GetImport(uploadID)
+	if err != nil {
+		return err
+	}
+	importRecord.Status = "analyzing"
+	importRecord.Stage = StageAnalyze
+	s.progress.Save(importRecord)
+
+	for i, date := range dates {
+		if err := s.analysis.GenerateAnalysisForDate(date, true); err != nil {
+			s.log.Warn("Failed to regenerate analysis",
+				zap.Uint("upload_id", uploadID),
+				zap.String("date", date),
+				zap.Error(err),
+			)
+		}
+
+		importRecord.ProgressPercent = 95 + int(float64(i+1)/float64(len(dates))*4) // 95-99%
+		s.progress.SetCount(importRecord, "dates_analyzed", i+1)
+		s.progress.Save(importRecord)
+	}
+
+	return nil
+}
+
+// clearExtraction removes the extraction records and extracted files
+func (s *PipelineService) clearExtraction(uploadID uint) error {
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil {
+		return fmt.Errorf("upload not found: %w", err)
+	}
+
+	if _, err := s.store.Extractions.DeleteWhere("upload_id = ?", uploadID); err != nil {
+		return fmt.Errorf("failed to delete extraction records: %w", err)
+	}
+
+	extractDir := filepath.Join(s.cfg.Directories.ExtractedDir, upload.UUID)
+	if err := os.RemoveAll(extractDir); err != nil {
+		return fmt.Errorf("failed to remove extraction directory: %w", err)
+	}
+	return nil
+}
+
+// clearConversations removes the conversations, messages, threads, findings
+// and other export data parsed from an upload, with every analysis of the
+// dates they fed. It returns the message dates that were removed so their
+// message files can be rebuilt, and the analysis dates that were invalidated.
+func (s *PipelineService) clearConversations(uploadID uint) ([]string, []string, error) {
+	messageDates, err := s.uploadMessageDates(uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	analysisDates, err := s.uploadAnalysisDates(uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		if _, err := deleteDateAnalyses(tx, analysisDates); err != nil {
+			return err
+		}
+		return deleteConversations(tx, uploadID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.removeAnalysisFiles(analysisDates)
+	return messageDates, analysisDates, nil
+}
+
+// clearThreads removes the threads created for an upload's conversations,
+// with every analysis of their dates. It returns the invalidated dates.
+func (s *PipelineService) clearThreads(uploadID uint) ([]string, error) {
+	analysisDates, err := s.uploadAnalysisDates(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		if _, err := deleteDateAnalyses(tx, analysisDates); err != nil {
+			return err
+		}
+		if _, err := tx.Threads.DeleteWhere("conversation_id IN (?)", tx.Conversations.IDsForUpload(uploadID)); err != nil {
+			return fmt.Errorf("failed to delete threads: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeAnalysisFiles(analysisDates)
+	return analysisDates, nil
+}
+
+// deleteConversations removes the rows parsed from an upload within tx
+func deleteConversations(tx *repository.Store, uploadID uint) error {
+	conversationIDs := tx.Conversations.IDsForUpload(uploadID)
+	if _, err := tx.SecretFindings.DeleteWhere("conversation_id IN (?)", conversationIDs); err != nil {
+		return fmt.Errorf("failed to delete secret findings: %w", err)
+	}
+	if _, err := tx.Threads.DeleteWhere("conversation_id IN (?)", conversationIDs); err != nil {
+		return fmt.Errorf("failed to delete threads: %w", err)
+	}
+	if _, err := tx.Messages.DeleteWhere("conversation_id IN (?)", conversationIDs); err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if _, err := tx.Conversations.DeleteWhere("upload_id = ?", uploadID); err != nil {
+		return fmt.Errorf("failed to delete conversations: %w", err)
+	}
+	for _, model := range exportDataModels {
+		if err := tx.DB().Where("upload_id = ?", uploadID).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to delete %T rows: %w", model, err)
+		}
+	}
+	if err := tx.Extractions.Query().
+		Where("upload_id = ? AND status = ?", uploadID, "parsed").
+		Update("status", "extracted").Error; err != nil {
+		return fmt.Errorf("failed to reset extraction status: %w", err)
+	}
+	return nil
+}
+
+// deleteDateAnalyses removes every analysis for the dates within tx. Date
+// analyses are built from all uploads, so dimension, synthesis and summary
+// rows are dropped together rather than leaving a partial set behind.
+func deleteDateAnalyses(tx *repository.Store, dates []string) (int64, error) {
+	if len(dates) == 0 {
+		return 0, nil
+	}
+	removed, err := tx.Analyses.DeleteWhere("date IN ?", dates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete analyses: %w", err)
+	}
+	return removed, nil
+}
+
+// uploadAnalysisDates returns the dates whose analyses draw on an upload:
+// the dates of its threads and of any analysis linked to it or its threads
+func (s *PipelineService) uploadAnalysisDates(uploadID uint) ([]string, error) {
+	conversationIDs := s.store.Conversations.IDsForUpload(uploadID)
+
+	var threadDates []string
+	if err := s.store.Threads.Query().
+		Distinct("date").
+		Where("conversation_id IN (?)", conversationIDs).
+		Pluck("date", &threadDates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get upload dates: %w", err)
+	}
+
+	var analysisDates []string
+	if err := s.store.Analyses.Query().
+		Distinct("date").
+		Where("date IS NOT NULL").
+		Where("upload_id = ? OR conversation_id IN (?) OR thread_id IN (?)", uploadID, conversationIDs,
+			s.store.Threads.Query().Select("id").Where("conversation_id IN (?)", conversationIDs)).
+		Pluck("date", &analysisDates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get analysis dates: %w", err)
+	}
+
+	return mergeDates(threadDates, analysisDates), nil
+}
+
+// removeAnalysisFiles removes the analysis directories of the dates and
+// returns how many files and bytes they held
+func (s *PipelineService) removeAnalysisFiles(dates []string) (int, int64) {
+	var files int
+	var bytes int64
+	for _, date := range dates {
+		removed, size, err := removePath(filepath.Join(s.cfg.Directories.AnalysisDir, date))
+		if err != nil {
+			s.log.Warn("Failed to remove analysis files", zap.String("date", date), zap.Error(err))
+		}
+		files += removed
+		bytes += size
+	}
+	return files, bytes
+}
+
+// setUploadStatus records the pipeline outcome on the upload
+func (s *PipelineService) setUploadStatus(uploadID uint, status string, errorMessage *string) {
+	if err := s.store.Uploads.SetStatus(uploadID, status, errorMessage); err != nil {
+		s.log.Warn("Failed to update upload status", zap.Uint("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// acquireOrQueue marks an upload as processing, or queues it if the
+// pipeline is paused, failing if it is already being processed
+func (s *PipelineService) acquireOrQueue(uploadID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused != "" {
+		s.queued = append(s.queued, uploadID)
+		return true, nil
+	}
+	return false, s.acquireLocked(uploadID)
+}
+
+// acquire marks an upload as processing, failing if it already is or the
+// pipeline is paused
+func (s *PipelineService) acquire(uploadID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acquireLocked(uploadID)
+}
+
+// acquireLocked is acquire with s.mu held
+func (s *PipelineService) acquireLocked(uploadID uint) error {
+	if s.paused != "" {
+		return fmt.Errorf("uploads cannot be processed while %s", s.paused)
+	}
+	if s.running[uploadID] {
+		return fmt.Errorf("upload %d is already being processed", uploadID)
+	}
+	s.running[uploadID] = true
+	return nil
+}
+
+// Pause stops new pipeline runs until the returned resume function is
+// called; reason completes "uploads cannot be processed while ...". It fails
+// if any upload is being processed. Uploads started in the meantime are
+// queued, and resuming starts those still pending.
+func (s *PipelineService) Pause(reason string) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused != "" {
+		return nil, fmt.Errorf("the pipeline is paused while %s", s.paused)
+	}
+	if len(s.running) > 0 {
+		return nil, fmt.Errorf("%d uploads are being processed", len(s.running))
+	}
+	s.paused = reason
+	return func() {
+		s.mu.Lock()
+		s.paused = ""
+		queued := s.queued
+		s.queued = nil
+		s.mu.Unlock()
+
+		for _, uploadID := range queued {
+			s.startQueued(uploadID)
+		}
+	}, nil
+}
+
+// startQueued starts an upload queued during a pause. A restore replaces the
+// database, so uploads that no longer exist or are no longer pending are
+// skipped.
+func (s *PipelineService) startQueued(uploadID uint) {
+	upload, err := s.store.Uploads.Get(uploadID)
+	if err != nil || upload.Status != "pending" {
+		s.log.Warn("Skipping queued upload", zap.Uint("upload_id", uploadID), zap.Error(err))
+		return
+	}
+	if err := s.Start(uploadID); err != nil {
+		s.log.Error("Failed to start queued upload", zap.Uint("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// Paused returns what the pipeline is paused for, empty when it is not
+func (s *PipelineService) Paused() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// release clears the processing mark for an upload
+func (s *PipelineService) release(uploadID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, uploadID)
+}
+
+// planStages validates the requested stages and expands them into the
+// stages to run
+func planStages(stages []string) ([]string, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("invalid stages: at least one stage is required")
+	}
+
+	first := len(PipelineStages)
+	analyze := false
+	for _, stage := range stages {
+		index := -1
+		for i, known := range PipelineStages {
+			if stage == known {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("invalid stage: %s", stage)
+		}
+		if stage == StageAnalyze {
+			analyze = true
+		} else if index < first {
+			first = index
+		}
+	}
+
+	var plan []string
+	for i, stage := range PipelineStages {
+		if stage == StageAnalyze {
+			if analyze {
+				plan = append(plan, stage)
+			}
+		} else if i >= first {
+			plan = append(plan, stage)
+		}
+	}
+	return plan, nil
+}
+
+// containsStage reports whether stage is in plan
+func containsStage(plan []string, stage string) bool {
+	for _, planned := range plan {
+		if planned == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadMessageDates returns the dates of the user messages in an upload
+func (s *PipelineService) uploadMessageDates(uploadID uint) ([]string, error) {
+	var messages []models.Message
+	if err := s.store.Messages.Query().Select("timestamp").
+		Where("role = ? AND conversation_id IN (?)", "user", s.store.Conversations.IDsForUpload(uploadID)).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get message dates: %w", err)
+	}
+
+	dates := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		dates = append(dates, msg.Timestamp.UTC().Format("2006-01-02"))
+	}
+	return mergeDates(nil, dates), nil
+}
+
+// mergeDates returns the sorted, de-duplicated union of two date lists,
+// normalising values to YYYY-MM-DD
+func mergeDates(a, b []string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, date := range append(append([]string{}, a...), b...) {
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		if date == "" || seen[date] {
+			continue
+		}
+		seen[date] = true
+		merged = append(merged, date)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// removePath removes a file or directory tree and returns how many files and
+// bytes it held. A missing path is not an error.
+func removePath(path string) (int, int64, error) {
+	var files int
+	var bytes int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files++
+			bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return 0, 0, err
+	}
+	return files, bytes, nil
+}
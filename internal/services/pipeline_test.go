@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chatgpt-autopsy-go/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// writeAnalysisFile writes a file into a date's analysis directory
+func writeAnalysisFile(t *testing.T, ts *testServices, date string) string {
+	t.Helper()
+	dir := filepath.Join(ts.cfg.Directories.AnalysisDir, date)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create analysis directory: %v", err)
+	}
+	path := filepath.Join(dir, "synthesis.md")
+	if err := os.WriteFile(path, []byte("# synthesis\n"), 0644); err != nil {
+		t.Fatalf("failed to write analysis file: %v", err)
+	}
+	return path
+}
+
+func TestPurgeInvalidatesSharedDates(t *testing.T) {
+	ts := newTestServices(t)
+	data := chatGPTExport(t, "conv-1", "hello", testDay)
+	purged := ts.importExport(t, data)
+	kept := ts.importExport(t, chatGPTExport(t, "conv-2", "goodbye", testDay))
+
+	// The date's analyses draw on both uploads
+	ts.fixtures.Analysis(testDate, "synthesis")
+	ts.fixtures.Analysis(testDate, "summary", func(a *models.Analysis) { a.UploadID = &purged.ID })
+	analysisFile := writeAnalysisFile(t, ts, testDate)
+
+	report, err := ts.pipeline.Purge(purged.ID)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if report.Conversations != 1 || report.Messages != 2 || report.Threads != 1 || report.Analyses != 2 {
+		t.Errorf("report = %+v, want 1 conversation, 2 messages, 1 thread, 2 analyses", report)
+	}
+	if len(report.InvalidatedDates) != 1 || report.InvalidatedDates[0] != testDate {
+		t.Errorf("invalidated dates = %v, want [%s]", report.InvalidatedDates, testDate)
+	}
+
+	if got := countRows(t, ts.store, &models.Upload{}, "id = ?", purged.ID); got != 0 {
+		t.Errorf("purged upload still has %d rows", got)
+	}
+	if got := countRows(t, ts.store, &models.Conversation{}, "upload_id = ?", purged.ID); got != 0 {
+		t.Errorf("purged upload still has %d conversations", got)
+	}
+	if got := countRows(t, ts.store, &models.Conversation{}, "upload_id = ?", kept.ID); got != 1 {
+		t.Errorf("other upload has %d conversations, want 1", got)
+	}
+	if got := countRows(t, ts.store, &models.Analysis{}, "date = ?", testDate); got != 0 {
+		t.Errorf("%d analyses left for the purged date", got)
+	}
+	if _, err := os.Stat(analysisFile); !os.IsNotExist(err) {
+		t.Errorf("analysis file survived the purge: %v", err)
+	}
+	if _, err := os.Stat(purged.StoredPath); !os.IsNotExist(err) {
+		t.Errorf("stored upload survived the purge: %v", err)
+	}
+
+	// The hash is free again
+	ts.upload(t, "export.zip", data)
+}
+
+func TestReprocessThreadsDropsDateAnalyses(t *testing.T) {
+	ts := newTestServices(t)
+	upload := ts.importExport(t, chatGPTExport(t, "conv-1", "hello", testDay))
+	other := ts.fixtures.Upload()
+	otherConversation := ts.fixtures.Conversation(other)
+	ts.fixtures.Thread(ts.fixtures.Message(otherConversation, "user", "elsewhere", testDay))
+
+	ts.fixtures.Analysis(testDate, "synthesis")
+	ts.fixtures.Analysis(testDate, "summary")
+	analysisFile := writeAnalysisFile(t, ts, testDate)
+
+	if err := ts.pipeline.acquire(upload.ID); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if err := ts.pipeline.run(upload.ID, []string{StageThread}); err != nil {
+		t.Fatalf("reprocess failed: %v", err)
+	}
+
+	if got := countRows(t, ts.store, &models.Analysis{}, "date = ?", testDate); got != 0 {
+		t.Errorf("%d stale analyses left for %s", got, testDate)
+	}
+	if _, err := os.Stat(analysisFile); !os.IsNotExist(err) {
+		t.Errorf("analysis file survived the reprocess: %v", err)
+	}
+	if got := countRows(t, ts.store, &models.Thread{}, "date = ?", testDate); got != 2 {
+		t.Errorf("threads on %s = %d, want 2", testDate, got)
+	}
+}
+
+func TestUploadsDuringPauseRunOnResume(t *testing.T) {
+	ts := newTestServices(t)
+	ts.cfg.Upload.InboxDir = t.TempDir()
+	inbox := NewInboxService(ts.cfg, zap.NewNop(), ts.uploads, ts.pipeline)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts.cfg.Upload.InboxPollInterval = time.Hour // polled by hand below
+	if err := inbox.Start(ctx); err != nil {
+		t.Fatalf("inbox Start failed: %v", err)
+	}
+
+	resume, err := ts.pipeline.Pause("a backup is being created")
+	if err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+
+	// An upload that slipped in as the pause began is queued, not dropped
+	upload := ts.upload(t, "export.zip", chatGPTExport(t, "conv-1", "hello", testDay))
+	if err := ts.pipeline.Start(upload.ID); err != nil {
+		t.Fatalf("Start while paused failed: %v", err)
+	}
+
+	// Inbox files stay in the inbox until the pause ends
+	inboxFile := filepath.Join(ts.cfg.Upload.InboxDir, "inbox.zip")
+	if err := os.WriteFile(inboxFile, chatGPTExport(t, "conv-2", "inbox", testDay), 0644); err != nil {
+		t.Fatalf("failed to write inbox file: %v", err)
+	}
+	inbox.poll()
+	inbox.poll()
+	if _, err := os.Stat(inboxFile); err != nil {
+		t.Fatalf("inbox file moved while paused: %v", err)
+	}
+	if got, err := ts.store.Uploads.Get(upload.ID); err != nil || got.Status != "pending" {
+		t.Fatalf("upload during pause = %v, %v; want pending", got, err)
+	}
+
+	resume()
+	waitForStatus(t, ts, upload.ID, "completed")
+
+	inbox.poll()
+	inbox.poll()
+	if _, err := os.Stat(inboxFile); !os.IsNotExist(err) {
+		t.Fatalf("inbox file not imported after resume: %v", err)
+	}
+	imported, err := ts.store.Uploads.First("original_filename = ?", "inbox.zip")
+	if err != nil {
+		t.Fatalf("inbox upload missing: %v", err)
+	}
+	waitForStatus(t, ts, imported.ID, "completed")
+}
+
+// waitForStatus waits for a background pipeline run to leave an upload in
+// the given status
+func waitForStatus(t *testing.T, ts *testServices, uploadID uint, status string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		upload, err := ts.store.Uploads.Get(uploadID)
+		if err == nil && upload.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("upload %d did not reach %s: %v, %v", uploadID, status, upload, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
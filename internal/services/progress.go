@@ -0,0 +1,239 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// Import pipeline stages
+const (
+	StageQueued  = "queued"
+	StageExtract = "extract"
+	StageParse   = "parse"
+	StageThread  = "thread"
+	StageAnalyze = "analyze"
+	StageDone    = "done"
+)
+
+// importEventBuffer is the number of events buffered per watcher
+const importEventBuffer = 32
+
+// ImportProgressService saves import progress and fans it out to watchers
+type ImportProgressService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+
+	mu       sync.Mutex
+	watchers map[uint]map[chan ImportEvent]struct{} // upload ID -> watchers
+}
+
+// NewImportProgressService creates a new import progress service
+func NewImportProgressService(cfg *config.Config, log *zap.Logger, store *repository.Store) *ImportProgressService {
+	return &ImportProgressService{
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		watchers: make(map[uint]map[chan ImportEvent]struct{}),
+	}
+}
+
+// ImportEvent is a snapshot of an import pushed to watchers
+type ImportEvent struct {
+	ImportID        uint           `json:"import_id"`
+	UploadID        uint           `json:"upload_id"`
+	Status          string         `json:"status"`
+	Stage           string         `json:"stage"`
+	ProgressPercent int            `json:"progress_percent"`
+	Counts          map[string]int `json:"counts"`
+	Error           *string        `json:"error,omitempty"`
+	StartedAt       time.Time      `json:"started_at"`
+	CompletedAt     *time.Time     `json:"completed_at,omitempty"`
+	Timestamp       time.Time      `json:"timestamp"`
+}
+
+// Terminal reports whether the import has finished, successfully or not
+func (e ImportEvent) Terminal() bool {
+	return e.Status == "completed" || e.Status == "failed"
+}
+
+// NewImportEvent builds an event from an import record
+func NewImportEvent(importRecord *models.Import) ImportEvent {
+	return ImportEvent{
+		ImportID:        importRecord.ID,
+		UploadID:        importRecord.UploadID,
+		Status:          importRecord.Status,
+		Stage:           importRecord.Stage,
+		ProgressPercent: importRecord.ProgressPercent,
+		Counts:          importCounts(importRecord.Stats),
+		Error:           importRecord.ErrorMessage,
+		StartedAt:       importRecord.StartedAt,
+		CompletedAt:     importRecord.CompletedAt,
+		Timestamp:       time.Now().UTC(),
+	}
+}
+
+// GetImport returns the import record for an upload
+func (s *ImportProgressService) GetImport(uploadID uint) (*models.Import, error) {
+	importRecord, err := s.store.Imports.ForUpload(uploadID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("import not found")
+		}
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	return importRecord, nil
+}
+
+// Save persists an import record and notifies its watchers
+func (s *ImportProgressService) Save(importRecord *models.Import) error {
+	if err := s.store.Imports.Save(importRecord); err != nil {
+		return err
+	}
+	s.publish(NewImportEvent(importRecord))
+	return nil
+}
+
+// SetCount sets one of the counts kept in the import stats
+func (s *ImportProgressService) SetCount(importRecord *models.Import, key string, value int) {
+	stats := make(map[string]interface{})
+	if importRecord.Stats != "" {
+		if err := json.Unmarshal([]byte(importRecord.Stats), &stats); err != nil {
+			s.log.Warn("Failed to parse import stats", zap.Uint("import_id", importRecord.ID), zap.Error(err))
+		}
+	}
+	stats[key] = value
+	statsJSON, _ := json.Marshal(stats)
+	importRecord.Stats = string(statsJSON)
+}
+
+// Restart resets an upload's import before the pipeline runs again
+func (s *ImportProgressService) Restart(uploadID uint) error {
+	importRecord, err := s.GetImport(uploadID)
+	if err != nil {
+		return err
+	}
+
+	importRecord.Status = "pending"
+	importRecord.Stage = StageQueued
+	importRecord.ProgressPercent = 0
+	importRecord.ErrorMessage = nil
+	importRecord.StartedAt = time.Now().UTC()
+	importRecord.CompletedAt = nil
+	if err := s.Save(importRecord); err != nil {
+		return fmt.Errorf("failed to reset import: %w", err)
+	}
+	return nil
+}
+
+// Complete marks an upload's import as completed
+func (s *ImportProgressService) Complete(uploadID uint) error {
+	importRecord, err := s.GetImport(uploadID)
+	if err != nil {
+		return err
+	}
+
+	completedAt := time.Now().UTC()
+	importRecord.Status = "completed"
+	importRecord.Stage = StageDone
+	importRecord.ProgressPercent = 100
+	importRecord.CompletedAt = &completedAt
+	if err := s.Save(importRecord); err != nil {
+		return fmt.Errorf("failed to complete import: %w", err)
+	}
+	return nil
+}
+
+// Fail marks an upload's import as failed unless it already failed
+func (s *ImportProgressService) Fail(uploadID uint, err error) {
+	importRecord, getErr := s.GetImport(uploadID)
+	if getErr != nil {
+		s.log.Warn("Failed to load import to mark it failed", zap.Uint("upload_id", uploadID), zap.Error(getErr))
+		return
+	}
+	if importRecord.Status == "failed" {
+		return
+	}
+
+	errorMsg := err.Error()
+	importRecord.Status = "failed"
+	importRecord.ErrorMessage = &errorMsg
+	if saveErr := s.Save(importRecord); saveErr != nil {
+		s.log.Warn("Failed to mark import failed", zap.Uint("upload_id", uploadID), zap.Error(saveErr))
+	}
+}
+
+// Watch subscribes to events for an upload. The returned function must be
+// called to unsubscribe.
+func (s *ImportProgressService) Watch(uploadID uint) (<-chan ImportEvent, func()) {
+	ch := make(chan ImportEvent, importEventBuffer)
+
+	s.mu.Lock()
+	if s.watchers[uploadID] == nil {
+		s.watchers[uploadID] = make(map[chan ImportEvent]struct{})
+	}
+	s.watchers[uploadID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.watchers[uploadID][ch]; !ok {
+			return
+		}
+		delete(s.watchers[uploadID], ch)
+		if len(s.watchers[uploadID]) == 0 {
+			delete(s.watchers, uploadID)
+		}
+		close(ch)
+	}
+}
+
+// publish sends an event to every watcher of the upload. Slow watchers lose
+// their oldest buffered event rather than blocking the pipeline.
+func (s *ImportProgressService) publish(event ImportEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.watchers[event.UploadID] {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// importCounts extracts the numeric counts from import stats JSON
+func importCounts(statsJSON string) map[string]int {
+	counts := make(map[string]int)
+	if statsJSON == "" {
+		return counts
+	}
+
+	var stats map[string]interface{}
+	if err := json.Unmarshal([]byte(statsJSON), &stats); err != nil {
+		return counts
+	}
+	for key, value := range stats {
+		if n, ok := value.(float64); ok {
+			counts[key] = int(n)
+		}
+	}
+	return counts
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// PromptService manages versioned prompt templates for AI-enhanced analysis
+type PromptService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+}
+
+// NewPromptService creates a new prompt service
+func NewPromptService(cfg *config.Config, log *zap.Logger, store *repository.Store) *PromptService {
+	return &PromptService{
+		cfg:   cfg,
+		log:   log,
+		store: store,
+	}
+}
+
+// PromptData holds the variables available to prompt templates
+type PromptData struct {
+	Date          string
+	Name          string
+	MessageCount  int
+	ThreadCount   int
+	Messages      []PromptMessage
+	PriorFindings map[string]string // analysis type -> findings produced earlier for the date
+	Chunk         int               // 1-based index of the message chunk being analyzed
+	ChunkCount    int               // number of chunks the day's messages were split into
+	ChunkFindings []string          // per-chunk findings, set when rendering the reduce template
+}
+
+// PromptMessage is a message excerpt exposed to prompt templates
+type PromptMessage struct {
+	Role    string
+	Time    string
+	Content string
+}
+
+// Excerpts renders the message excerpts as a plain-text transcript
+func (d PromptData) Excerpts() string {
+	var b strings.Builder
+	for _, msg := range d.Messages {
+		b.WriteString(fmt.Sprintf("[%s] %s: %s\n\n", msg.Time, msg.Role, msg.Content))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// PromptPreview is a rendered prompt returned by a dry run
+type PromptPreview struct {
+	Name             string   `json:"name"`
+	PromptTemplateID uint     `json:"prompt_template_id"`
+	PromptVersion    int      `json:"prompt_version"`
+	Prompt           string   `json:"prompt"`
+	EstimatedTokens  int      `json:"estimated_tokens"`
+	ChunkCount       int      `json:"chunk_count"`
+	ChunkPrompts     []string `json:"chunk_prompts,omitempty"` // map-stage prompts when the day is chunked
+}
+
+// chunkReducePrompt is the template that merges per-chunk findings
+const chunkReducePrompt = "chunk_reduce"
+
+// analysisPromptNames returns the templates that produce an analysis
+func analysisPromptNames() []string {
+	names := append([]string{}, AnalysisDimensions...)
+	return append(names, "synthesis", "summary")
+}
+
+// PromptNames returns the template names used by the analysis pipeline
+func PromptNames() []string {
+	return append(analysisPromptNames(), chunkReducePrompt)
+}
+
+// isPromptName reports whether name is a known template name
+func isPromptName(name string) bool {
+	for _, known := range PromptNames() {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedDefaults creates version 1 of every template that has no versions yet
+func (s *PromptService) SeedDefaults() error {
+	for _, name := range PromptNames() {
+		count, err := s.store.PromptTemplates.Count("name = ?", name)
+		if err != nil {
+			return fmt.Errorf("failed to count prompt templates: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		description := "Built-in default template"
+		tmpl := models.PromptTemplate{
+			Name:        name,
+			Version:     1,
+			Body:        defaultPromptBody(name),
+			Description: &description,
+			IsActive:    true,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if err := s.store.PromptTemplates.Create(&tmpl); err != nil {
+			return fmt.Errorf("failed to seed prompt template %s: %w", name, err)
+		}
+		s.log.Info("Seeded default prompt template", zap.String("name", name))
+	}
+	return nil
+}
+
+// ListTemplates lists prompt templates, optionally filtered by name
+func (s *PromptService) ListTemplates(name string) ([]models.PromptTemplate, error) {
+	var templates []models.PromptTemplate
+	query := s.store.PromptTemplates.Query().Order("name ASC, version DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves a prompt template by ID
+func (s *PromptService) GetTemplate(id uint) (*models.PromptTemplate, error) {
+	tmpl, err := s.store.PromptTemplates.Get(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("prompt template not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// GetActiveTemplate retrieves the active version of a named template
+func (s *PromptService) GetActiveTemplate(name string) (*models.PromptTemplate, error) {
+	tmpl, err := s.store.PromptTemplates.First("name = ? AND is_active = ?", name, true)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("active prompt template not found: %s", name)
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// CreateVersion stores body as the next version of the named template and
+// activates it when activate is true
+func (s *PromptService) CreateVersion(name, body string, description *string, activate bool) (*models.PromptTemplate, error) {
+	if !isPromptName(name) {
+		return nil, fmt.Errorf("invalid prompt template name: %s", name)
+	}
+	if _, err := template.New(name).Parse(body); err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var tmpl models.PromptTemplate
+	err := s.store.Transaction(func(tx *repository.Store) error {
+		var latest int
+		if err := tx.PromptTemplates.Query().
+			Where("name = ?", name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to get latest version: %w", err)
+		}
+
+		if activate {
+			if err := tx.PromptTemplates.Query().
+				Where("name = ?", name).
+				Update("is_active", false).Error; err != nil {
+				return fmt.Errorf("failed to deactivate previous versions: %w", err)
+			}
+		}
+
+		tmpl = models.PromptTemplate{
+			Name:        name,
+			Version:     latest + 1,
+			Body:        body,
+			Description: description,
+			IsActive:    activate || latest == 0,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if err := tx.PromptTemplates.Create(&tmpl); err != nil {
+			return fmt.Errorf("failed to create prompt template: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Prompt template version created",
+		zap.String("name", tmpl.Name),
+		zap.Int("version", tmpl.Version),
+		zap.Bool("active", tmpl.IsActive),
+	)
+	return &tmpl, nil
+}
+
+// UpdateTemplate creates a new version derived from an existing template.
+// Published versions are immutable so analyses keep pointing at the exact
+// prompt that produced them.
+func (s *PromptService) UpdateTemplate(id uint, body string, description *string, activate bool) (*models.PromptTemplate, error) {
+	existing, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateVersion(existing.Name, body, description, activate)
+}
+
+// ActivateTemplate makes the given version the active one for its name
+func (s *PromptService) ActivateTemplate(id uint) (*models.PromptTemplate, error) {
+	tmpl, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.Transaction(func(tx *repository.Store) error {
+		if err := tx.PromptTemplates.Query().
+			Where("name = ?", tmpl.Name).
+			Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate previous versions: %w", err)
+		}
+		updatedAt := time.Now().UTC()
+		tmpl.IsActive = true
+		tmpl.UpdatedAt = &updatedAt
+		if err := tx.PromptTemplates.Save(tmpl); err != nil {
+			return fmt.Errorf("failed to activate prompt template: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// DeleteTemplate deletes a template version. Deleting the active version
+// activates the latest remaining one; the last version cannot be deleted.
+func (s *PromptService) DeleteTemplate(id uint) error {
+	tmpl, err := s.GetTemplate(id)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Transaction(func(tx *repository.Store) error {
+		var remaining models.PromptTemplate
+		err := tx.PromptTemplates.Query().Where("name = ? AND id <> ?", tmpl.Name, tmpl.ID).Order("version DESC").First(&remaining).Error
+		if err == repository.ErrNotFound {
+			return fmt.Errorf("cannot delete the only version of prompt template %s", tmpl.Name)
+		} else if err != nil {
+			return fmt.Errorf("failed to find remaining versions: %w", err)
+		}
+
+		if err := tx.PromptTemplates.Delete(tmpl); err != nil {
+			return fmt.Errorf("failed to delete prompt template: %w", err)
+		}
+
+		if tmpl.IsActive {
+			if err := tx.PromptTemplates.Query().Where("id = ?", remaining.ID).Update("is_active", true).Error; err != nil {
+				return fmt.Errorf("failed to activate remaining version: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Render executes a template against the given data
+func (s *PromptService) Render(tmpl *models.PromptTemplate, data PromptData) (string, error) {
+	parsed, err := template.New(tmpl.Name).Option("missingkey=zero").Parse(tmpl.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template %s v%d: %w", tmpl.Name, tmpl.Version, err)
+	}
+
+	var out bytes.Buffer
+	if err := parsed.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s v%d: %w", tmpl.Name, tmpl.Version, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// dimensionGuidance describes what each analysis dimension looks for
+var dimensionGuidance = map[string]string{
+	"meaning":             "Identify the core themes and underlying messages.",
+	"signals":             "Identify behavioral patterns and communication styles.",
+	"shadows":             "Identify unconscious patterns and potential blind spots.",
+	"lies":                "Identify self-deceptions and rationalizations.",
+	"truths":              "Identify authentic expressions and validated experiences.",
+	"questionable_truths": "Identify beliefs that require further examination.",
+	"actionable_items":    "Extract concrete next steps for growth and optimization.",
+	"doubts":              "Document uncertainties and unresolved questions.",
+	"topics_of_interest":  "Identify recurring themes and areas of passion.",
+}
+
+// defaultPromptBody returns the built-in template for a name
+func defaultPromptBody(name string) string {
+	switch name {
+	case "synthesis":
+		return `You are reviewing one day ({{.Date}}) of a person's ChatGPT conversations.
+Combine the findings below into an integrated synthesis. Highlight connections
+and tensions between dimensions. Respond in Markdown.
+
+{{range $dimension, $findings := .PriorFindings}}## {{$dimension}}
+{{$findings}}
+
+{{end}}`
+	case chunkReducePrompt:
+		return `The messages from {{.Date}} were analyzed in {{.ChunkCount}} parts for
+the "{{.Name}}" dimension. Merge the partial findings below into a single
+answer. Remove duplicates, keep the most specific points and preserve quotes.
+Respond in Markdown.
+
+{{range .ChunkFindings}}---
+{{.}}
+
+{{end}}`
+	case "summary":
+		return `Write a short summary of the key insights from {{.Date}}
+({{.MessageCount}} messages across {{.ThreadCount}} conversations).
+Use at most five bullet points in Markdown.
+
+{{range $dimension, $findings := .PriorFindings}}## {{$dimension}}
+{{$findings}}
+
+{{end}}`
+	default:
+		return `You are analyzing one day ({{.Date}}) of a person's ChatGPT conversations
+for the "{{.Name}}" dimension. ` + dimensionGuidance[name] + `
+Focus on the user's own messages. Respond in Markdown with short,
+specific findings that quote the messages where useful.
+{{if gt .ChunkCount 1}}
+This is part {{.Chunk}} of {{.ChunkCount}} of the day's messages; report
+findings for this part only.
+{{end}}
+Messages ({{.MessageCount}} total across {{.ThreadCount}} conversations):
+
+{{.Excerpts}}`
+	}
+}
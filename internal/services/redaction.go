@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chatgpt-autopsy-go/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// RedactionService redacts sensitive values from content before it is sent
+// to an AI provider
+type RedactionService struct {
+	cfg       *config.Config
+	log       *zap.Logger
+	detectors []detector
+	key       []byte // per-install HMAC key, set by LoadKey
+}
+
+// redactionKeySize is the length in bytes of the placeholder HMAC key
+const redactionKeySize = 32
+
+// NewRedactionService creates a new redaction service
+func NewRedactionService(cfg *config.Config, log *zap.Logger) *RedactionService {
+	var detectors []detector
+	if cfg.Redaction.Enabled {
+		detectors = newDetectors(cfg.Redaction.Categories, cfg.Redaction.EntropyThreshold, cfg.Redaction.MinSecretLength)
+	}
+	return &RedactionService{
+		cfg:       cfg,
+		log:       log,
+		detectors: detectors,
+	}
+}
+
+// LoadKey reads the per-install placeholder key, creating it on first use.
+// The key never leaves the machine, so providers cannot recover redacted
+// values by hashing guesses against the placeholders they receive.
+func (s *RedactionService) LoadKey() error {
+	path := s.cfg.Redaction.KeyPath
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < redactionKeySize {
+			return fmt.Errorf("invalid redaction key in %s", path)
+		}
+		s.key = key
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read redaction key: %w", err)
+	}
+
+	key := make([]byte, redactionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate redaction key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create redaction key directory: %w", err)
+	}
+	// O_EXCL so two processes starting together cannot overwrite each other's key
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return s.LoadKey()
+		}
+		return fmt.Errorf("failed to create redaction key: %w", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write redaction key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write redaction key: %w", err)
+	}
+
+	s.key = key
+	s.log.Info("Created redaction key", zap.String("path", path))
+	return nil
+}
+
+// Redactor pseudonymises sensitive values and restores them in AI output.
+// Placeholders are an HMAC of the value under the per-install key, so the
+// same value gets the same placeholder in every prompt and cached
+// completions stay valid, while the provider cannot brute-force them.
+type Redactor struct {
+	detectors []detector
+	key       []byte
+	assigned  map[string]string // category + value -> placeholder
+	originals map[string]string // placeholder -> original value
+	stats     map[string]int    // category -> values redacted
+}
+
+// RedactionPreview shows exactly what would be sent for a prompt
+type RedactionPreview struct {
+	Name    string         `json:"name"`
+	Prompts []string       `json:"prompts"` // one per provider call, after redaction
+	Stats   map[string]int `json:"stats"`
+}
+
+// NewRedactor returns a redactor for one analysis
+func (s *RedactionService) NewRedactor() *Redactor {
+	return &Redactor{
+		detectors: s.detectors,
+		key:       s.key,
+		assigned:  make(map[string]string),
+		originals: make(map[string]string),
+		stats:     make(map[string]int),
+	}
+}
+
+// Preview redacts text and returns what would be sent with the stats
+func (s *RedactionService) Preview(text string) (string, map[string]int) {
+	redactor := s.NewRedactor()
+	redacted := redactor.Redact(text)
+	return redacted, redactor.Stats()
+}
+
+// Redact replaces sensitive values in text with placeholders. Every detector
+// runs over the original text and the matches are replaced in one pass, so a
+// placeholder is never itself mistaken for a secret.
+func (r *Redactor) Redact(text string) string {
+	matches := findSensitive(r.detectors, text)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var redacted strings.Builder
+	last := 0
+	for _, m := range matches {
+		value := text[m.start:m.end]
+		placeholder := r.placeholder(m.category, value)
+		r.originals[placeholder] = value
+		r.stats[m.category]++
+
+		redacted.WriteString(text[last:m.start])
+		redacted.WriteString(placeholder)
+		last = m.end
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String()
+}
+
+// Restore replaces placeholders in text with the original values
+func (r *Redactor) Restore(text string) string {
+	if len(r.originals) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(r.originals)*2)
+	for placeholder, original := range r.originals {
+		pairs = append(pairs, placeholder, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Stats returns how many values were redacted per category
+func (r *Redactor) Stats() map[string]int {
+	stats := make(map[string]int, len(r.stats))
+	for category, count := range r.stats {
+		stats[category] = count
+	}
+	return stats
+}
+
+// Total returns the number of values redacted
+func (r *Redactor) Total() int {
+	total := 0
+	for _, count := range r.stats {
+		total += count
+	}
+	return total
+}
+
+// placeholder returns the placeholder for a value. Without a key it falls
+// back to sequential tokens, which are only stable within this redactor.
+func (r *Redactor) placeholder(category, value string) string {
+	id := category + "\x00" + value
+	if placeholder, ok := r.assigned[id]; ok {
+		return placeholder
+	}
+
+	var placeholder string
+	if r.key == nil {
+		placeholder = fmt.Sprintf("[%s_%d]", strings.ToUpper(category), len(r.assigned)+1)
+	} else {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(id))
+		placeholder = fmt.Sprintf("[%s_%s]", strings.ToUpper(category), hex.EncodeToString(mac.Sum(nil)[:6]))
+	}
+	r.assigned[id] = placeholder
+	return placeholder
+}
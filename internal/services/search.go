@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// SearchService searches message content, using full-text search on
+// PostgreSQL and word matching on SQLite
+type SearchService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(cfg *config.Config, log *zap.Logger, store *repository.Store) *SearchService {
+	return &SearchService{
+		cfg:   cfg,
+		log:   log,
+		store: store,
+	}
+}
+
+// SearchFilter narrows a message search
+type SearchFilter struct {
+	Query          string
+	UploadID       uint
+	ConversationID uint
+	Role           string
+}
+
+// SearchMessages lists the messages containing every word of the query,
+// newest first
+func (s *SearchService) SearchMessages(filter SearchFilter, page, limit int) ([]models.Message, int64, error) {
+	text := strings.TrimSpace(filter.Query)
+	if text == "" {
+		return nil, 0, fmt.Errorf("search query is required")
+	}
+
+	query := s.store.Messages.Search(text)
+	if filter.UploadID != 0 {
+		query = query.Where("conversation_id IN (?)", s.store.Conversations.IDsForUpload(filter.UploadID))
+	}
+	if filter.ConversationID != 0 {
+		query = query.Where("conversation_id = ?", filter.ConversationID)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	var messages []models.Message
+	total, err := paginate(query, "timestamp DESC, id DESC", page, limit, &messages)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return messages, total, nil
+}
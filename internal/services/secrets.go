@@ -0,0 +1,190 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SecretScanService scans the message archive for leaked credentials
+type SecretScanService struct {
+	cfg       *config.Config
+	log       *zap.Logger
+	store     *repository.Store
+	detectors []detector
+}
+
+// NewSecretScanService creates a new secret scan service
+func NewSecretScanService(cfg *config.Config, log *zap.Logger, store *repository.Store) *SecretScanService {
+	return &SecretScanService{
+		cfg:       cfg,
+		log:       log,
+		store:     store,
+		detectors: newDetectors(SecretCategories, cfg.Redaction.EntropyThreshold, cfg.Redaction.MinSecretLength),
+	}
+}
+
+// SecretScanResult summarises a scan
+type SecretScanResult struct {
+	MessagesScanned int            `json:"messages_scanned"`
+	FindingsCreated int            `json:"findings_created"`
+	ByType          map[string]int `json:"by_type"`
+	Duration        string         `json:"duration"`
+}
+
+// SecretFindingFilter narrows a findings listing
+type SecretFindingFilter struct {
+	SecretType     string
+	Date           string
+	ConversationID uint
+}
+
+// scanBatchSize is the number of messages loaded per scan batch
+const scanBatchSize = 500
+
+// ScanAll scans every message for secrets. Findings are keyed by message and
+// secret fingerprint, so re-running a scan only adds new findings.
+func (s *SecretScanService) ScanAll() (*SecretScanResult, error) {
+	return s.scan(s.store.Messages.Query())
+}
+
+// ScanUpload scans the messages belonging to one upload
+func (s *SecretScanService) ScanUpload(uploadID uint) (*SecretScanResult, error) {
+	return s.scan(s.store.Messages.Query().
+		Where("conversation_id IN (?)", s.store.Conversations.IDsForUpload(uploadID)))
+}
+
+// scan runs the detectors over the messages matched by query
+func (s *SecretScanService) scan(query *gorm.DB) (*SecretScanResult, error) {
+	start := time.Now()
+	result := &SecretScanResult{}
+
+	var batch []models.Message
+	err := query.Order("id ASC").FindInBatches(&batch, scanBatchSize, func(tx *gorm.DB, _ int) error {
+		var findings []models.SecretFinding
+		for _, msg := range batch {
+			findings = append(findings, s.scanMessage(msg)...)
+		}
+		result.MessagesScanned += len(batch)
+
+		if len(findings) == 0 {
+			return nil
+		}
+
+		created := s.store.SecretFindings.Query().Clauses(clause.OnConflict{DoNothing: true}).Create(&findings)
+		if created.Error != nil {
+			return fmt.Errorf("failed to save secret findings: %w", created.Error)
+		}
+		result.FindingsCreated += int(created.RowsAffected)
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan messages: %w", err)
+	}
+
+	byType, err := s.Summary()
+	if err != nil {
+		return nil, err
+	}
+	result.ByType = byType
+
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	s.log.Info("Secret scan completed",
+		zap.Int("messages_scanned", result.MessagesScanned),
+		zap.Int("findings_created", result.FindingsCreated),
+	)
+	return result, nil
+}
+
+// scanMessage returns the findings for a single message
+func (s *SecretScanService) scanMessage(msg models.Message) []models.SecretFinding {
+	var findings []models.SecretFinding
+	seen := make(map[string]bool)
+	content := msg.Content
+
+	for _, d := range s.detectors {
+		content = d.pattern.ReplaceAllStringFunc(content, func(match string) string {
+			if d.validate != nil && !d.validate(match) {
+				return match
+			}
+
+			sum := sha256.Sum256([]byte(secretValue(d.category, match)))
+			fingerprint := hex.EncodeToString(sum[:])
+			if !seen[fingerprint] {
+				seen[fingerprint] = true
+				findings = append(findings, models.SecretFinding{
+					ConversationID: msg.ConversationID,
+					MessageID:      msg.ID,
+					Date:           msg.Timestamp.UTC().Format("2006-01-02"),
+					Role:           msg.Role,
+					SecretType:     d.category,
+					MaskedPreview:  maskSecret(d.category, match),
+					Fingerprint:    fingerprint,
+					DetectedAt:     time.Now().UTC(),
+				})
+			}
+
+			// Blank out the match so lower-priority detectors don't report it again
+			return " "
+		})
+	}
+
+	return findings
+}
+
+// ListFindings lists secret findings with pagination
+func (s *SecretScanService) ListFindings(filter SecretFindingFilter, page, limit int) ([]models.SecretFinding, int64, error) {
+	var findings []models.SecretFinding
+	var total int64
+
+	query := s.store.SecretFindings.Query()
+	if filter.SecretType != "" {
+		query = query.Where("secret_type = ?", filter.SecretType)
+	}
+	if filter.Date != "" {
+		query = query.Where("date = ?", filter.Date)
+	}
+	if filter.ConversationID != 0 {
+		query = query.Where("conversation_id = ?", filter.ConversationID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count secret findings: %w", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("date DESC, id DESC").Offset(offset).Limit(limit).Find(&findings).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list secret findings: %w", err)
+	}
+
+	return findings, total, nil
+}
+
+// Summary returns the number of findings per secret type
+func (s *SecretScanService) Summary() (map[string]int, error) {
+	var counts []struct {
+		SecretType string
+		Count      int
+	}
+	if err := s.store.SecretFindings.Query().
+		Select("secret_type, COUNT(*) AS count").
+		Group("secret_type").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count secret findings: %w", err)
+	}
+
+	summary := make(map[string]int, len(counts))
+	for _, count := range counts {
+		summary[count.SecretType] = count.Count
+	}
+	return summary, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+	"chatgpt-autopsy-go/internal/repository/repotest"
+
+	"go.uber.org/zap"
+)
+
+// testServices wires the import services to a fresh store, the way
+// cmd/server does without the AI-backed analysis
+type testServices struct {
+	cfg        *config.Config
+	store      *repository.Store
+	fixtures   *repotest.Fixtures
+	uploads    *UploadService
+	extraction *ExtractionService
+	parser     *ParserService
+	pipeline   *PipelineService
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	cfg := repotest.Config(t)
+	store := repotest.NewStore(t)
+	log := zap.NewNop()
+
+	progress := NewImportProgressService(cfg, log, store)
+	extraction := NewExtractionService(cfg, log, store, progress)
+	parser := NewParserService(cfg, log, store, progress, extraction)
+	thread := NewThreadService(cfg, log, store, progress)
+	secrets := NewSecretScanService(cfg, log, store)
+	media := NewMediaService(cfg, log, store, extraction)
+
+	return &testServices{
+		cfg:        cfg,
+		store:      store,
+		fixtures:   repotest.NewFixtures(t, store),
+		uploads:    NewUploadService(cfg, log, store),
+		extraction: extraction,
+		parser:     parser,
+		pipeline:   NewPipelineService(cfg, log, store, extraction, parser, thread, nil, secrets, media, progress),
+	}
+}
+
+// upload stores data as a new upload
+func (ts *testServices) upload(t *testing.T, name string, data []byte) *models.Upload {
+	t.Helper()
+	upload, err := ts.uploads.UploadFile(name, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	return upload
+}
+
+// importExport uploads data and runs the import pipeline on it
+func (ts *testServices) importExport(t *testing.T, data []byte) *models.Upload {
+	t.Helper()
+	upload := ts.upload(t, "export.zip", data)
+	if err := ts.pipeline.Import(upload.ID); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	return upload
+}
+
+// zipEntry is one file of a test archive
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+// zipArchive builds a ZIP archive of the entries, in order
+func zipArchive(t *testing.T, entries ...zipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		f, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", entry.name, err)
+		}
+		if _, err := f.Write(entry.data); err != nil {
+			t.Fatalf("failed to write %s: %v", entry.name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// chatGPTExport builds a ChatGPT export with one conversation of a user
+// message and its reply at the given time
+func chatGPTExport(t *testing.T, conversationID, question string, at time.Time) []byte {
+	t.Helper()
+	created := float64(at.Unix())
+	node := func(id, role, text string, parent interface{}, children ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":       id,
+			"parent":   parent,
+			"children": children,
+			"message": map[string]interface{}{
+				"id":          id,
+				"author":      map[string]interface{}{"role": role},
+				"content":     map[string]interface{}{"content_type": "text", "parts": []string{text}},
+				"status":      "finished_successfully",
+				"create_time": created,
+			},
+		}
+	}
+	userID, replyID := conversationID+"-user", conversationID+"-reply"
+	data, err := json.Marshal([]map[string]interface{}{{
+		"id":              conversationID,
+		"conversation_id": conversationID,
+		"title":           "Conversation " + conversationID,
+		"create_time":     created,
+		"update_time":     created,
+		"mapping": map[string]interface{}{
+			userID:  node(userID, "user", question, nil, replyID),
+			replyID: node(replyID, "assistant", "Answer to "+question, userID),
+		},
+	}})
+	if err != nil {
+		t.Fatalf("failed to encode export: %v", err)
+	}
+	return zipArchive(t, zipEntry{"conversations.json", data})
+}
+
+// countRows returns the number of rows of model matching the condition
+func countRows(t *testing.T, store *repository.Store, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	db := store.DB().Model(model)
+	if query != "" {
+		db = db.Where(query, args...)
+	}
+	if err := db.Count(&n).Error; err != nil {
+		t.Fatalf("failed to count %T: %v", model, err)
+	}
+	return n
+}
+
+// testDay is the time the test conversations are held on
+var testDay = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testDate is testDay as stored on threads and analyses
+var testDate = testDay.Format("2006-01-02")
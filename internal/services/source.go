@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SourceImporter reads the conversation files of one chat export format
+type SourceImporter interface {
+	// Source names the format; it is recorded on every conversation
+	Source() string
+	// Detect reports whether a conversation file is in this format
+	Detect(sample *SourceSample) bool
+	// Decode streams the conversations in r to fn, stopping at fn's first error
+	Decode(r io.Reader, fn func(conv *ImportedConversation) error) error
+}
+
+// SourceSample is what source detection sees of a conversation file
+type SourceSample struct {
+	Name  string          // path of the file in the upload
+	Lines bool            // newline-delimited JSON objects rather than an array
+	Keys  map[string]bool // top-level keys of the first record
+}
+
+// ImportedConversation is a conversation decoded from any source
+type ImportedConversation struct {
+	ConversationID string // unique within an upload, used to skip duplicates
+	ExportID       string // the source's own conversation ID, if it has one
+	Title          string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Messages       []ImportedMessage // in display order
+}
+
+// ImportedMessage is one message of an imported conversation
+type ImportedMessage struct {
+	ID        string // the source's message ID, if it has one
+	Role      string // user, assistant, system, tool
+	Content   string
+	Timestamp time.Time // zero if unknown
+	Metadata  map[string]interface{}
+	Assets    []ImportedAsset
+}
+
+// ImportedAsset is a file in the export that a message refers to
+type ImportedAsset struct {
+	AssetID     string
+	PointerType string
+}
+
+// defaultSourceImporters returns the importers tried, in order, on every
+// conversation file
+func defaultSourceImporters() []SourceImporter {
+	return []SourceImporter{
+		chatGPTImporter{},
+		claudeImporter{},
+		geminiImporter{},
+		jsonlImporter{},
+	}
+}
+
+// sourceSampleSize is how much of a file source detection may look at
+const sourceSampleSize = 64 << 10
+
+// sampleSource peeks at the first record of a conversation file and collects
+// its top-level keys. Keys before a value too large for the sample are still
+// seen, which is enough to tell the formats apart.
+func sampleSource(r *bufio.Reader, name string) *SourceSample {
+	sample := &SourceSample{Name: name, Keys: make(map[string]bool)}
+
+	head, _ := r.Peek(sourceSampleSize)
+	head = bytes.TrimLeft(head, " \t\r\n")
+	decoder := json.NewDecoder(bytes.NewReader(head))
+	if len(head) > 0 && head[0] == '{' {
+		sample.Lines = true
+	} else if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return sample
+	}
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return sample
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		key, ok := token.(string)
+		if err != nil || !ok {
+			break
+		}
+		sample.Keys[key] = true
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+	}
+	return sample
+}
+
+// detectSource picks the importer for a conversation file. An empty array
+// holds no conversations in any format and goes to the first importer.
+func (s *ParserService) detectSource(sample *SourceSample) (SourceImporter, error) {
+	for _, importer := range s.importers {
+		if importer.Detect(sample) {
+			return importer, nil
+		}
+	}
+	if !sample.Lines && len(sample.Keys) == 0 {
+		return s.importers[0], nil
+	}
+	return nil, fmt.Errorf("unrecognised conversation format: %s", sample.Name)
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// ChatGPTExport represents the structure of ChatGPT export JSON
+type ChatGPTExport []ChatGPTConversation
+
+// ChatGPTConversation represents a single conversation in the export
+type ChatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	UpdateTime     float64                `json:"update_time"`
+	Mapping        map[string]MessageNode `json:"mapping"`
+}
+
+// exportID returns the conversation's UUID in the export, which newer
+// exports carry as conversation_id
+func (c ChatGPTConversation) exportID() string {
+	if c.ConversationID != "" {
+		return c.ConversationID
+	}
+	return c.ID
+}
+
+// MessageNode represents a message node in the conversation tree
+type MessageNode struct {
+	ID       string       `json:"id"`
+	Message  *MessageData `json:"message"`
+	Parent   *string      `json:"parent"`
+	Children []string     `json:"children"`
+}
+
+// MessageData represents the actual message content
+type MessageData struct {
+	ID         string                 `json:"id"`
+	Author     AuthorData             `json:"author"`
+	Content    ContentData            `json:"content"`
+	Status     string                 `json:"status"`
+	CreateTime *float64               `json:"create_time"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// AuthorData represents message author information
+type AuthorData struct {
+	Role     string                 `json:"role"`
+	Name     *string                `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// ContentData represents message content
+type ContentData struct {
+	ContentType string        `json:"content_type"`
+	Parts       []ContentPart `json:"parts"`
+}
+
+// ContentPart is one part of a message's content: plain text, or an object
+// such as an image or audio asset pointer
+type ContentPart struct {
+	ContentType   string // empty for plain text
+	Text          string
+	AssetPointers []string
+
+	plain bool // decoded from a JSON string
+}
+
+// assetPointer is the reference a content part holds to a file in the export
+type assetPointer struct {
+	AssetPointer string `json:"asset_pointer"`
+}
+
+func (p *ContentPart) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		p.plain = true
+		return json.Unmarshal(data, &p.Text)
+	}
+
+	var part struct {
+		ContentType                string         `json:"content_type"`
+		Text                       string         `json:"text"`
+		AssetPointer               string         `json:"asset_pointer"`
+		AudioAssetPointer          *assetPointer  `json:"audio_asset_pointer"`
+		VideoContainerAssetPointer *assetPointer  `json:"video_container_asset_pointer"`
+		FramesAssetPointers        []assetPointer `json:"frames_asset_pointers"`
+	}
+	// Parts of an unknown shape carry nothing we use
+	if err := json.Unmarshal(data, &part); err != nil {
+		return nil
+	}
+
+	p.ContentType = part.ContentType
+	p.Text = part.Text
+	pointers := append([]assetPointer{{AssetPointer: part.AssetPointer}}, part.FramesAssetPointers...)
+	for _, pointer := range []*assetPointer{part.AudioAssetPointer, part.VideoContainerAssetPointer} {
+		if pointer != nil {
+			pointers = append(pointers, *pointer)
+		}
+	}
+	for _, pointer := range pointers {
+		if pointer.AssetPointer != "" {
+			p.AssetPointers = append(p.AssetPointers, pointer.AssetPointer)
+		}
+	}
+	return nil
+}
+
+// text joins the text of the content's parts. Parts without text, such as
+// images, are left out.
+func (c ContentData) text() string {
+	var content strings.Builder
+	written := 0
+	for _, part := range c.Parts {
+		if !part.plain && part.Text == "" {
+			continue
+		}
+		if written > 0 {
+			content.WriteString("\n\n")
+		}
+		content.WriteString(part.Text)
+		written++
+	}
+	return content.String()
+}
+
+// chatGPTImporter reads ChatGPT conversations.json files, whose messages form
+// a tree keyed by node ID
+type chatGPTImporter struct{}
+
+func (chatGPTImporter) Source() string { return "chatgpt" }
+
+func (chatGPTImporter) Detect(sample *SourceSample) bool {
+	return !sample.Lines && sample.Keys["mapping"]
+}
+
+func (chatGPTImporter) Decode(r io.Reader, fn func(conv *ImportedConversation) error) error {
+	return decodeExportArray(r, func(decoder *json.Decoder) error {
+		var conv ChatGPTConversation
+		if err := decoder.Decode(&conv); err != nil {
+			return err
+		}
+
+		imported := &ImportedConversation{
+			ConversationID: findRootMessageID(conv.Mapping),
+			ExportID:       conv.exportID(),
+			Title:          conv.Title,
+			CreatedAt:      time.Unix(int64(conv.CreateTime), 0).UTC(),
+			UpdatedAt:      time.Unix(int64(conv.UpdateTime), 0).UTC(),
+		}
+
+		// Find root message (no parent)
+		for id, node := range conv.Mapping {
+			if node.Parent == nil {
+				traverseMessages(conv.Mapping, id, &imported.Messages)
+				break
+			}
+		}
+		return fn(imported)
+	})
+}
+
+// traverseMessages walks the message tree depth first, appending finished
+// messages. Nodes without a message, such as the root of newer exports,
+// are passed through to their children.
+func traverseMessages(mapping map[string]MessageNode, nodeID string, messages *[]ImportedMessage) {
+	node, exists := mapping[nodeID]
+	if !exists {
+		return
+	}
+
+	// Skip empty and incomplete messages
+	if msg := node.Message; msg != nil && msg.Status == "finished_successfully" {
+		*messages = append(*messages, ImportedMessage{
+			ID:        msg.ID,
+			Role:      msg.Author.Role,
+			Content:   msg.Content.text(),
+			Timestamp: msg.timestamp(),
+			Metadata:  msg.Metadata,
+			Assets:    msg.assets(),
+		})
+	}
+
+	// Process children
+	for _, childID := range node.Children {
+		traverseMessages(mapping, childID, messages)
+	}
+}
+
+// timestamp returns when the message was created, which older exports keep
+// in the metadata, or zero if unknown
+func (m *MessageData) timestamp() time.Time {
+	if m.CreateTime != nil && *m.CreateTime > 0 {
+		return time.Unix(int64(*m.CreateTime), 0).UTC()
+	}
+	if createTime, ok := m.Metadata["create_time"].(float64); ok {
+		return time.Unix(int64(createTime), 0).UTC()
+	}
+	return time.Time{}
+}
+
+// assets lists the files the message points to, through content part asset
+// pointers and attachments
+func (m *MessageData) assets() []ImportedAsset {
+	var assets []ImportedAsset
+	for _, part := range m.Content.Parts {
+		for _, pointer := range part.AssetPointers {
+			assets = append(assets, ImportedAsset{AssetID: assetIDFromPointer(pointer), PointerType: part.ContentType})
+		}
+	}
+	if attachments, ok := m.Metadata["attachments"].([]interface{}); ok {
+		for _, attachment := range attachments {
+			if fields, ok := attachment.(map[string]interface{}); ok {
+				if id, ok := fields["id"].(string); ok && id != "" {
+					assets = append(assets, ImportedAsset{AssetID: id, PointerType: "attachment"})
+				}
+			}
+		}
+	}
+	return assets
+}
+
+// assetIDFromPointer strips the scheme from an asset pointer such as
+// file-service://file-abc123, leaving the file ID
+func assetIDFromPointer(pointer string) string {
+	if i := strings.Index(pointer, "://"); i >= 0 {
+		return pointer[i+3:]
+	}
+	return pointer
+}
+
+// findRootMessageID finds the root message ID (conversation identifier)
+func findRootMessageID(mapping map[string]MessageNode) string {
+	for id, node := range mapping {
+		if node.Parent == nil {
+			return id
+		}
+	}
+	// Fallback: return first key
+	for id := range mapping {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// claudeConversation is one conversation of a Claude conversations.json export
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	CreatedAt    exportTime      `json:"created_at"`
+	UpdatedAt    exportTime      `json:"updated_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+// claudeMessage is one message of a Claude conversation
+type claudeMessage struct {
+	UUID      string     `json:"uuid"`
+	Text      string     `json:"text"`
+	Sender    string     `json:"sender"` // human, assistant
+	CreatedAt exportTime `json:"created_at"`
+	Content   []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Attachments []claudeFile `json:"attachments"`
+	Files       []claudeFile `json:"files"`
+}
+
+// claudeFile names a file attached to a Claude message. Exports do not
+// include the files themselves.
+type claudeFile struct {
+	FileName string `json:"file_name"`
+}
+
+// claudeImporter reads Claude conversations.json exports
+type claudeImporter struct{}
+
+func (claudeImporter) Source() string { return "claude" }
+
+func (claudeImporter) Detect(sample *SourceSample) bool {
+	return !sample.Lines && sample.Keys["chat_messages"]
+}
+
+func (claudeImporter) Decode(r io.Reader, fn func(conv *ImportedConversation) error) error {
+	return decodeExportArray(r, func(decoder *json.Decoder) error {
+		var conv claudeConversation
+		if err := decoder.Decode(&conv); err != nil {
+			return err
+		}
+
+		imported := &ImportedConversation{
+			ConversationID: conv.UUID,
+			ExportID:       conv.UUID,
+			Title:          conv.Name,
+			CreatedAt:      conv.CreatedAt.Time,
+			UpdatedAt:      conv.UpdatedAt.Time,
+		}
+		for _, msg := range conv.ChatMessages {
+			imported.Messages = append(imported.Messages, ImportedMessage{
+				ID:        msg.UUID,
+				Role:      claudeRole(msg.Sender),
+				Content:   msg.text(),
+				Timestamp: msg.CreatedAt.Time,
+				Metadata:  msg.metadata(),
+			})
+		}
+		return fn(imported)
+	})
+}
+
+// text returns the message text, joining its text blocks when the flat
+// text field is empty
+func (m claudeMessage) text() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	var parts []string
+	for _, block := range m.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// metadata keeps the sender and the names of attached files
+func (m claudeMessage) metadata() map[string]interface{} {
+	metadata := map[string]interface{}{"sender": m.Sender}
+	var names []string
+	for _, file := range append(m.Attachments, m.Files...) {
+		if file.FileName != "" {
+			names = append(names, file.FileName)
+		}
+	}
+	if len(names) > 0 {
+		metadata["attachments"] = names
+	}
+	return metadata
+}
+
+// claudeRole maps Claude's sender names onto the roles used elsewhere
+func claudeRole(sender string) string {
+	if sender == "human" {
+		return "user"
+	}
+	return sender
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// geminiActivity is one record of a Google Takeout "My Activity" file for
+// Gemini Apps (formerly Bard)
+type geminiActivity struct {
+	Header       string     `json:"header"`
+	Title        string     `json:"title"` // "Prompted <prompt text>" for prompts
+	Time         exportTime `json:"time"`
+	Products     []string   `json:"products"`
+	SafeHTMLItem []struct {
+		HTML string `json:"html"`
+	} `json:"safeHtmlItem"` // the response, as HTML
+}
+
+// geminiPromptPrefix starts the title of activity records that are prompts
+const geminiPromptPrefix = "Prompted "
+
+// geminiImporter reads Gemini activity from Google Takeout. Takeout keeps no
+// conversation threads, so each day's prompts and responses are grouped
+// into one conversation.
+type geminiImporter struct{}
+
+func (geminiImporter) Source() string { return "gemini" }
+
+func (geminiImporter) Detect(sample *SourceSample) bool {
+	return !sample.Lines && sample.Keys["header"] && sample.Keys["title"] && sample.Keys["time"]
+}
+
+func (geminiImporter) Decode(r io.Reader, fn func(conv *ImportedConversation) error) error {
+	days := make(map[string]*ImportedConversation)
+	err := decodeExportArray(r, func(decoder *json.Decoder) error {
+		var activity geminiActivity
+		if err := decoder.Decode(&activity); err != nil {
+			return err
+		}
+		if !activity.isPrompt() {
+			return nil
+		}
+
+		timestamp := activity.Time.Time
+		day := timestamp.Format("2006-01-02")
+		conv, ok := days[day]
+		if !ok {
+			conv = &ImportedConversation{
+				ConversationID: "gemini-" + day,
+				Title:          "Gemini activity " + day,
+			}
+			days[day] = conv
+		}
+
+		conv.Messages = append(conv.Messages, ImportedMessage{
+			Role:      "user",
+			Content:   strings.TrimPrefix(activity.Title, geminiPromptPrefix),
+			Timestamp: timestamp,
+			Metadata:  map[string]interface{}{"products": activity.Products},
+		})
+		var response []string
+		for _, item := range activity.SafeHTMLItem {
+			if text := htmlToText(item.HTML); text != "" {
+				response = append(response, text)
+			}
+		}
+		if len(response) > 0 {
+			conv.Messages = append(conv.Messages, ImportedMessage{
+				Role:      "assistant",
+				Content:   strings.Join(response, "\n\n"),
+				Timestamp: timestamp,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Takeout lists activity newest first
+	keys := make([]string, 0, len(days))
+	for day := range days {
+		keys = append(keys, day)
+	}
+	sort.Strings(keys)
+	for _, day := range keys {
+		conv := days[day]
+		sort.SliceStable(conv.Messages, func(i, j int) bool {
+			return conv.Messages[i].Timestamp.Before(conv.Messages[j].Timestamp)
+		})
+		conv.CreatedAt = conv.Messages[0].Timestamp
+		conv.UpdatedAt = conv.Messages[len(conv.Messages)-1].Timestamp
+		if err := fn(conv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPrompt reports whether the record is a Gemini prompt rather than some
+// other activity, or activity from another Google product
+func (a geminiActivity) isPrompt() bool {
+	if !strings.HasPrefix(a.Title, geminiPromptPrefix) || a.Time.IsZero() {
+		return false
+	}
+	for _, product := range append([]string{a.Header}, a.Products...) {
+		if strings.Contains(product, "Gemini") || strings.Contains(product, "Bard") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|pre|tr|blockquote)>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText flattens an HTML fragment to plain text, keeping line breaks
+// between block elements
+func htmlToText(fragment string) string {
+	text := htmlBreakPattern.ReplaceAllString(fragment, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankLinePattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
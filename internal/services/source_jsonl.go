@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonlRecord is one line of a generic JSONL chat export: a single message,
+// tagged with the conversation it belongs to. See the README for the schema.
+type jsonlRecord struct {
+	ConversationID    string                 `json:"conversation_id"`
+	ConversationTitle string                 `json:"conversation_title"`
+	MessageID         string                 `json:"message_id"`
+	Role              string                 `json:"role"`
+	Content           string                 `json:"content"`
+	Timestamp         exportTime             `json:"timestamp"` // RFC 3339 or Unix seconds
+	Metadata          map[string]interface{} `json:"metadata"`
+}
+
+// jsonlImporter reads newline-delimited JSON messages. Lines of different
+// conversations may be interleaved; messages keep their order in the file.
+type jsonlImporter struct{}
+
+func (jsonlImporter) Source() string { return "jsonl" }
+
+func (jsonlImporter) Detect(sample *SourceSample) bool {
+	return sample.Lines && sample.Keys["role"] && sample.Keys["content"]
+}
+
+func (jsonlImporter) Decode(r io.Reader, fn func(conv *ImportedConversation) error) error {
+	var order []string
+	conversations := make(map[string]*ImportedConversation)
+
+	decoder := json.NewDecoder(r)
+	for n := 1; decoder.More(); n++ {
+		var record jsonlRecord
+		if err := decoder.Decode(&record); err != nil {
+			return fmt.Errorf("failed to parse JSON: record %d: %w", n, err)
+		}
+		if record.ConversationID == "" || record.Role == "" {
+			return fmt.Errorf("failed to parse JSON: record %d: conversation_id and role are required", n)
+		}
+
+		conv, ok := conversations[record.ConversationID]
+		if !ok {
+			conv = &ImportedConversation{
+				ConversationID: record.ConversationID,
+				ExportID:       record.ConversationID,
+			}
+			conversations[record.ConversationID] = conv
+			order = append(order, record.ConversationID)
+		}
+		if conv.Title == "" {
+			conv.Title = record.ConversationTitle
+		}
+
+		timestamp := record.Timestamp.Time
+		if !timestamp.IsZero() {
+			if conv.CreatedAt.IsZero() || timestamp.Before(conv.CreatedAt) {
+				conv.CreatedAt = timestamp
+			}
+			if timestamp.After(conv.UpdatedAt) {
+				conv.UpdatedAt = timestamp
+			}
+		}
+		conv.Messages = append(conv.Messages, ImportedMessage{
+			ID:        record.MessageID,
+			Role:      record.Role,
+			Content:   record.Content,
+			Timestamp: timestamp,
+			Metadata:  record.Metadata,
+		})
+	}
+
+	for _, id := range order {
+		if err := fn(conversations[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
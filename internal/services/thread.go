@@ -3,49 +3,51 @@ package services
 import (
 	"fmt"
 	"sort"
-	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 // ThreadService handles date-based thread division
 type ThreadService struct {
-	cfg *config.Config
-	log *zap.Logger
+	cfg      *config.Config
+	log      *zap.Logger
+	store    *repository.Store
+	progress *ImportProgressService
 }
 
 // NewThreadService creates a new thread service
-func NewThreadService(cfg *config.Config, log *zap.Logger) *ThreadService {
+func NewThreadService(cfg *config.Config, log *zap.Logger, store *repository.Store, progress *ImportProgressService) *ThreadService {
 	return &ThreadService{
-		cfg: cfg,
-		log: log,
+		cfg:      cfg,
+		log:      log,
+		store:    store,
+		progress: progress,
 	}
 }
 
 // CreateThreadsForUpload creates threads for all conversations in an upload
 func (s *ThreadService) CreateThreadsForUpload(uploadID uint) error {
-	var upload models.Upload
-	if err := database.DB.First(&upload, uploadID).Error; err != nil {
+	if _, err := s.store.Uploads.Get(uploadID); err != nil {
 		return fmt.Errorf("upload not found: %w", err)
 	}
 
-	var importRecord models.Import
-	if err := database.DB.Where("upload_id = ?", uploadID).First(&importRecord).Error; err != nil {
+	importRecord, err := s.store.Imports.ForUpload(uploadID)
+	if err != nil {
 		return fmt.Errorf("import record not found: %w", err)
 	}
 
 	importRecord.Status = "importing"
+	importRecord.Stage = StageThread
 	importRecord.ProgressPercent = 70
-	database.DB.Save(&importRecord)
+	s.progress.Save(importRecord)
 
 	// Get all conversations for this upload
-	var conversations []models.Conversation
-	if err := database.DB.Where("upload_id = ?", uploadID).Find(&conversations).Error; err != nil {
+	conversations, err := s.store.Conversations.ForUpload(uploadID)
+	if err != nil {
 		return fmt.Errorf("failed to get conversations: %w", err)
 	}
 
@@ -67,29 +69,14 @@ func (s *ThreadService) CreateThreadsForUpload(uploadID uint) error {
 		// Update progress
 		progress := 70 + int(float64(i+1)/float64(len(conversations))*25) // 70-95%
 		importRecord.ProgressPercent = progress
-		database.DB.Save(&importRecord)
+		s.progress.SetCount(importRecord, "threads_count", totalThreads)
+		s.progress.Save(importRecord)
 	}
 
-	// Update import stats
-	var stats map[string]interface{}
-	if importRecord.Stats != "" {
-		// Parse existing stats
-		// For simplicity, we'll just update threads_count
-		stats = map[string]interface{}{
-			"threads_count": totalThreads,
-		}
-	} else {
-		stats = map[string]interface{}{
-			"threads_count": totalThreads,
-		}
-	}
-
-	// Update import status
-	importRecord.Status = "completed"
-	importRecord.ProgressPercent = 100
-	completedAt := time.Now().UTC()
-	importRecord.CompletedAt = &completedAt
-	database.DB.Save(&importRecord)
+	// Update import stats; the pipeline marks the import completed
+	s.progress.SetCount(importRecord, "threads_count", totalThreads)
+	importRecord.ProgressPercent = 95
+	s.progress.Save(importRecord)
 
 	s.log.Info("Thread creation completed",
 		zap.Uint("upload_id", uploadID),
@@ -102,10 +89,8 @@ func (s *ThreadService) CreateThreadsForUpload(uploadID uint) error {
 // createThreadsForConversation creates threads for a single conversation
 func (s *ThreadService) createThreadsForConversation(conversationID uint) (int, error) {
 	// Get all messages for this conversation, ordered by timestamp
-	var messages []models.Message
-	if err := database.DB.Where("conversation_id = ?", conversationID).
-		Order("timestamp ASC").
-		Find(&messages).Error; err != nil {
+	messages, err := s.store.Messages.ForConversation(conversationID)
+	if err != nil {
 		return 0, fmt.Errorf("failed to get messages: %w", err)
 	}
 
@@ -132,9 +117,7 @@ func (s *ThreadService) createThreadsForConversation(conversationID uint) (int,
 		}
 
 		// Check if thread already exists
-		var existing models.Thread
-		if err := database.DB.Where("conversation_id = ? AND date = ?", conversationID, date).
-			First(&existing).Error; err == nil {
+		if _, err := s.store.Threads.First("conversation_id = ? AND date = ?", conversationID, date); err == nil {
 			// Thread already exists, skip
 			continue
 		}
@@ -190,7 +173,7 @@ func (s *ThreadService) createThreadsForConversation(conversationID uint) (int,
 			if end > len(threads) {
 				end = len(threads)
 			}
-			if err := database.DB.CreateInBatches(threads[i:end], batchSize).Error; err != nil {
+			if err := s.store.Threads.CreateInBatches(threads[i:end], batchSize); err != nil {
 				return 0, fmt.Errorf("failed to create threads batch: %w", err)
 			}
 		}
@@ -202,10 +185,8 @@ func (s *ThreadService) createThreadsForConversation(conversationID uint) (int,
 
 // GetThreadsForConversation gets all threads for a conversation
 func (s *ThreadService) GetThreadsForConversation(conversationID uint) ([]models.Thread, error) {
-	var threads []models.Thread
-	if err := database.DB.Where("conversation_id = ?", conversationID).
-		Order("date ASC").
-		Find(&threads).Error; err != nil {
+	threads, err := s.store.Threads.ForConversation(conversationID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to get threads: %w", err)
 	}
 	return threads, nil
@@ -213,13 +194,13 @@ func (s *ThreadService) GetThreadsForConversation(conversationID uint) ([]models
 
 // GetThread gets a thread by ID
 func (s *ThreadService) GetThread(threadID uint) (*models.Thread, error) {
-	var thread models.Thread
-	if err := database.DB.First(&thread, threadID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	thread, err := s.store.Threads.Get(threadID)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("thread not found: %d", threadID)
 		}
 		return nil, fmt.Errorf("failed to get thread: %w", err)
 	}
-	return &thread, nil
+	return thread, nil
 }
 
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"unicode/utf8"
+)
+
+const (
+	// charsPerToken approximates how many characters make up one token for
+	// English prose with the GPT and Claude tokenizers
+	charsPerToken = 4
+
+	// messageTokenOverhead accounts for the role and timestamp prefix of each
+	// message in a rendered transcript
+	messageTokenOverhead = 8
+
+	// assistantExcerptLength caps assistant messages so user messages get
+	// most of the budget
+	assistantExcerptLength = 300
+
+	// minExcerptLength is the shortest user message excerpt kept when
+	// shrinking messages to fit the chunk limit
+	minExcerptLength = 200
+)
+
+// EstimateTokens returns a conservative token estimate for text
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// estimateMessageTokens returns the estimated tokens of a transcript line
+func estimateMessageTokens(msg PromptMessage) int {
+	return EstimateTokens(msg.Content) + messageTokenOverhead
+}
+
+// estimateMessagesTokens sums the estimated tokens of a set of messages
+func estimateMessagesTokens(messages []PromptMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += estimateMessageTokens(msg)
+	}
+	return total
+}
+
+// fitMessages reduces messages so they fit in maxChunks chunks of chunkBudget
+// tokens. Assistant messages are dropped first, then user message excerpts
+// are shortened, and finally the latest messages are dropped. It returns the
+// messages to use and how many were dropped.
+func fitMessages(messages []PromptMessage, chunkBudget, maxChunks int) ([]PromptMessage, int) {
+	limit := chunkBudget * maxChunks
+	if estimateMessagesTokens(messages) <= limit {
+		return messages, 0
+	}
+
+	// Keep user messages only
+	var fitted []PromptMessage
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			fitted = append(fitted, msg)
+		}
+	}
+	dropped := len(messages) - len(fitted)
+
+	// Shorten user message excerpts until they fit
+	for length := promptExcerptLength / 2; length >= minExcerptLength && estimateMessagesTokens(fitted) > limit; length /= 2 {
+		for i := range fitted {
+			fitted[i].Content = truncateRunes(fitted[i].Content, length)
+		}
+	}
+
+	// Drop the latest messages as a last resort
+	for len(fitted) > 0 && estimateMessagesTokens(fitted) > limit {
+		fitted = fitted[:len(fitted)-1]
+		dropped++
+	}
+
+	return fitted, dropped
+}
+
+// chunkMessages splits messages into consecutive chunks of at most
+// chunkBudget estimated tokens. A single message larger than the budget is
+// truncated to fit in its own chunk.
+func chunkMessages(messages []PromptMessage, chunkBudget int) [][]PromptMessage {
+	var chunks [][]PromptMessage
+	var current []PromptMessage
+	used := 0
+
+	for _, msg := range messages {
+		tokens := estimateMessageTokens(msg)
+		if tokens > chunkBudget {
+			msg.Content = truncateRunes(msg.Content, (chunkBudget-messageTokenOverhead)*charsPerToken)
+			tokens = estimateMessageTokens(msg)
+		}
+
+		if used+tokens > chunkBudget && len(current) > 0 {
+			chunks = append(chunks, current)
+			current = nil
+			used = 0
+		}
+		current = append(current, msg)
+		used += tokens
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// truncationMarker is appended to text shortened to fit a token budget
+const truncationMarker = "..."
+
+// truncateRunes shortens s to at most max runes, counting the marker it
+// appends, so the result is measured in the same unit as EstimateTokens
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max < len(truncationMarker) {
+		return cutRunes(s, max)
+	}
+	return cutRunes(s, max-len(truncationMarker)) + truncationMarker
+}
+
+// cutRunes returns the first n runes of s
+func cutRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
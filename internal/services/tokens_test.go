@@ -0,0 +1,37 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkMessagesTruncatesToBudget(t *testing.T) {
+	const chunkBudget = 100
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"ascii", strings.Repeat("a", 2000)},
+		{"two-byte", strings.Repeat("é", 2000)},
+		{"three-byte", strings.Repeat("漢字", 1000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkMessages([]PromptMessage{{Role: "user", Content: tt.text}}, chunkBudget)
+			if len(chunks) != 1 || len(chunks[0]) != 1 {
+				t.Fatalf("chunks = %d, want one chunk holding the message", len(chunks))
+			}
+			msg := chunks[0][0]
+			if !utf8.ValidString(msg.Content) || !strings.HasSuffix(msg.Content, truncationMarker) {
+				t.Errorf("truncated content is not valid UTF-8 ending in %q", truncationMarker)
+			}
+
+			// The message fills its chunk without running over it
+			if tokens := estimateMessageTokens(msg); tokens != chunkBudget {
+				t.Errorf("truncated message is %d tokens, want %d", tokens, chunkBudget)
+			}
+		})
+	}
+}
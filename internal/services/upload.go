@@ -3,32 +3,35 @@ package services
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"chatgpt-autopsy-go/internal/config"
-	"chatgpt-autopsy-go/internal/database"
 	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 // UploadService handles file upload operations
 type UploadService struct {
-	cfg *config.Config
-	log *zap.Logger
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
 }
 
 // NewUploadService creates a new upload service
-func NewUploadService(cfg *config.Config, log *zap.Logger) *UploadService {
+func NewUploadService(cfg *config.Config, log *zap.Logger, store *repository.Store) *UploadService {
 	return &UploadService{
-		cfg: cfg,
-		log: log,
+		cfg:   cfg,
+		log:   log,
+		store: store,
 	}
 }
 
@@ -42,12 +45,9 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 	// Generate UUID for file storage
 	fileUUID := uuid.New().String()
 	
-	// Create stored filename
-	storedFilename := fmt.Sprintf("%s.zip", fileUUID)
-	storedPath := filepath.Join(s.cfg.Directories.UploadsDir, storedFilename)
-
-	// Create temp file for atomic write
-	tempPath := storedPath + ".tmp"
+	// Create temp file for atomic write; the stored extension depends on the
+	// format detected once the content is written
+	tempPath := filepath.Join(s.cfg.Directories.UploadsDir, fileUUID+".tmp")
 	tempFile, err := os.Create(tempPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp file: %w", err)
@@ -76,11 +76,27 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 	// Calculate file hash
 	fileHash := hex.EncodeToString(hash.Sum(nil))
 
+	return s.storeUpload(originalFilename, fileUUID, tempPath, fileSize, fileHash)
+}
+
+// storeUpload moves a fully written temp file into the uploads directory and
+// creates the Upload and Import records. The caller removes tempPath if the
+// upload is rejected.
+func (s *UploadService) storeUpload(originalFilename, fileUUID, tempPath string, fileSize int64, fileHash string) (*models.Upload, error) {
+	// Detect the export format from content; the file name is not trusted
+	format, err := DetectFormat(tempPath)
+	if err != nil {
+		return nil, err
+	}
+	storedPath := filepath.Join(s.cfg.Directories.UploadsDir, fmt.Sprintf("%s.%s", fileUUID, format))
+
 	// Check for duplicate upload
-	var existingUpload models.Upload
-	if err := database.DB.Where("file_hash = ?", fileHash).First(&existingUpload).Error; err == nil {
+	if existingUpload, err := s.store.Uploads.ByHash(fileHash); err == nil {
 		s.log.Info("Duplicate file detected", zap.String("hash", fileHash), zap.Uint("existing_id", existingUpload.ID))
-		return &existingUpload, fmt.Errorf("file already uploaded (ID: %d)", existingUpload.ID)
+		return existingUpload, fmt.Errorf("file already uploaded (ID: %d)", existingUpload.ID)
+	}
+	if existingUpload, err := s.store.Uploads.Unscoped().First("file_hash = ?", fileHash); err == nil {
+		return nil, fmt.Errorf("file already uploaded (ID: %d) and deleted but not yet purged; purge it to upload again", existingUpload.ID)
 	}
 
 	// Atomic rename: temp file → final file
@@ -90,7 +106,7 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 
 	// Create Upload and Import records in transaction
 	var upload models.Upload
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
+	err = s.store.Transaction(func(tx *repository.Store) error {
 		// Create Upload record
 		upload = models.Upload{
 			UUID:            fileUUID,
@@ -98,12 +114,12 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 			StoredPath:      storedPath,
 			FileSize:        fileSize,
 			FileHash:        fileHash,
-			MimeType:        "application/zip",
+			MimeType:        formatMimeTypes[format],
 			UploadedAt:      time.Now().UTC(),
 			Status:          "pending",
 		}
 
-		if err := tx.Create(&upload).Error; err != nil {
+		if err := tx.Uploads.Create(&upload); err != nil {
 			return fmt.Errorf("failed to create upload record: %w", err)
 		}
 
@@ -115,7 +131,7 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 			ProgressPercent: 0,
 		}
 
-		if err := tx.Create(&importRecord).Error; err != nil {
+		if err := tx.Imports.Create(&importRecord); err != nil {
 			return fmt.Errorf("failed to create import record: %w", err)
 		}
 
@@ -138,16 +154,87 @@ func (s *UploadService) UploadFile(originalFilename string, fileReader io.Reader
 	return &upload, nil
 }
 
+// ImportFromPath uploads an export file that is already on the server. The
+// file is copied, so the original stays where it is. Only files inside the
+// configured import base directory are accepted.
+func (s *UploadService) ImportFromPath(path string) (*models.Upload, error) {
+	if s.cfg.Upload.ImportBaseDir == "" {
+		return nil, fmt.Errorf("server-side imports are disabled")
+	}
+
+	resolved, err := resolveWithin(s.cfg.Upload.ImportBaseDir, path)
+	if err != nil {
+		return nil, err
+	}
+	return s.UploadLocalFile(resolved)
+}
+
+// UploadLocalFile uploads an export file from the local filesystem without
+// any directory restriction, for the inbox and the command-line tool
+func (s *UploadService) UploadLocalFile(path string) (*models.Upload, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("invalid path: not a regular file")
+	}
+
+	return s.UploadFile(filepath.Base(path), file, info.Size())
+}
+
+// errImportPath is returned alike for paths that are missing and paths
+// outside the import base directory, so requests cannot probe the host
+var errImportPath = errors.New("file not found in the import base directory")
+
+// resolveWithin checks that path lies inside baseDir, first lexically and
+// again after following symlinks, and returns the resolved path
+func resolveWithin(baseDir, path string) (string, error) {
+	base, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve import base directory: %w", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	if !isWithin(base, path) && !isWithin(filepath.Clean(baseDir), path) {
+		return "", errImportPath
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil || !isWithin(base, resolved) {
+		return "", errImportPath
+	}
+	return resolved, nil
+}
+
+// isWithin reports whether the clean absolute path is base or below it
+func isWithin(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // GetUpload retrieves an upload by ID
 func (s *UploadService) GetUpload(id uint) (*models.Upload, error) {
-	var upload models.Upload
-	if err := database.DB.First(&upload, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	upload, err := s.store.Uploads.Get(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("upload not found: %d", id)
 		}
 		return nil, fmt.Errorf("failed to get upload: %w", err)
 	}
-	return &upload, nil
+	return upload, nil
 }
 
 // ListUploads lists uploads with pagination
@@ -158,12 +245,12 @@ func (s *UploadService) ListUploads(page, limit int) ([]models.Upload, int64, er
 	offset := (page - 1) * limit
 
 	// Get total count
-	if err := database.DB.Model(&models.Upload{}).Count(&total).Error; err != nil {
+	if err := s.store.Uploads.Query().Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to count uploads: %w", err)
 	}
 
 	// Get paginated results
-	if err := database.DB.
+	if err := s.store.Uploads.Query().
 		Order("uploaded_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -176,21 +263,21 @@ func (s *UploadService) ListUploads(page, limit int) ([]models.Upload, int64, er
 
 // DeleteUpload deletes an upload and its associated data
 func (s *UploadService) DeleteUpload(id uint) error {
-	var upload models.Upload
-	if err := database.DB.First(&upload, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	upload, err := s.store.Uploads.Get(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			return fmt.Errorf("upload not found: %d", id)
 		}
 		return fmt.Errorf("failed to get upload: %w", err)
 	}
 
 	// Soft delete (GORM handles this)
-	if err := database.DB.Delete(&upload).Error; err != nil {
+	if err := s.store.Uploads.Delete(upload); err != nil {
 		return fmt.Errorf("failed to delete upload: %w", err)
 	}
 
-	// Note: File cleanup can be done by a background job
-	// For now, we just mark it as deleted in the database
+	// Files and derived rows are removed by the garbage collector, or
+	// immediately by PipelineService.Purge
 
 	s.log.Info("Upload deleted", zap.Uint("upload_id", id))
 	return nil
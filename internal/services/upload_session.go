@@ -0,0 +1,385 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// UploadSessionService implements resumable chunked uploads. A session
+// accepts chunks at increasing offsets, hashing them as they arrive, and is
+// finalised into a normal Upload once every byte has been received.
+type UploadSessionService struct {
+	cfg     *config.Config
+	log     *zap.Logger
+	store   *repository.Store
+	uploads *UploadService
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // session UUID -> chunk lock
+}
+
+// NewUploadSessionService creates a new upload session service
+func NewUploadSessionService(cfg *config.Config, log *zap.Logger, store *repository.Store, uploads *UploadService) *UploadSessionService {
+	return &UploadSessionService{
+		cfg:     cfg,
+		log:     log,
+		store:   store,
+		uploads: uploads,
+		locks:   make(map[string]*sync.Mutex),
+	}
+}
+
+// sessionsDir holds partial session files. The leading dot keeps the garbage
+// collector's orphan sweep of the uploads directory away from it.
+func (s *UploadSessionService) sessionsDir() string {
+	return filepath.Join(s.cfg.Directories.UploadsDir, ".sessions")
+}
+
+// CreateSession starts a resumable upload of totalSize bytes. expectedHash
+// is optional; when set the finished file must match it.
+func (s *UploadSessionService) CreateSession(filename string, totalSize int64, expectedHash string) (*models.UploadSession, error) {
+	if totalSize <= 0 {
+		return nil, fmt.Errorf("invalid size: must be positive")
+	}
+	if totalSize > s.cfg.Upload.MaxFileSize {
+		return nil, fmt.Errorf("file size %d exceeds maximum %d", totalSize, s.cfg.Upload.MaxFileSize)
+	}
+	expectedHash = strings.ToLower(strings.TrimSpace(expectedHash))
+	if expectedHash != "" {
+		if decoded, err := hex.DecodeString(expectedHash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256: must be a 64 character hex digest")
+		}
+	}
+
+	if err := os.MkdirAll(s.sessionsDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	sessionUUID := uuid.New().String()
+	tempPath := filepath.Join(s.sessionsDir(), sessionUUID+".part")
+	if err := os.WriteFile(tempPath, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create session file: %w", err)
+	}
+
+	state, err := marshalHash(sha256.New())
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := models.UploadSession{
+		UUID:             sessionUUID,
+		OriginalFilename: filename,
+		TotalSize:        totalSize,
+		ExpectedHash:     expectedHash,
+		HashState:        state,
+		TempPath:         tempPath,
+		Status:           "active",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		ExpiresAt:        now.Add(s.cfg.Upload.SessionTTL),
+	}
+	if err := s.store.UploadSessions.Create(&session); err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	s.log.Info("Upload session created",
+		zap.String("session", session.UUID),
+		zap.String("filename", filename),
+		zap.Int64("size", totalSize),
+	)
+	return &session, nil
+}
+
+// errSessionNotFound is returned for unknown session UUIDs
+var errSessionNotFound = errors.New("upload session not found")
+
+// GetSession returns a session by UUID
+func (s *UploadSessionService) GetSession(sessionUUID string) (*models.UploadSession, error) {
+	session, err := s.store.UploadSessions.First("uuid = ?", sessionUUID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("%w: %s", errSessionNotFound, sessionUUID)
+		}
+		return nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+	return session, nil
+}
+
+// WriteChunk appends a chunk starting at offset, which must equal the
+// session's current offset. Bytes received before a dropped connection are
+// kept, so the client resumes from the returned session's offset. When the
+// last byte arrives the session is finalised and UploadID is set.
+func (s *UploadSessionService) WriteChunk(sessionUUID string, offset int64, chunk io.Reader) (*models.UploadSession, error) {
+	lock := s.lock(sessionUUID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	session, err := s.GetSession(sessionUUID)
+	if err != nil {
+		if errors.Is(err, errSessionNotFound) {
+			s.dropLock(sessionUUID)
+		}
+		return nil, err
+	}
+	if session.Status != "active" {
+		s.dropLock(sessionUUID)
+		return session, fmt.Errorf("upload session is %s", session.Status)
+	}
+	if session.ExpiresAt.Before(time.Now().UTC()) {
+		s.expire(session)
+		return session, fmt.Errorf("upload session is expired")
+	}
+	if offset != session.Offset {
+		return session, fmt.Errorf("offset mismatch: expected %d, got %d", session.Offset, offset)
+	}
+
+	hasher, err := unmarshalHash(session.HashState)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(session.TempPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+
+	// Drop anything past the acknowledged offset from an interrupted write
+	if err := file.Truncate(session.Offset); err != nil {
+		return nil, fmt.Errorf("failed to truncate session file: %w", err)
+	}
+	if _, err := file.Seek(session.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek session file: %w", err)
+	}
+
+	limit := session.TotalSize - session.Offset
+	if limit > s.cfg.Upload.MaxChunkSize {
+		limit = s.cfg.Upload.MaxChunkSize
+	}
+	dst := &chunkWriter{file: file, hasher: hasher}
+	written, copyErr := io.Copy(dst, io.LimitReader(chunk, limit))
+
+	// Sync before the offset is saved, including the partial write of a
+	// dropped connection, so a crash never leaves the file shorter than the
+	// offset and hash state say
+	if dst.err == nil {
+		dst.err = file.Sync()
+	}
+
+	// A failed file write or sync leaves bytes on disk the saved hash state
+	// has not seen, so roll the file back to the acknowledged offset and keep
+	// the session as it was; the client resends the chunk
+	if dst.err != nil {
+		if err := file.Truncate(session.Offset); err != nil {
+			s.log.Warn("Failed to roll back session file", zap.String("session", session.UUID), zap.Error(err))
+		}
+		return session, fmt.Errorf("failed to write chunk: %w", dst.err)
+	}
+
+	// Save progress when the client went away mid-chunk
+	state, err := marshalHash(hasher)
+	if err != nil {
+		return nil, err
+	}
+	session.Offset += written
+	session.HashState = state
+	session.UpdatedAt = time.Now().UTC()
+	session.ExpiresAt = session.UpdatedAt.Add(s.cfg.Upload.SessionTTL)
+	if err := s.store.UploadSessions.Save(session); err != nil {
+		return nil, fmt.Errorf("failed to save upload session: %w", err)
+	}
+
+	if copyErr != nil {
+		return session, fmt.Errorf("chunk interrupted after %d bytes: %w", written, copyErr)
+	}
+
+	// Reject bodies that run past the declared size
+	if session.Offset == session.TotalSize {
+		var extra [1]byte
+		if n, _ := chunk.Read(extra[:]); n > 0 {
+			return session, fmt.Errorf("chunk exceeds declared upload size %d", session.TotalSize)
+		}
+		return s.finalise(session, hasher)
+	}
+
+	return session, nil
+}
+
+// CancelSession aborts a session and removes its partial file
+func (s *UploadSessionService) CancelSession(sessionUUID string) error {
+	lock := s.lock(sessionUUID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	session, err := s.GetSession(sessionUUID)
+	if err != nil {
+		if errors.Is(err, errSessionNotFound) {
+			s.dropLock(sessionUUID)
+		}
+		return err
+	}
+
+	os.Remove(session.TempPath)
+	if err := s.store.UploadSessions.Delete(session); err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+	s.dropLock(sessionUUID)
+	return nil
+}
+
+// ExpireSessions removes partial files of sessions idle past their TTL and
+// returns how many sessions expired and the bytes reclaimed
+func (s *UploadSessionService) ExpireSessions() (int, int64, error) {
+	sessions, err := s.store.UploadSessions.Find("status = ? AND expires_at < ?", "active", time.Now().UTC())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list expired upload sessions: %w", err)
+	}
+
+	var reclaimed int64
+	for i := range sessions {
+		lock := s.lock(sessions[i].UUID)
+		lock.Lock()
+		if info, err := os.Stat(sessions[i].TempPath); err == nil {
+			reclaimed += info.Size()
+		}
+		s.expire(&sessions[i])
+		lock.Unlock()
+	}
+	return len(sessions), reclaimed, nil
+}
+
+// finalise verifies the received file and turns it into an Upload
+func (s *UploadSessionService) finalise(session *models.UploadSession, hasher hash.Hash) (*models.UploadSession, error) {
+	fileHash := hex.EncodeToString(hasher.Sum(nil))
+	if session.ExpectedHash != "" && fileHash != session.ExpectedHash {
+		return session, s.fail(session, fmt.Errorf("sha256 mismatch: expected %s, got %s", session.ExpectedHash, fileHash))
+	}
+
+	upload, err := s.uploads.storeUpload(session.OriginalFilename, uuid.New().String(), session.TempPath, session.TotalSize, fileHash)
+	if err != nil {
+		return session, s.fail(session, err)
+	}
+
+	session.Status = "completed"
+	session.UploadID = &upload.ID
+	session.HashState = nil
+	s.dropLock(session.UUID)
+	if err := s.store.UploadSessions.Save(session); err != nil {
+		return session, fmt.Errorf("failed to complete upload session: %w", err)
+	}
+
+	s.log.Info("Upload session completed",
+		zap.String("session", session.UUID),
+		zap.Uint("upload_id", upload.ID),
+	)
+	return session, nil
+}
+
+// fail marks a session failed, removes its file and lock, and returns err
+func (s *UploadSessionService) fail(session *models.UploadSession, err error) error {
+	os.Remove(session.TempPath)
+	s.dropLock(session.UUID)
+	errorMsg := err.Error()
+	session.Status = "failed"
+	session.ErrorMessage = &errorMsg
+	session.HashState = nil
+	if saveErr := s.store.UploadSessions.Save(session); saveErr != nil {
+		s.log.Warn("Failed to mark upload session failed", zap.String("session", session.UUID), zap.Error(saveErr))
+	}
+	return err
+}
+
+// expire marks a session expired and removes its file and lock
+func (s *UploadSessionService) expire(session *models.UploadSession) {
+	os.Remove(session.TempPath)
+	s.dropLock(session.UUID)
+	session.Status = "expired"
+	session.HashState = nil
+	if err := s.store.UploadSessions.Save(session); err != nil {
+		s.log.Warn("Failed to expire upload session", zap.String("session", session.UUID), zap.Error(err))
+	}
+}
+
+// lock returns the mutex serialising chunk writes for a session
+func (s *UploadSessionService) lock(sessionUUID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.locks[sessionUUID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[sessionUUID] = lock
+	}
+	return lock
+}
+
+// dropLock forgets the mutex of a finished or unknown session. Callers
+// holding it may still unlock it; later calls get a fresh one.
+func (s *UploadSessionService) dropLock(sessionUUID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, sessionUUID)
+}
+
+// chunkWriter writes to the session file and feeds the hasher only the
+// bytes the file accepted, recording the first file write error
+type chunkWriter struct {
+	file   *os.File
+	hasher hash.Hash
+	err    error
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	if err != nil {
+		w.err = err
+		return n, err
+	}
+	w.hasher.Write(p)
+	return n, nil
+}
+
+// marshalHash saves a hash's internal state so hashing can resume later
+func marshalHash(h hash.Hash) ([]byte, error) {
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash state cannot be saved")
+	}
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save hash state: %w", err)
+	}
+	return state, nil
+}
+
+// unmarshalHash restores a SHA256 hash from saved state
+func unmarshalHash(state []byte) (hash.Hash, error) {
+	h := sha256.New()
+	unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash state cannot be restored")
+	}
+	if err := unmarshaler.UnmarshalBinary(state); err != nil {
+		return nil, fmt.Errorf("failed to restore hash state: %w", err)
+	}
+	return h, nil
+}
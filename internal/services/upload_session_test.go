@@ -0,0 +1,73 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// failingReader returns data and then err, like a client dropping mid-chunk
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestWriteChunkResumesAfterDroppedConnection(t *testing.T) {
+	ts := newTestServices(t)
+	sessions := NewUploadSessionService(ts.cfg, zap.NewNop(), ts.store, ts.uploads)
+	data := chatGPTExport(t, "conv-1", "hello", testDay)
+
+	session, err := sessions.CreateSession("export.zip", int64(len(data)), "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	half := len(data) / 2
+	session, err = sessions.WriteChunk(session.UUID, 0, &failingReader{data: data[:half], err: io.ErrUnexpectedEOF})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("interrupted chunk error = %v, want unexpected EOF", err)
+	}
+	if session.Offset != int64(half) {
+		t.Fatalf("offset after interrupted chunk = %d, want %d", session.Offset, half)
+	}
+	if info, err := os.Stat(session.TempPath); err != nil || info.Size() != session.Offset {
+		t.Fatalf("session file = %v, %v; want %d bytes on disk", info, err, session.Offset)
+	}
+
+	session, err = sessions.WriteChunk(session.UUID, session.Offset, bytes.NewReader(data[half:]))
+	if err != nil {
+		t.Fatalf("resumed chunk failed: %v", err)
+	}
+	if session.Status != "completed" || session.UploadID == nil {
+		t.Fatalf("session after last chunk = %s, upload %v; want completed", session.Status, session.UploadID)
+	}
+	upload, err := ts.store.Uploads.Get(*session.UploadID)
+	if err != nil {
+		t.Fatalf("failed to get upload: %v", err)
+	}
+	stored, err := os.ReadFile(upload.StoredPath)
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Errorf("stored file does not match the uploaded bytes (err %v)", err)
+	}
+
+	// Unknown sessions must not leave a lock behind either
+	if _, err := sessions.WriteChunk("no-such-session", 0, bytes.NewReader(data)); err == nil {
+		t.Error("WriteChunk accepted an unknown session")
+	}
+	if n := len(sessions.locks); n != 0 {
+		t.Errorf("%d session locks left after completion and an unknown session", n)
+	}
+}
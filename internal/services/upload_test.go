@@ -0,0 +1,30 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileRejectsDuplicate(t *testing.T) {
+	ts := newTestServices(t)
+	data := chatGPTExport(t, "conv-1", "hello", testDay)
+
+	first := ts.upload(t, "export.zip", data)
+
+	existing, err := ts.uploads.UploadFile("copy.zip", bytes.NewReader(data), int64(len(data)))
+	if err == nil || !strings.Contains(err.Error(), "already uploaded") {
+		t.Fatalf("second upload error = %v, want already uploaded", err)
+	}
+	if existing == nil || existing.ID != first.ID {
+		t.Errorf("duplicate upload returned %v, want upload %d", existing, first.ID)
+	}
+
+	// A soft-deleted upload still holds its hash until it is purged
+	if err := ts.store.Uploads.Delete(first); err != nil {
+		t.Fatalf("failed to delete upload: %v", err)
+	}
+	if _, err := ts.uploads.UploadFile("copy.zip", bytes.NewReader(data), int64(len(data))); err == nil || !strings.Contains(err.Error(), "not yet purged") {
+		t.Fatalf("upload after delete error = %v, want not yet purged", err)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"chatgpt-autopsy-go/internal/config"
+	"chatgpt-autopsy-go/internal/models"
+	"chatgpt-autopsy-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// UsageService records AI provider calls in the usage ledger and enforces
+// the monthly budget
+type UsageService struct {
+	cfg   *config.Config
+	log   *zap.Logger
+	store *repository.Store
+}
+
+// NewUsageService creates a new usage service
+func NewUsageService(cfg *config.Config, log *zap.Logger, store *repository.Store) *UsageService {
+	return &UsageService{
+		cfg:   cfg,
+		log:   log,
+		store: store,
+	}
+}
+
+// UsageGroup is a row of aggregated usage
+type UsageGroup struct {
+	Key           string  `gorm:"column:group_key" json:"key"`
+	Calls         int64   `json:"calls"`
+	FailedCalls   int64   `json:"failed_calls"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	EstimatedCost float64 `json:"estimated_cost"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+}
+
+// BudgetStatus describes spending against the monthly budget
+type BudgetStatus struct {
+	Month         string  `json:"month"` // YYYY-MM
+	Budget        float64 `json:"budget"`
+	Spent         float64 `json:"spent"`
+	Remaining     float64 `json:"remaining"`
+	Exceeded      bool    `json:"exceeded"`
+	BudgetEnabled bool    `json:"budget_enabled"`
+}
+
+// usageGroupColumns maps supported groupings to ledger columns
+var usageGroupColumns = map[string]string{
+	"day":       "called_on",
+	"provider":  "provider || '/' || model",
+	"dimension": "dimension",
+}
+
+// EstimateCost returns the USD cost of a call from the configured price table
+func (s *UsageService) EstimateCost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := s.cfg.AI.Prices[model]
+	if !ok {
+		return 0
+	}
+	return (float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion) / 1_000_000
+}
+
+// Record stores a ledger row, filling in the estimated cost and call date
+func (s *UsageService) Record(usage *models.AIUsage) error {
+	now := time.Now().UTC()
+	usage.EstimatedCost = s.EstimateCost(usage.Model, usage.InputTokens, usage.OutputTokens)
+	usage.CalledOn = now.Format("2006-01-02")
+	usage.CreatedAt = now
+
+	if _, ok := s.cfg.AI.Prices[usage.Model]; !ok && usage.Success {
+		s.log.Warn("No price configured for model, recording zero cost", zap.String("model", usage.Model))
+	}
+
+	if err := s.store.AIUsage.Create(usage); err != nil {
+		return fmt.Errorf("failed to record AI usage: %w", err)
+	}
+	return nil
+}
+
+// LinkAnalysis attaches ledger rows to the analysis they produced
+func (s *UsageService) LinkAnalysis(usageIDs []uint, analysisID uint) error {
+	if len(usageIDs) == 0 {
+		return nil
+	}
+	if err := s.store.AIUsage.Query().
+		Where("id IN ?", usageIDs).
+		Update("analysis_id", analysisID).Error; err != nil {
+		return fmt.Errorf("failed to link AI usage to analysis: %w", err)
+	}
+	return nil
+}
+
+// Budget returns spending against the monthly budget for the current month
+func (s *UsageService) Budget() (*BudgetStatus, error) {
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var spent float64
+	if err := s.store.AIUsage.Query().
+		Where("called_on >= ?", monthStart.Format("2006-01-02")).
+		Select("COALESCE(SUM(estimated_cost), 0)").
+		Scan(&spent).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum monthly AI cost: %w", err)
+	}
+
+	status := &BudgetStatus{
+		Month:         monthStart.Format("2006-01"),
+		Budget:        s.cfg.AI.MonthlyBudget,
+		Spent:         spent,
+		BudgetEnabled: s.cfg.AI.MonthlyBudget > 0,
+	}
+	if status.BudgetEnabled {
+		status.Remaining = status.Budget - spent
+		if status.Remaining < 0 {
+			status.Remaining = 0
+		}
+		status.Exceeded = spent >= status.Budget
+	}
+	return status, nil
+}
+
+// BudgetExceeded reports whether this month's spending has reached the budget
+func (s *UsageService) BudgetExceeded() (bool, error) {
+	if s.cfg.AI.MonthlyBudget <= 0 {
+		return false, nil
+	}
+	status, err := s.Budget()
+	if err != nil {
+		return false, err
+	}
+	return status.Exceeded, nil
+}
+
+// Aggregate returns usage grouped by day, provider or dimension for calls
+// made between from and to (inclusive, YYYY-MM-DD, either may be empty)
+func (s *UsageService) Aggregate(groupBy, from, to string) ([]UsageGroup, error) {
+	column, ok := usageGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid usage grouping: %s", groupBy)
+	}
+
+	query := s.store.AIUsage.Query().Select(fmt.Sprintf(`%s AS group_key,
+		COUNT(*) AS calls,
+		SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failed_calls,
+		COALESCE(SUM(input_tokens), 0) AS input_tokens,
+		COALESCE(SUM(output_tokens), 0) AS output_tokens,
+		COALESCE(SUM(estimated_cost), 0) AS estimated_cost,
+		COALESCE(AVG(latency_ms), 0) AS avg_latency_ms`, column))
+	if from != "" {
+		query = query.Where("called_on >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("called_on <= ?", to)
+	}
+
+	var groups []UsageGroup
+	if err := query.Group("group_key").Order("group_key ASC").Scan(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate AI usage: %w", err)
+	}
+	return groups, nil
+}
+
+// Totals returns overall usage for calls made between from and to
+func (s *UsageService) Totals(from, to string) (*UsageGroup, error) {
+	query := s.store.AIUsage.Query().Select(`COUNT(*) AS calls,
+		COALESCE(SUM(CASE WHEN success THEN 0 ELSE 1 END), 0) AS failed_calls,
+		COALESCE(SUM(input_tokens), 0) AS input_tokens,
+		COALESCE(SUM(output_tokens), 0) AS output_tokens,
+		COALESCE(SUM(estimated_cost), 0) AS estimated_cost,
+		COALESCE(AVG(latency_ms), 0) AS avg_latency_ms`)
+	if from != "" {
+		query = query.Where("called_on >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("called_on <= ?", to)
+	}
+
+	var totals UsageGroup
+	if err := query.Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to total AI usage: %w", err)
+	}
+	totals.Key = "total"
+	return &totals, nil
+}